@@ -0,0 +1,307 @@
+package activation
+
+import (
+	"sort"
+)
+
+const (
+	DEFINED   = "DEFINED"
+	STARTED   = "STARTED"
+	LOCKED_IN = "LOCKED_IN"
+	ACTIVE    = "ACTIVE"
+	FAILED    = "FAILED"
+
+	// Supermajority milestones of HEIGHT deployments.
+	ENFORCED   = "ENFORCED"
+	REJECT_OLD = "REJECT_OLD"
+
+	MEDIAN_TIME_SPAN = 11
+
+	VERSIONBITS_TOP_MASK  = 0xe0000000
+	VERSIONBITS_TOP_BITS  = 0x20000000
+	VERSIONBITS_NUM_BITS  = 29
+	VERSIONBITS_LAST_BIT  = VERSIONBITS_NUM_BITS - 1
+	VERSIONBITS_FIRST_BIT = 0
+)
+
+// Block is the part of a block header the finder needs.
+type Block struct {
+	Height  int64  `json:"height"`
+	Hash    string `json:"hash"`
+	Time    int64  `json:"time"`
+	Version int32  `json:"version"`
+}
+
+// Event is a state change of a deployment. Height is the first block the
+// new state applies to.
+type Event struct {
+	Deployment string `json:"deployment"`
+	State      string `json:"state"`
+	Height     int64  `json:"height"`
+	Hash       string `json:"hash"`
+	Time       int64  `json:"time"`
+	MedianTime int64  `json:"mediantime"`
+	// Count and Total are the signalling blocks of the window that decided
+	// a LOCKED_IN or FAILED transition, or the new version blocks of the
+	// supermajority window for ENFORCED and REJECT_OLD.
+	Count int64 `json:"count,omitempty"`
+	Total int64 `json:"total,omitempty"`
+}
+
+// Period is the signalling of a VERSIONBITS deployment over one window
+// while it was STARTED.
+type Period struct {
+	Deployment string `json:"deployment"`
+	Height     int64  `json:"height"`
+	Count      int64  `json:"count"`
+	Total      int64  `json:"total"`
+}
+
+// Signals reports whether version signals for bit under BIP9.
+func Signals(version int32, bit uint) bool {
+	return uint32(version)&VERSIONBITS_TOP_MASK == VERSIONBITS_TOP_BITS && uint32(version)&(1<<bit) != 0
+}
+
+// SignalledBits returns the BIP9 bits set in version, which is empty when
+// the version does not use version bits.
+func SignalledBits(version int32) []uint {
+	var bits []uint
+	if uint32(version)&VERSIONBITS_TOP_MASK != VERSIONBITS_TOP_BITS {
+		return bits
+	}
+	for bit := uint(VERSIONBITS_FIRST_BIT); bit <= VERSIONBITS_LAST_BIT; bit++ {
+		if uint32(version)&(1<<bit) != 0 {
+			bits = append(bits, bit)
+		}
+	}
+	return bits
+}
+
+// Finder follows consecutive blocks and records when each deployment
+// changed state. Blocks below Start only provide the median time and
+// supermajority context. Its fields are exported so a scan can be saved to a
+// checkpoint and resumed.
+type Finder struct {
+	Params      *ChainParams `json:"-"`
+	Deployments []Deployment `json:"-"`
+	Start       int64        `json:"start"`
+
+	Events  []Event  `json:"events"`
+	Periods []Period `json:"periods"`
+
+	LastHeight int64             `json:"last_height"`
+	Times      []int64           `json:"times"`
+	Versions   []int32           `json:"versions"`
+	States     map[string]string `json:"states"`
+	Counts     map[string]int64  `json:"counts"`
+	PeriodFrom int64             `json:"period_from"`
+}
+
+func NewFinder(params *ChainParams, deployments []Deployment, start int64) *Finder {
+	f := &Finder{
+		Params:      params,
+		Deployments: deployments,
+		Start:       start,
+		LastHeight:  -1,
+		PeriodFrom:  -1,
+		States:      make(map[string]string),
+		Counts:      make(map[string]int64),
+	}
+	for _, d := range deployments {
+		if d.Type == VERSIONBITS {
+			f.States[d.Name] = DEFINED
+		}
+	}
+	return f
+}
+
+// MedianTimePast returns the median time of the last 11 blocks added.
+func (f *Finder) MedianTimePast() int64 {
+	if len(f.Times) == 0 {
+		return 0
+	}
+	times := append([]int64(nil), f.Times...)
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times[len(times)/2]
+}
+
+// State returns the current state of deployment name. TIME and HEIGHT
+// deployments are DEFINED until they become ACTIVE.
+func (f *Finder) State(name string) string {
+	if s, ok := f.States[name]; ok {
+		return s
+	}
+	return DEFINED
+}
+
+// Done reports whether every deployment has reached a final state.
+func (f *Finder) Done() bool {
+	for _, d := range f.Deployments {
+		switch d.Type {
+		case TIME:
+			if f.State(d.Name) != ACTIVE {
+				return false
+			}
+		case HEIGHT:
+			if f.State(d.Name) != ACTIVE || !f.hasEvent(d.Name, REJECT_OLD) {
+				return false
+			}
+		case VERSIONBITS:
+			if s := f.State(d.Name); s != ACTIVE && s != FAILED {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *Finder) hasEvent(name, state string) bool {
+	for _, e := range f.Events {
+		if e.Deployment == name && e.State == state {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Finder) event(d Deployment, state string, b Block, mtp, count, total int64) {
+	f.Events = append(f.Events, Event{
+		Deployment: d.Name,
+		State:      state,
+		Height:     b.Height,
+		Hash:       b.Hash,
+		Time:       b.Time,
+		MedianTime: mtp,
+		Count:      count,
+		Total:      total,
+	})
+}
+
+// Add processes the next block, which must follow the previous one added.
+func (f *Finder) Add(b Block) {
+	// The rules for a block depend on the median time past of its parent.
+	mtp := f.MedianTimePast()
+
+	if b.Height >= f.Start {
+		if f.Params.Window > 0 && b.Height%f.Params.Window == 0 {
+			f.newPeriod(b, mtp)
+		}
+		for _, d := range f.Deployments {
+			switch d.Type {
+			case TIME:
+				f.checkTime(d, b, mtp)
+			case HEIGHT:
+				f.checkHeight(d, b, mtp)
+			case VERSIONBITS:
+				if Signals(b.Version, d.Bit) {
+					f.Counts[d.Name]++
+				}
+			}
+		}
+	}
+
+	f.LastHeight = b.Height
+	f.Times = append(f.Times, b.Time)
+	if len(f.Times) > MEDIAN_TIME_SPAN {
+		f.Times = f.Times[len(f.Times)-MEDIAN_TIME_SPAN:]
+	}
+	if f.Params.MajorityWindow > 0 {
+		f.Versions = append(f.Versions, b.Version)
+		if len(f.Versions) > f.Params.MajorityWindow {
+			f.Versions = f.Versions[len(f.Versions)-f.Params.MajorityWindow:]
+		}
+	}
+}
+
+func (f *Finder) checkTime(d Deployment, b Block, mtp int64) {
+	if f.State(d.Name) != ACTIVE && b.Time >= d.Time {
+		f.States[d.Name] = ACTIVE
+		f.event(d, ACTIVE, b, mtp, 0, 0)
+	}
+}
+
+func (f *Finder) checkHeight(d Deployment, b Block, mtp int64) {
+	// The supermajority is counted over the blocks before b, which is only
+	// meaningful once the window is full or reaches back to genesis.
+	full := len(f.Versions) == f.Params.MajorityWindow || int64(len(f.Versions)) == b.Height
+	if full && !f.hasEvent(d.Name, REJECT_OLD) {
+		var count int64
+		for _, v := range f.Versions {
+			if v >= d.MinVersion {
+				count++
+			}
+		}
+		total := int64(len(f.Versions))
+		if count >= int64(f.Params.MajorityEnforce) && !f.hasEvent(d.Name, ENFORCED) {
+			f.event(d, ENFORCED, b, mtp, count, total)
+		}
+		if count >= int64(f.Params.MajorityReject) {
+			f.event(d, REJECT_OLD, b, mtp, count, total)
+		}
+	}
+
+	if b.Height == d.Height {
+		f.States[d.Name] = ACTIVE
+		f.event(d, ACTIVE, b, mtp, 0, 0)
+	}
+}
+
+// newPeriod moves the VERSIONBITS deployments to their state for the window
+// starting at b, following BIP9 with Litecoin's optional height based start
+// and timeout.
+func (f *Finder) newPeriod(b Block, mtp int64) {
+	// Only a window seen from its first block can lock in.
+	complete := f.PeriodFrom == b.Height-f.Params.Window
+	f.PeriodFrom = b.Height
+
+	for _, d := range f.Deployments {
+		if d.Type != VERSIONBITS {
+			continue
+		}
+		count := f.Counts[d.Name]
+		f.Counts[d.Name] = 0
+
+		state := f.State(d.Name)
+		if state == STARTED && complete {
+			f.Periods = append(f.Periods, Period{Deployment: d.Name, Height: b.Height - f.Params.Window, Count: count, Total: f.Params.Window})
+		}
+
+		started := mtp >= d.StartTime
+		if d.StartHeight > 0 {
+			started = b.Height >= d.StartHeight
+		}
+		timedOut := mtp >= d.Timeout
+		if d.TimeoutHeight > 0 {
+			timedOut = b.Height >= d.TimeoutHeight
+		}
+
+		next := state
+		switch state {
+		case DEFINED:
+			if timedOut {
+				next = FAILED
+			} else if started {
+				next = STARTED
+			}
+		case STARTED:
+			if complete && count >= f.Params.DeploymentThreshold(d) {
+				next = LOCKED_IN
+			} else if timedOut {
+				next = FAILED
+			}
+		case LOCKED_IN:
+			if b.Height >= d.MinActivationHeight {
+				next = ACTIVE
+			}
+		}
+
+		if next != state {
+			f.States[d.Name] = next
+			if state == STARTED {
+				f.event(d, next, b, mtp, count, f.Params.Window)
+			} else {
+				f.event(d, next, b, mtp, 0, 0)
+			}
+		}
+	}
+}
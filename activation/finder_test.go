@@ -0,0 +1,165 @@
+package activation
+
+import (
+	"testing"
+)
+
+// testChain retargets every 10 blocks and locks in with 8 signalling blocks
+// out of 10.
+var testChain = ChainParams{
+	Name:            "test",
+	Window:          10,
+	Threshold:       8,
+	MajorityWindow:  10,
+	MajorityEnforce: 6,
+	MajorityReject:  9,
+}
+
+// run feeds f the blocks from start to end, 600 seconds apart, with the
+// version version returns for each height.
+func run(f *Finder, start, end int64, version func(height int64) int32) {
+	for height := start; height <= end; height++ {
+		f.Add(Block{Height: height, Time: 1000 + height*600, Version: version(height)})
+	}
+}
+
+// events returns the height of each deployment/state event.
+func events(f *Finder) map[string]int64 {
+	result := make(map[string]int64)
+	for _, e := range f.Events {
+		result[e.Deployment+"/"+e.State] = e.Height
+	}
+	return result
+}
+
+func checkEvents(t *testing.T, f *Finder, want map[string]int64) {
+	got := events(f)
+	for k, v := range want {
+		if h, ok := got[k]; !ok || h != v {
+			t.Errorf("%s at height %d (%v), want %d", k, h, ok, v)
+		}
+	}
+	for k, v := range got {
+		if _, ok := want[k]; !ok {
+			t.Errorf("unexpected event %s at height %d", k, v)
+		}
+	}
+}
+
+// signalling returns versions that signal for bit in every block from
+// start to end, except every tenth one.
+func signalling(bit uint, start, end int64) func(int64) int32 {
+	return func(height int64) int32 {
+		if height >= start && height < end && height%10 != 9 {
+			return VERSIONBITS_TOP_BITS | 1<<bit
+		}
+		return VERSIONBITS_TOP_BITS
+	}
+}
+
+func TestVersionBitsActivation(t *testing.T) {
+	d := Deployment{Name: "vb", Type: VERSIONBITS, Bit: 2, StartHeight: 20, TimeoutHeight: 100}
+	f := NewFinder(&testChain, []Deployment{d}, 0)
+
+	// Not enough signalling in the first started window, then 9 of 10.
+	version := signalling(d.Bit, 30, 40)
+	run(f, 0, 19, version)
+	if s := f.State("vb"); s != DEFINED {
+		t.Fatalf("state before StartHeight = %s, want %s", s, DEFINED)
+	}
+	run(f, 20, 60, version)
+
+	checkEvents(t, f, map[string]int64{"vb/STARTED": 20, "vb/LOCKED_IN": 40, "vb/ACTIVE": 50})
+	if !f.Done() {
+		t.Fatal("Done = false after activation")
+	}
+	if len(f.Periods) != 2 || f.Periods[0].Count != 0 || f.Periods[1].Height != 30 || f.Periods[1].Count != 9 {
+		t.Fatalf("Periods = %+v", f.Periods)
+	}
+	for _, e := range f.Events {
+		if e.State == LOCKED_IN && (e.Count != 9 || e.Total != 10) {
+			t.Fatalf("LOCKED_IN count %d/%d, want 9/10", e.Count, e.Total)
+		}
+	}
+}
+
+func TestVersionBitsTimeout(t *testing.T) {
+	// Signalling below the threshold until the timeout fails the
+	// deployment, and signalling after that doesn't matter.
+	d := Deployment{Name: "vb", Type: VERSIONBITS, Bit: 1, StartHeight: 20, TimeoutHeight: 50}
+	f := NewFinder(&testChain, []Deployment{d}, 0)
+	run(f, 0, 80, func(height int64) int32 {
+		if height%10 < 7 || height >= 50 {
+			return VERSIONBITS_TOP_BITS | 1<<d.Bit
+		}
+		return VERSIONBITS_TOP_BITS
+	})
+	checkEvents(t, f, map[string]int64{"vb/STARTED": 20, "vb/FAILED": 50})
+	if !f.Done() {
+		t.Fatal("Done = false after the deployment failed")
+	}
+
+	// A deployment whose timeout passes before it starts fails straight
+	// from DEFINED.
+	d = Deployment{Name: "late", Type: VERSIONBITS, Bit: 1, StartTime: 1000 + 30*600, Timeout: 1000 + 20*600}
+	f = NewFinder(&testChain, []Deployment{d}, 0)
+	run(f, 0, 40, signalling(d.Bit, 0, 40))
+	checkEvents(t, f, map[string]int64{"late/FAILED": 30})
+}
+
+func TestMinActivationHeight(t *testing.T) {
+	// Locked in at 40, but ACTIVE waits for the first window at or after
+	// MinActivationHeight.
+	d := Deployment{Name: "vb", Type: VERSIONBITS, Bit: 2, StartHeight: 20, TimeoutHeight: 100, MinActivationHeight: 75, Threshold: 9}
+	f := NewFinder(&testChain, []Deployment{d}, 0)
+	run(f, 0, 100, signalling(d.Bit, 30, 40))
+	checkEvents(t, f, map[string]int64{"vb/STARTED": 20, "vb/LOCKED_IN": 40, "vb/ACTIVE": 80})
+}
+
+func TestTimeAndHeightDeployments(t *testing.T) {
+	deployments := []Deployment{
+		{Name: "ts", Type: TIME, Time: 1000 + 33*600},
+		{Name: "h", Type: HEIGHT, Height: 40, MinVersion: 2},
+	}
+	f := NewFinder(&testChain, deployments, 0)
+	run(f, 0, 50, func(height int64) int32 {
+		if height >= 20 {
+			return 2
+		}
+		return 1
+	})
+	// 6 of the previous 10 blocks are version 2 at height 26 and 9 at 29.
+	checkEvents(t, f, map[string]int64{"ts/ACTIVE": 33, "h/ENFORCED": 26, "h/REJECT_OLD": 29, "h/ACTIVE": 40})
+	if !f.Done() {
+		t.Fatal("Done = false after every deployment activated")
+	}
+}
+
+func TestSignalledBits(t *testing.T) {
+	if bits := SignalledBits(0x20000012); len(bits) != 2 || bits[0] != 1 || bits[1] != 4 {
+		t.Fatalf("SignalledBits(20000012) = %v, want [1 4]", bits)
+	}
+	if bits := SignalledBits(0x00000012); len(bits) != 0 {
+		t.Fatalf("SignalledBits(00000012) = %v, want none", bits)
+	}
+	if !Signals(0x20000004, 2) || Signals(0x20000004, 1) || Signals(0x40000004, 2) {
+		t.Fatal("Signals doesn't match the BIP9 top bits and the bit")
+	}
+}
+
+func TestLitecoinTestDeployments(t *testing.T) {
+	for _, name := range []string{"taproot", "mweb"} {
+		d, ok := LitecoinTest.Deployment(name)
+		if !ok {
+			t.Fatalf("litecoin-test has no %s deployment", name)
+		}
+		main, _ := LitecoinMain.Deployment(name)
+		if d.Bit != main.Bit {
+			t.Errorf("%s bit %d, want mainnet's %d", name, d.Bit, main.Bit)
+		}
+		window := LitecoinTest.Window
+		if d.StartHeight%window != 0 || d.TimeoutHeight%window != 0 || d.ScanFrom >= d.StartHeight {
+			t.Errorf("%s heights %d-%d, scan from %d, don't line up with the %d block window", name, d.StartHeight, d.TimeoutHeight, d.ScanFrom, window)
+		}
+	}
+}
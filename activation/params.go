@@ -0,0 +1,209 @@
+package activation
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	TIME        = "time"
+	HEIGHT      = "height"
+	VERSIONBITS = "versionbits"
+)
+
+// Deployment describes how a soft fork was switched on.
+//
+// TIME deployments are enforced for blocks with a timestamp at or after Time
+// (BIP16). HEIGHT deployments were first enforced by a supermajority of
+// blocks with at least MinVersion and later buried at Height (BIP34/65/66).
+// VERSIONBITS deployments follow BIP9 and signal on Bit once StartTime or
+// StartHeight has passed until Timeout or TimeoutHeight.
+type Deployment struct {
+	Name string
+	Type string
+
+	Time int64
+
+	Height     int64
+	MinVersion int32
+
+	Bit                 uint
+	StartTime           int64
+	Timeout             int64
+	StartHeight         int64
+	TimeoutHeight       int64
+	MinActivationHeight int64
+	// Threshold overrides the chain's threshold when set.
+	Threshold int64
+
+	// ScanFrom is a height safely before anything happens to the deployment,
+	// used when no start block is given.
+	ScanFrom int64
+}
+
+// ChainParams holds the activation rules of a network and its deployments.
+type ChainParams struct {
+	Name    string
+	RPCPort int
+
+	// Window is the BIP9 miner confirmation window and Threshold the number
+	// of signalling blocks in it needed to lock in.
+	Window    int64
+	Threshold int64
+
+	// Supermajority rules used by the version 2-4 upgrades: blocks of the
+	// new version are checked once MajorityEnforce of the last
+	// MajorityWindow blocks have it, and older versions are rejected after
+	// MajorityReject.
+	MajorityWindow  int
+	MajorityEnforce int
+	MajorityReject  int
+
+	Deployments []Deployment
+}
+
+var (
+	LitecoinMain = ChainParams{
+		Name:            "litecoin-main",
+		RPCPort:         9332,
+		Window:          8064,
+		Threshold:       6048,
+		MajorityWindow:  1000,
+		MajorityEnforce: 750,
+		MajorityReject:  950,
+		Deployments: []Deployment{
+			{Name: "bip16", Type: TIME, Time: 1349049600, ScanFrom: 218570},
+			{Name: "bip34", Type: HEIGHT, Height: 710000, MinVersion: 2},
+			{Name: "bip66", Type: HEIGHT, Height: 811879, MinVersion: 3},
+			{Name: "bip65", Type: HEIGHT, Height: 918684, MinVersion: 4},
+			{Name: "csv", Type: VERSIONBITS, Bit: 0, StartTime: 1485561600, Timeout: 1517356801, ScanFrom: 1048320},
+			{Name: "segwit", Type: VERSIONBITS, Bit: 1, StartTime: 1485561600, Timeout: 1517356801, ScanFrom: 1048320},
+			{Name: "taproot", Type: VERSIONBITS, Bit: 2, StartHeight: 2161152, TimeoutHeight: 2370816, ScanFrom: 2153088},
+			{Name: "mweb", Type: VERSIONBITS, Bit: 4, StartHeight: 2209536, TimeoutHeight: 2419200, ScanFrom: 2201472},
+		},
+	}
+
+	LitecoinTest = ChainParams{
+		Name:            "litecoin-test",
+		RPCPort:         19332,
+		Window:          2016,
+		Threshold:       1512,
+		MajorityWindow:  100,
+		MajorityEnforce: 51,
+		MajorityReject:  75,
+		Deployments: []Deployment{
+			{Name: "bip16", Type: TIME, Time: 1349049600},
+			{Name: "bip34", Type: HEIGHT, Height: 76, MinVersion: 2},
+			{Name: "bip66", Type: HEIGHT, Height: 76, MinVersion: 3},
+			{Name: "bip65", Type: HEIGHT, Height: 76, MinVersion: 4},
+			{Name: "csv", Type: VERSIONBITS, Bit: 0, StartTime: 1483228800, Timeout: 1517356801},
+			{Name: "segwit", Type: VERSIONBITS, Bit: 1, StartTime: 1483228800, Timeout: 1517356801},
+			{Name: "taproot", Type: VERSIONBITS, Bit: 2, StartHeight: 2225664, TimeoutHeight: 2435328, ScanFrom: 2223648},
+			{Name: "mweb", Type: VERSIONBITS, Bit: 4, StartHeight: 2209536, TimeoutHeight: 2419200, ScanFrom: 2207520},
+		},
+	}
+
+	BitcoinMain = ChainParams{
+		Name:            "bitcoin-main",
+		RPCPort:         8332,
+		Window:          2016,
+		Threshold:       1916,
+		MajorityWindow:  1000,
+		MajorityEnforce: 750,
+		MajorityReject:  950,
+		Deployments: []Deployment{
+			{Name: "bip16", Type: TIME, Time: 1333238400, ScanFrom: 173800},
+			{Name: "bip34", Type: HEIGHT, Height: 227931, MinVersion: 2},
+			{Name: "bip66", Type: HEIGHT, Height: 363725, MinVersion: 3},
+			{Name: "bip65", Type: HEIGHT, Height: 388381, MinVersion: 4},
+			{Name: "csv", Type: VERSIONBITS, Bit: 0, StartTime: 1462060800, Timeout: 1493596800, ScanFrom: 403200},
+			{Name: "segwit", Type: VERSIONBITS, Bit: 1, StartTime: 1479168000, Timeout: 1510704000, ScanFrom: 403200},
+			{Name: "taproot", Type: VERSIONBITS, Bit: 2, StartTime: 1619222400, Timeout: 1628640000, MinActivationHeight: 709632, Threshold: 1815, ScanFrom: 677376},
+		},
+	}
+
+	BitcoinTest = ChainParams{
+		Name:            "bitcoin-test",
+		RPCPort:         18332,
+		Window:          2016,
+		Threshold:       1512,
+		MajorityWindow:  100,
+		MajorityEnforce: 51,
+		MajorityReject:  75,
+		Deployments: []Deployment{
+			{Name: "bip16", Type: TIME, Time: 1333238400},
+			{Name: "bip34", Type: HEIGHT, Height: 21111, MinVersion: 2},
+			{Name: "bip66", Type: HEIGHT, Height: 330776, MinVersion: 3},
+			{Name: "bip65", Type: HEIGHT, Height: 581885, MinVersion: 4},
+			{Name: "csv", Type: VERSIONBITS, Bit: 0, StartTime: 1456790400, Timeout: 1493596800},
+			{Name: "segwit", Type: VERSIONBITS, Bit: 1, StartTime: 1462060800, Timeout: 1493596800},
+			{Name: "taproot", Type: VERSIONBITS, Bit: 2, StartTime: 1619222400, Timeout: 1628640000},
+		},
+	}
+)
+
+// GetChainParams returns the presets for chain (litecoin or bitcoin) on
+// network (main or test).
+func GetChainParams(chain, network string) (*ChainParams, error) {
+	switch network {
+	case "mainnet":
+		network = "main"
+	case "testnet":
+		network = "test"
+	}
+
+	switch chain + "-" + network {
+	case "litecoin-main":
+		return &LitecoinMain, nil
+	case "litecoin-test":
+		return &LitecoinTest, nil
+	case "bitcoin-main":
+		return &BitcoinMain, nil
+	case "bitcoin-test":
+		return &BitcoinTest, nil
+	}
+	return nil, fmt.Errorf("unknown chain %q network %q", chain, network)
+}
+
+// Select returns the named deployments, in the order given. "all" selects
+// every deployment of the chain.
+func (p *ChainParams) Select(names string) ([]Deployment, error) {
+	if names == "all" {
+		return append([]Deployment(nil), p.Deployments...), nil
+	}
+
+	var result []Deployment
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		d, ok := p.Deployment(name)
+		if !ok {
+			return nil, fmt.Errorf("%s has no deployment %q", p.Name, name)
+		}
+		result = append(result, d)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no deployments selected")
+	}
+	return result, nil
+}
+
+func (p *ChainParams) Deployment(name string) (Deployment, bool) {
+	for _, d := range p.Deployments {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Deployment{}, false
+}
+
+// DeploymentThreshold returns the number of signalling blocks per window d
+// needs to lock in.
+func (p *ChainParams) DeploymentThreshold(d Deployment) int64 {
+	if d.Threshold > 0 {
+		return d.Threshold
+	}
+	return p.Threshold
+}
@@ -1,169 +1,533 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"strings"
+	"math"
+	"runtime"
+	"time"
+
+	"github.com/thrasher-/litecoin-tools/checkpoint"
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+	"github.com/thrasher-/litecoin-tools/rawblock"
 )
 
 const (
-	RPC_PORT     = 9332
-	RPC_USERNAME = "user"
-	RPC_PASSWORD = "pass"
-	RPC_HOST     = "127.0.0.1"
+	BATCH_SIZE = 500
+
+	MAINNET_RPC_PORT = 9332
+	TESTNET_RPC_PORT = 19332
+	REGTEST_RPC_PORT = 19443
 )
 
-func BuildURL() string {
-	return fmt.Sprintf("http://%s:%s@%s:%d", RPC_USERNAME, RPC_PASSWORD, RPC_HOST, RPC_PORT)
-}
+var (
+	RPCHost     string
+	RPCPort     int
+	RPCUsername string
+	RPCPassword string
+	rpc         *litecoinrpc.Client
 
-func JSONDecode(data []byte, to interface{}) error {
-	err := json.Unmarshal(data, &to)
+	// chain is set when blocks are read from the node's blk*.dat files with
+	// -datadir instead of over RPC.
+	chain *rawblock.Chain
+)
 
+func GetBlockHeight() (int, error) {
+	if chain != nil {
+		return int(chain.Height()), nil
+	}
+	info, err := rpc.GetBlockchainInfo()
 	if err != nil {
-		return err
+		return 0, err
 	}
+	return int(info.Blocks), nil
+}
 
-	return nil
+type BlockInfo struct {
+	Height         int    `json:"height"`
+	Hash           string `json:"hash"`
+	Time           int64  `json:"time"`
+	Size           int    `json:"size"`
+	StrippedSize   int    `json:"stripped_size"`
+	Weight         int    `json:"weight"`
+	TXCount        int    `json:"tx_count"`
+	WitnessTXCount int    `json:"witness_tx_count"`
+	LargestTXID    string `json:"largest_txid,omitempty"`
+	LargestTXVSize int    `json:"largest_tx_vsize"`
+	Fees           int64  `json:"fees"`
+	HogEx          bool   `json:"hogex"`
+	MWEBInputs     int    `json:"mweb_inputs"`
+	MWEBOutputs    int    `json:"mweb_outputs"`
+	MWEBKernels    int    `json:"mweb_kernels"`
+	PegIn          int64  `json:"pegin"`
+	PegOut         int64  `json:"pegout"`
 }
 
-func SendRPCRequest(method, req interface{}) (map[string]interface{}, error) {
-	var params []interface{}
-	if req != nil {
-		params = append(params, req)
-	} else {
-		params = nil
+// SetMWEB fills in the extension block metrics. Only blocks carrying a
+// HogEx (integration) transaction have an MWEB section.
+func (bi *BlockInfo) SetMWEB(mweb *litecoinrpc.MWEBBlock) {
+	if mweb == nil {
+		return
 	}
 
-	data, err := json.Marshal(map[string]interface{}{
-		"method": method,
-		"id":     1,
-		"params": params,
-	})
+	bi.HogEx = true
+	bi.MWEBInputs = len(mweb.Inputs)
+	bi.MWEBOutputs = len(mweb.Outputs)
+	bi.MWEBKernels = len(mweb.Kernels)
+	for _, k := range mweb.Kernels {
+		bi.PegIn += k.PegIn
+		for _, x := range k.PegOuts {
+			bi.PegOut += x.Value
+		}
+	}
+}
 
-	if err != nil {
-		return nil, err
+// fetchTransactions makes GetBlocks request fully decoded blocks, which is
+// needed for per-transaction data such as fees, vsize and witness usage.
+var fetchTransactions bool
+
+func GetBlocks(start, end int) ([]BlockInfo, error) {
+	if chain != nil {
+		return getBlocksFromChain(start, end)
 	}
 
-	resp, err := http.Post(BuildURL(), "application/json", strings.NewReader(string(data)))
+	hashes, err := rpc.GetBlockHashes(int64(start), int64(end))
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	var blockHashes []string
+	for _, x := range hashes {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block hash for height %d: %s", x.Height, x.Err)
+		}
+		blockHashes = append(blockHashes, x.Hash)
+	}
+
+	if fetchTransactions {
+		return getBlocksVerbose(hashes, blockHashes)
 	}
 
-	result := make(map[string]interface{})
-	err = json.Unmarshal(body, &result)
+	blocks, err := rpc.GetBlocks(blockHashes)
 	if err != nil {
 		return nil, err
 	}
 
-	if result["error"] != nil {
-		errorMsg := result["error"].(map[string]interface{})
-		return nil, fmt.Errorf("Error code: %v, message: %v\n", errorMsg["code"], errorMsg["message"])
+	var result []BlockInfo
+	for i, x := range blocks {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block %s at height %d: %s", x.Hash, hashes[i].Height, x.Err)
+		}
+		bi := BlockInfo{
+			Height:       int(hashes[i].Height),
+			Hash:         x.Hash,
+			Time:         x.Block.Time,
+			Size:         x.Block.Size,
+			StrippedSize: x.Block.StrippedSize,
+			Weight:       x.Block.Weight,
+			TXCount:      len(x.Block.Tx),
+		}
+		bi.SetMWEB(x.Block.MWEB)
+		result = append(result, bi)
 	}
 	return result, nil
 }
 
-func GetBlockHeight() (int, error) {
-	result, err := SendRPCRequest("getinfo", nil)
+func getBlocksVerbose(hashes []litecoinrpc.BlockHashResult, blockHashes []string) ([]BlockInfo, error) {
+	blocks, err := rpc.GetBlocksVerbose(blockHashes)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	var result []BlockInfo
+	for i, x := range blocks {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block %s at height %d: %s", x.Hash, hashes[i].Height, x.Err)
+		}
+
+		bi := BlockInfo{
+			Height:       int(hashes[i].Height),
+			Hash:         x.Hash,
+			Time:         x.Block.Time,
+			Size:         x.Block.Size,
+			StrippedSize: x.Block.StrippedSize,
+			Weight:       x.Block.Weight,
+			TXCount:      len(x.Block.Tx),
+		}
+		for _, tx := range x.Block.Tx {
+			bi.Fees += int64(math.Round(tx.Fee * 1e8))
+
+			// The wtxid only differs from the txid when witness data is present.
+			if tx.Hash != "" && tx.Hash != tx.TxID {
+				bi.WitnessTXCount++
+			}
+
+			if tx.VSize > bi.LargestTXVSize {
+				bi.LargestTXID = tx.TxID
+				bi.LargestTXVSize = tx.VSize
+			}
+		}
+		bi.SetMWEB(x.Block.MWEB)
+		result = append(result, bi)
 	}
+	return result, nil
+}
 
-	result = result["result"].(map[string]interface{})
-	block := result["blocks"].(float64)
-	return int(block), nil
+// getBlocksFromChain decodes blocks from the blk*.dat files. Fees need the
+// spent outputs, which the block files do not hold, so they are left at 0.
+func getBlocksFromChain(start, end int) ([]BlockInfo, error) {
+	var result []BlockInfo
+	for height := start; height <= end; height++ {
+		block, err := chain.Block(int64(height))
+		if err != nil {
+			return nil, err
+		}
+
+		bi := BlockInfo{
+			Height:       height,
+			Hash:         rawblock.HashString(block.Header.Hash()),
+			Time:         int64(block.Header.Time),
+			Size:         block.Size(),
+			StrippedSize: block.StrippedSize(),
+			Weight:       block.Weight(),
+			TXCount:      len(block.Transactions),
+		}
+		if fetchTransactions {
+			for _, tx := range block.Transactions {
+				if tx.HasWitness() {
+					bi.WitnessTXCount++
+				}
+				if vsize := tx.VSize(); vsize > bi.LargestTXVSize {
+					bi.LargestTXID = rawblock.HashString(tx.TxID())
+					bi.LargestTXVSize = vsize
+				}
+			}
+		}
+		if block.MWEB != nil {
+			bi.SetMWEB(block.MWEB.Verbose())
+		}
+		result = append(result, bi)
+	}
+	return result, nil
 }
 
-func GetBlockHash(block int) (string, error) {
-	result, err := SendRPCRequest("getblockhash", block)
-	if err != nil {
-		return "", err
+// GetBlockHash returns the hash at height from the block files or the node.
+func GetBlockHash(height int64) (string, error) {
+	if chain != nil {
+		return chain.Hash(height)
 	}
+	return rpc.GetBlockHash(height)
+}
 
-	return result["result"].(string), nil
+type BiggestBlockInfo struct {
+	BiggestBlock struct {
+		BlockHeight int    `json:"block_height"`
+		BlockHash   string `json:"block_hash"`
+		BlockSize   int    `json:"block_size"`
+	} `json:"biggest_block"`
+	BiggestBlockTX struct {
+		BlockHeight int    `json:"block_height"`
+		BlockHash   string `json:"block_hash"`
+		TXCount     int    `json:"tx_count"`
+	} `json:"biggest_block_tx"`
+	HeaviestBlock struct {
+		BlockHeight int    `json:"block_height"`
+		BlockHash   string `json:"block_hash"`
+		BlockWeight int    `json:"block_weight"`
+	} `json:"heaviest_block"`
+	LargestTX struct {
+		BlockHeight int    `json:"block_height"`
+		BlockHash   string `json:"block_hash"`
+		TXID        string `json:"txid"`
+		VSize       int    `json:"vsize"`
+	} `json:"largest_tx"`
+	SegWit struct {
+		FirstWitnessBlock int   `json:"first_witness_block"`
+		TXCount           int64 `json:"tx_count"`
+		WitnessTXCount    int64 `json:"witness_tx_count"`
+	} `json:"segwit"`
+	MWEB struct {
+		FirstHogExBlock int   `json:"first_hogex_block"`
+		HogExBlocks     int64 `json:"hogex_blocks"`
+		Inputs          int64 `json:"inputs"`
+		Outputs         int64 `json:"outputs"`
+		Kernels         int64 `json:"kernels"`
+		PegIn           int64 `json:"pegin"`
+		PegOut          int64 `json:"pegout"`
+	} `json:"mweb"`
 }
 
-type BlockInfo struct {
-	Hash    string
-	Size    int
-	TXCount int
+// ScanState is the running state saved in each checkpoint. Start is the
+// -start height of the scan so a checkpoint is not resumed into a different
+// range.
+type ScanState struct {
+	Start  int              `json:"start"`
+	Result BiggestBlockInfo `json:"result"`
+	Stats  *Stats           `json:"stats,omitempty"`
 }
 
-func GetBlock(block int) (BlockInfo, error) {
-	bi := BlockInfo{}
-	blockHash, err := GetBlockHash(block)
-	if err != nil {
-		return bi, err
+// Update records bi if it beats the current biggest blocks. Ties go to the
+// lowest height so the answer does not depend on the order blocks arrive in.
+func (b *BiggestBlockInfo) Update(bi BlockInfo) {
+	if bi.Size > b.BiggestBlock.BlockSize || (bi.Size == b.BiggestBlock.BlockSize && bi.Height < b.BiggestBlock.BlockHeight) {
+		b.BiggestBlock.BlockHash = bi.Hash
+		b.BiggestBlock.BlockHeight = bi.Height
+		b.BiggestBlock.BlockSize = bi.Size
 	}
 
-	result, err := SendRPCRequest("getblock", blockHash)
-	if err != nil {
-		return bi, err
+	if bi.TXCount > b.BiggestBlockTX.TXCount || (bi.TXCount == b.BiggestBlockTX.TXCount && bi.Height < b.BiggestBlockTX.BlockHeight) {
+		b.BiggestBlockTX.BlockHash = bi.Hash
+		b.BiggestBlockTX.BlockHeight = bi.Height
+		b.BiggestBlockTX.TXCount = bi.TXCount
 	}
 
-	result = result["result"].(map[string]interface{})
-	bi.Hash = blockHash
-	bi.Size = int(result["size"].(float64))
-	bi.TXCount = len(result["tx"].([]interface{}))
-	return bi, nil
-}
+	if bi.Weight > b.HeaviestBlock.BlockWeight || (bi.Weight == b.HeaviestBlock.BlockWeight && bi.Height < b.HeaviestBlock.BlockHeight) {
+		b.HeaviestBlock.BlockHash = bi.Hash
+		b.HeaviestBlock.BlockHeight = bi.Height
+		b.HeaviestBlock.BlockWeight = bi.Weight
+	}
 
-type BiggestBlockInfo struct {
-	BiggestBlock struct {
-		BlockHeight int
-		BlockHash   string
-		BlockSize   int
+	if bi.LargestTXVSize > b.LargestTX.VSize || (bi.LargestTXVSize == b.LargestTX.VSize && bi.Height < b.LargestTX.BlockHeight) {
+		b.LargestTX.BlockHash = bi.Hash
+		b.LargestTX.BlockHeight = bi.Height
+		b.LargestTX.TXID = bi.LargestTXID
+		b.LargestTX.VSize = bi.LargestTXVSize
 	}
-	BiggestBlockTX struct {
-		BlockHeight int
-		BlockHash   string
-		TXCount     int
+
+	b.SegWit.TXCount += int64(bi.TXCount)
+	b.SegWit.WitnessTXCount += int64(bi.WitnessTXCount)
+	if bi.WitnessTXCount > 0 && (b.SegWit.FirstWitnessBlock == 0 || bi.Height < b.SegWit.FirstWitnessBlock) {
+		b.SegWit.FirstWitnessBlock = bi.Height
+	}
+
+	if bi.HogEx {
+		if b.MWEB.HogExBlocks == 0 || bi.Height < b.MWEB.FirstHogExBlock {
+			b.MWEB.FirstHogExBlock = bi.Height
+		}
+		b.MWEB.HogExBlocks++
+		b.MWEB.Inputs += int64(bi.MWEBInputs)
+		b.MWEB.Outputs += int64(bi.MWEBOutputs)
+		b.MWEB.Kernels += int64(bi.MWEBKernels)
+		b.MWEB.PegIn += bi.PegIn
+		b.MWEB.PegOut += bi.PegOut
 	}
 }
 
 func main() {
+	var workers, start, end int
+	var progressInterval, checkpointInterval time.Duration
+	var checkpointPath, format, outputFile, datadir string
+	var resume, testnet, regtest, statsMode, segwit, mweb bool
+	var topN int
+	var period string
+	flag.StringVar(&RPCHost, "rpchost", "127.0.0.1", "The RPC host to connect to.")
+	flag.IntVar(&RPCPort, "rpcport", MAINNET_RPC_PORT, "The RPC port to connect to.")
+	flag.StringVar(&RPCUsername, "rpcuser", "user", "The RPC username.")
+	flag.StringVar(&RPCPassword, "rpcpass", "pass", "The RPC password.")
+	flag.BoolVar(&testnet, "testnet", false, "Use the testnet RPC port unless -rpcport is set.")
+	flag.BoolVar(&regtest, "regtest", false, "Use the regtest RPC port unless -rpcport is set.")
+	flag.StringVar(&datadir, "datadir", "", "Read blocks from the blk*.dat files in this node data directory instead of over RPC. Fees are not available.")
+	flag.IntVar(&start, "start", 0, "Block height to start scanning from.")
+	flag.IntVar(&end, "end", -1, "Block height to stop scanning at, inclusive. Defaults to the current tip.")
+	flag.StringVar(&format, "format", "text", "Output format of the result: text, json or csv.")
+	flag.StringVar(&outputFile, "output", "", "File to write the result to. Defaults to stdout for json and csv.")
+	flag.BoolVar(&segwit, "segwit", false, "Fetch full blocks to count witness transactions and find the largest transaction by vsize.")
+	flag.BoolVar(&mweb, "mweb", false, "Fetch full blocks and report MWEB extension block metrics.")
+	flag.BoolVar(&statsMode, "stats", false, "Collect top-N blocks and per-period size and tx count statistics. Fetches full blocks so fees can be totalled.")
+	flag.IntVar(&topN, "topn", 10, "Number of blocks to keep per metric in -stats mode.")
+	flag.StringVar(&period, "period", PERIOD_MONTHLY, "Statistics period in -stats mode: daily, monthly or 10k.")
+	flag.IntVar(&workers, "workers", runtime.NumCPU(), "Number of concurrent block fetching workers.")
+	flag.DurationVar(&progressInterval, "progress", time.Second*30, "How often to report scan progress.")
+	flag.StringVar(&checkpointPath, "checkpoint", "biggest.checkpoint.json", "The checkpoint file to save scan progress to.")
+	flag.DurationVar(&checkpointInterval, "checkpointinterval", time.Minute, "How often to save a checkpoint.")
+	flag.BoolVar(&resume, "resume", false, "Resume the scan from the checkpoint file.")
+	flag.Parse()
+
+	if testnet && regtest {
+		log.Fatal("-testnet and -regtest are mutually exclusive.")
+	}
+
+	if format != "text" && format != "json" && format != "csv" {
+		log.Fatalf("Unknown output format %q.", format)
+	}
+
+	portSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "rpcport" {
+			portSet = true
+		}
+	})
+	if !portSet {
+		if testnet {
+			RPCPort = TESTNET_RPC_PORT
+		} else if regtest {
+			RPCPort = REGTEST_RPC_PORT
+		}
+	}
+
+	if datadir != "" {
+		params := rawblock.MainNetParams
+		if testnet {
+			params = rawblock.TestNetParams
+		} else if regtest {
+			params = rawblock.RegTestParams
+		}
+
+		log.Printf("Indexing block files in %s", rawblock.BlocksDir(datadir))
+		var err error
+		chain, err = rawblock.OpenChain(datadir, params)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer chain.Close()
+	} else {
+		rpc = litecoinrpc.New(RPCHost, RPCPort, RPCUsername, RPCPassword)
+		log.Printf("RPC URL: %s", rpc.URL())
+	}
+
 	currentHeight, err := GetBlockHeight()
 	if err != nil {
 		log.Fatal(err)
 	}
-
 	log.Printf("Current block height: %d\n", currentHeight)
-	log.Println("Checking for biggest block size and largest tx amount within a block.. (this may take a few minutes)")
-	bbi := BiggestBlockInfo{}
 
-	for i := 0; i < currentHeight; i++ {
-		bi, err := GetBlock(i)
+	if end < 0 || end > currentHeight {
+		end = currentHeight
+	}
+	if start < 0 || start > end {
+		log.Fatalf("Invalid block range %d-%d.", start, end)
+	}
+
+	bbi := BiggestBlockInfo{}
+	var stats *Stats
+	if statsMode {
+		stats, err = NewStats(topN, period)
 		if err != nil {
 			log.Fatal(err)
 		}
+	}
+	fetchTransactions = statsMode || segwit || mweb
+
+	scanStart := start
+	cpFile := &checkpoint.File{Keep: checkpoint.DefaultKeep}
+	if resume {
+		cpFile, err = checkpoint.Load(checkpointPath)
+		if err != nil {
+			log.Fatalf("Failed to load checkpoint file %s. Err: %s", checkpointPath, err)
+		}
 
-		if bi.Size > bbi.BiggestBlock.BlockSize {
-			bbi.BiggestBlock.BlockHash = bi.Hash
-			bbi.BiggestBlock.BlockHeight = i
-			bbi.BiggestBlock.BlockSize = bi.Size
+		var state ScanState
+		cp, ok, err := cpFile.Resume(int64(currentHeight), GetBlockHash, &state)
+		if err != nil {
+			log.Fatalf("Failed to validate checkpoint. Err: %s", err)
 		}
 
-		if bi.TXCount > bbi.BiggestBlockTX.TXCount {
-			bbi.BiggestBlockTX.BlockHash = bi.Hash
-			bbi.BiggestBlockTX.BlockHeight = i
-			bbi.BiggestBlockTX.TXCount = bi.TXCount
+		switch {
+		case !ok:
+			log.Println("No valid checkpoint found, starting from the beginning.")
+		case state.Start != scanStart:
+			log.Printf("Checkpoint was made for a scan starting at %d, starting from the beginning.", state.Start)
+			cpFile.Checkpoints = nil
+		case statsMode && (state.Stats == nil || state.Stats.PeriodType != period):
+			log.Println("Checkpoint has no matching -stats data, starting from the beginning.")
+			cpFile.Checkpoints = nil
+		default:
+			bbi = state.Result
+			if statsMode {
+				stats = state.Stats
+			}
+			start = int(cp.Height) + 1
+			log.Printf("Resuming from checkpoint at height %d hash %s.", cp.Height, cp.Hash)
 		}
+	}
 
-		if i%50000 == 0 && i > 0 {
-			progress := (float64(i) / float64(currentHeight)) * 100 / 1
-			log.Printf("%.2f%% complete. %d/%d", progress, i, currentHeight)
+	log.Printf("Checking blocks %d-%d for biggest block size and largest tx amount within a block using %d workers.. (this may take a few minutes)", start, end, workers)
+
+	var last BlockInfo
+	saveCheckpoint := func() {
+		if last.Hash == "" {
+			return
+		}
+		err := cpFile.Add(int64(last.Height), last.Hash, ScanState{Start: scanStart, Result: bbi, Stats: stats})
+		if err == nil {
+			err = cpFile.Save(checkpointPath)
+		}
+		if err != nil {
+			log.Printf("Failed to save checkpoint. Err: %s", err)
+		}
+	}
+
+	if rpc != nil {
+		rpc.BatchSize = BATCH_SIZE
+	}
+	if start > end {
+		log.Println("Checkpoint already covers the requested range.")
+	}
+	progress := NewProgress(end-start+1, progressInterval)
+	lastSave := time.Now()
+	err = ScanBlocks(start, end, workers, func(blocks []BlockInfo) {
+		for _, bi := range blocks {
+			bbi.Update(bi)
+			if stats != nil {
+				stats.Add(bi)
+			}
+		}
+		last = blocks[len(blocks)-1]
+		progress.Add(len(blocks))
+
+		if time.Since(lastSave) >= checkpointInterval {
+			saveCheckpoint()
+			lastSave = time.Now()
+		}
+	})
+	saveCheckpoint()
+	if err != nil {
+		done := start - 1
+		if last.Hash != "" {
+			done = last.Height
 		}
+		log.Fatalf("Scan failed after height %d, rerun with -resume to continue. Err: %s", done, err)
 	}
+	progress.Report()
 
 	log.Printf("Biggest block is %v\n", bbi.BiggestBlock)
 	log.Printf("Biggest tx block is %v\n", bbi.BiggestBlockTX)
+	log.Printf("Heaviest block is %v\n", bbi.HeaviestBlock)
+	if fetchTransactions {
+		log.Printf("Largest tx is %v\n", bbi.LargestTX)
+		if bbi.SegWit.TXCount > 0 {
+			share := float64(bbi.SegWit.WitnessTXCount) / float64(bbi.SegWit.TXCount) * 100
+			log.Printf("%d/%d (%.2f%%) transactions carry witness data, first seen in block %d\n", bbi.SegWit.WitnessTXCount, bbi.SegWit.TXCount, share, bbi.SegWit.FirstWitnessBlock)
+		}
+	}
+	if bbi.MWEB.HogExBlocks > 0 {
+		log.Printf("%d blocks carry an MWEB extension block, first seen in block %d\n", bbi.MWEB.HogExBlocks, bbi.MWEB.FirstHogExBlock)
+		log.Printf("MWEB inputs: %d outputs: %d kernels: %d peg-in: %d peg-out: %d litoshis\n", bbi.MWEB.Inputs, bbi.MWEB.Outputs, bbi.MWEB.Kernels, bbi.MWEB.PegIn, bbi.MWEB.PegOut)
+	}
+
+	if stats != nil {
+		stats.Finish()
+		if stats.Dropped > 0 {
+			log.Printf("%d blocks were left out of the period totals as their period had already closed", stats.Dropped)
+		}
+		for _, x := range stats.Top {
+			for i, y := range x.Blocks {
+				log.Printf("Top %s #%d: height %d hash %s value %d", x.Metric, i+1, y.Height, y.Hash, MetricValue(y, x.Metric))
+			}
+		}
+	}
+
+	if format == "text" {
+		return
+	}
+
+	if stats != nil {
+		err = WriteStats(bbi, stats, format, outputFile)
+	} else {
+		err = WriteResult(bbi, format, outputFile)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write result. Err: %s", err)
+	}
 }
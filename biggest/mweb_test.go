@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// fixtureChain returns the getblock fixtures from litecoinrpc/testdata as a
+// chain of heights 0 to 2: a block before MWEB, the first HogEx block and an
+// MWEB block with inputs, a peg-in and a peg-out.
+func fixtureChain(t *testing.T) []map[string]interface{} {
+	var blocks []map[string]interface{}
+	for _, x := range []string{"premweb", "hogex", "mweb"} {
+		data, err := ioutil.ReadFile("../litecoinrpc/testdata/getblock_" + x + ".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp struct {
+			Result map[string]interface{} `json:"result"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, resp.Result)
+	}
+	return blocks
+}
+
+func TestScanBlocksMWEB(t *testing.T) {
+	for _, verbose := range []bool{false, true} {
+		newFakeNode(t, fixtureChain(t))
+		fetchTransactions = verbose
+
+		stats, err := NewStats(5, PERIOD_MONTHLY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var bbi BiggestBlockInfo
+		var blocks []BlockInfo
+		err = ScanBlocks(0, 2, 2, func(x []BlockInfo) {
+			for _, bi := range x {
+				blocks = append(blocks, bi)
+				bbi.Update(bi)
+				stats.Add(bi)
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats.Finish()
+
+		type mwebInfo struct {
+			HogEx                  bool
+			Inputs, Outputs, Kerns int
+			PegIn, PegOut          int64
+		}
+		want := []mwebInfo{
+			{false, 0, 0, 0, 0, 0},
+			{true, 0, 1, 1, 0, 0},
+			{true, 2, 3, 2, 500000, 1000},
+		}
+		if len(blocks) != len(want) {
+			t.Fatalf("verbose %v: scanned %d blocks, want %d", verbose, len(blocks), len(want))
+		}
+		for i, bi := range blocks {
+			got := mwebInfo{bi.HogEx, bi.MWEBInputs, bi.MWEBOutputs, bi.MWEBKernels, bi.PegIn, bi.PegOut}
+			if got != want[i] {
+				t.Errorf("verbose %v: block %d MWEB %+v, want %+v", verbose, i, got, want[i])
+			}
+		}
+
+		m := bbi.MWEB
+		if m.FirstHogExBlock != 1 || m.HogExBlocks != 2 || m.Inputs != 2 || m.Outputs != 4 || m.Kernels != 3 || m.PegIn != 500000 || m.PegOut != 1000 {
+			t.Errorf("verbose %v: MWEB totals %+v", verbose, m)
+		}
+
+		top := make(map[string][]int)
+		for _, x := range stats.Top {
+			for _, y := range x.Blocks {
+				top[x.Metric] = append(top[x.Metric], y.Height)
+			}
+		}
+		wantTop := map[string][]int{
+			"mweb_outputs": {2, 1},
+			"mweb_kernels": {2, 1},
+			"pegin":        {2},
+			"pegout":       {2},
+		}
+		for metric, heights := range wantTop {
+			if !reflect.DeepEqual(top[metric], heights) {
+				t.Errorf("verbose %v: top %s = %v, want %v", verbose, metric, top[metric], heights)
+			}
+		}
+
+		records := PeriodCSVRecords(stats)
+		column := make(map[string]int)
+		for i, x := range records[0] {
+			column[x] = i
+		}
+		wantPeriods := []struct {
+			period                                    string
+			hogex, inputs, outputs, kerns, in, pegout int
+		}{
+			{"2021-01", 0, 0, 0, 0, 0, 0},
+			{"2022-05", 1, 0, 1, 1, 0, 0},
+			{"2022-06", 1, 2, 3, 2, 500000, 1000},
+		}
+		if len(records) != len(wantPeriods)+1 {
+			t.Fatalf("verbose %v: %d period rows, want %d", verbose, len(records)-1, len(wantPeriods))
+		}
+		for i, x := range wantPeriods {
+			row := records[i+1]
+			got := []string{row[column["period"]], row[column["hogex_blocks"]], row[column["mweb_inputs"]],
+				row[column["mweb_outputs"]], row[column["mweb_kernels"]], row[column["total_pegin"]], row[column["total_pegout"]]}
+			wantRow := []string{x.period, strconv.Itoa(x.hogex), strconv.Itoa(x.inputs), strconv.Itoa(x.outputs),
+				strconv.Itoa(x.kerns), strconv.Itoa(x.in), strconv.Itoa(x.pegout)}
+			if !reflect.DeepEqual(got, wantRow) {
+				t.Errorf("verbose %v: period row %v, want %v", verbose, got, wantRow)
+			}
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// WriteResult writes bbi as json or csv to path, or to stdout when path is
+// empty.
+func WriteResult(bbi BiggestBlockInfo, format, path string) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == "json" {
+		return WriteJSON(w, bbi)
+	}
+	return WriteCSV(w, bbi)
+}
+
+func WriteJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", " ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
+
+func WriteCSV(w io.Writer, bbi BiggestBlockInfo) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{
+		{"metric", "block_height", "block_hash", "value"},
+		{"block_size", strconv.Itoa(bbi.BiggestBlock.BlockHeight), bbi.BiggestBlock.BlockHash, strconv.Itoa(bbi.BiggestBlock.BlockSize)},
+		{"tx_count", strconv.Itoa(bbi.BiggestBlockTX.BlockHeight), bbi.BiggestBlockTX.BlockHash, strconv.Itoa(bbi.BiggestBlockTX.TXCount)},
+		{"block_weight", strconv.Itoa(bbi.HeaviestBlock.BlockHeight), bbi.HeaviestBlock.BlockHash, strconv.Itoa(bbi.HeaviestBlock.BlockWeight)},
+	}
+	if bbi.LargestTX.TXID != "" {
+		records = append(records,
+			[]string{"tx_vsize", strconv.Itoa(bbi.LargestTX.BlockHeight), bbi.LargestTX.BlockHash, strconv.Itoa(bbi.LargestTX.VSize)},
+			[]string{"total_txs", "", "", strconv.FormatInt(bbi.SegWit.TXCount, 10)},
+			[]string{"total_witness_txs", strconv.Itoa(bbi.SegWit.FirstWitnessBlock), "", strconv.FormatInt(bbi.SegWit.WitnessTXCount, 10)},
+		)
+	}
+	if bbi.MWEB.HogExBlocks > 0 {
+		first := strconv.Itoa(bbi.MWEB.FirstHogExBlock)
+		records = append(records,
+			[]string{"hogex_blocks", first, "", strconv.FormatInt(bbi.MWEB.HogExBlocks, 10)},
+			[]string{"mweb_inputs", first, "", strconv.FormatInt(bbi.MWEB.Inputs, 10)},
+			[]string{"mweb_outputs", first, "", strconv.FormatInt(bbi.MWEB.Outputs, 10)},
+			[]string{"mweb_kernels", first, "", strconv.FormatInt(bbi.MWEB.Kernels, 10)},
+			[]string{"pegin", first, "", strconv.FormatInt(bbi.MWEB.PegIn, 10)},
+			[]string{"pegout", first, "", strconv.FormatInt(bbi.MWEB.PegOut, 10)},
+		)
+	}
+	cw.WriteAll(records)
+	return cw.Error()
+}
+
+type StatsResult struct {
+	Result BiggestBlockInfo `json:"result"`
+	Stats  *Stats           `json:"stats"`
+}
+
+// WriteStats writes the -stats results. json goes to path or stdout like
+// WriteResult. csv produces two tables, so path is used as a base name for
+// path_top.csv and path_periods.csv.
+func WriteStats(bbi BiggestBlockInfo, stats *Stats, format, path string) error {
+	if format == "json" {
+		var w io.Writer = os.Stdout
+		if path != "" {
+			f, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		return WriteJSON(w, StatsResult{Result: bbi, Stats: stats})
+	}
+
+	if path == "" {
+		path = "biggest_stats"
+	}
+	path = strings.TrimSuffix(path, ".csv")
+
+	err := writeCSVFile(path+"_top.csv", TopCSVRecords(stats))
+	if err != nil {
+		return err
+	}
+	return writeCSVFile(path+"_periods.csv", PeriodCSVRecords(stats))
+}
+
+func writeCSVFile(path string, records [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cw := csv.NewWriter(f)
+	cw.WriteAll(records)
+	return cw.Error()
+}
+
+func TopCSVRecords(stats *Stats) [][]string {
+	records := [][]string{{"metric", "rank", "block_height", "block_hash", "block_time", "value"}}
+	for _, x := range stats.Top {
+		for i, y := range x.Blocks {
+			records = append(records, []string{
+				x.Metric,
+				strconv.Itoa(i + 1),
+				strconv.Itoa(y.Height),
+				y.Hash,
+				strconv.FormatInt(y.Time, 10),
+				strconv.FormatInt(MetricValue(y, x.Metric), 10),
+			})
+		}
+	}
+	return records
+}
+
+func summaryHeader(prefix string, bounds []int64) []string {
+	header := []string{prefix + "_min", prefix + "_max", prefix + "_mean"}
+	for _, p := range Percentiles {
+		header = append(header, prefix+"_"+PercentileKey(p))
+	}
+	for _, b := range bounds {
+		header = append(header, fmt.Sprintf("%s_le_%d", prefix, b))
+	}
+	return append(header, fmt.Sprintf("%s_gt_%d", prefix, bounds[len(bounds)-1]))
+}
+
+func summaryRecord(s *Summary) []string {
+	record := []string{
+		strconv.FormatInt(s.Min, 10),
+		strconv.FormatInt(s.Max, 10),
+		strconv.FormatFloat(s.Mean, 'f', 2, 64),
+	}
+	for _, p := range Percentiles {
+		record = append(record, strconv.FormatInt(s.Percentiles[PercentileKey(p)], 10))
+	}
+	for _, x := range s.Histogram {
+		record = append(record, strconv.Itoa(x))
+	}
+	return record
+}
+
+func PeriodCSVRecords(stats *Stats) [][]string {
+	header := []string{"period", "start_height", "end_height", "blocks", "total_fees", "total_txs", "total_witness_txs", "witness_tx_share",
+		"hogex_blocks", "mweb_inputs", "mweb_outputs", "mweb_kernels", "total_pegin", "total_pegout"}
+	header = append(header, summaryHeader("size", SizeHistogramBounds)...)
+	header = append(header, summaryHeader("weight", WeightHistogramBounds)...)
+	header = append(header, summaryHeader("tx_count", TXCountHistogramBounds)...)
+
+	records := [][]string{header}
+	for _, x := range stats.Periods {
+		record := []string{
+			x.Period,
+			strconv.Itoa(x.StartHeight),
+			strconv.Itoa(x.EndHeight),
+			strconv.Itoa(x.Blocks),
+			strconv.FormatInt(x.TotalFees, 10),
+			strconv.FormatInt(x.TotalTXs, 10),
+			strconv.FormatInt(x.TotalWitnessTX, 10),
+			strconv.FormatFloat(x.WitnessShare(), 'f', 2, 64),
+			strconv.Itoa(x.HogExBlocks),
+			strconv.FormatInt(x.MWEBInputs, 10),
+			strconv.FormatInt(x.MWEBOutputs, 10),
+			strconv.FormatInt(x.MWEBKernels, 10),
+			strconv.FormatInt(x.TotalPegIn, 10),
+			strconv.FormatInt(x.TotalPegOut, 10),
+		}
+		record = append(record, summaryRecord(x.Size)...)
+		record = append(record, summaryRecord(x.Weight)...)
+		record = append(record, summaryRecord(x.TXCount)...)
+		records = append(records, record)
+	}
+	return records
+}
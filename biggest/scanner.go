@@ -0,0 +1,152 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+type blockRange struct {
+	start, end int
+}
+
+type scanResult struct {
+	r      blockRange
+	blocks []BlockInfo
+	err    error
+}
+
+// ScanBlocks fetches the heights start through end inclusive with the given
+// number of workers, each requesting BATCH_SIZE blocks at a time. Batches
+// complete out of order but are buffered and passed to handle in height
+// order from the calling goroutine, so handle needs no locking and always
+// sees a contiguous prefix of the range. The first error stops the scan.
+func ScanBlocks(start, end, workers int, handle func([]BlockInfo)) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan blockRange)
+	results := make(chan scanResult)
+	quit := make(chan struct{})
+
+	// Bounds how far workers can run ahead of the slowest outstanding batch.
+	inflight := make(chan struct{}, workers*2)
+
+	go func() {
+		defer close(jobs)
+		for i := start; i <= end; i += BATCH_SIZE {
+			r := blockRange{start: i, end: i + BATCH_SIZE - 1}
+			if r.end > end {
+				r.end = end
+			}
+			select {
+			case inflight <- struct{}{}:
+			case <-quit:
+				return
+			}
+			select {
+			case jobs <- r:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				blocks, err := GetBlocks(r.start, r.end)
+				select {
+				case results <- scanResult{r: r, blocks: blocks, err: err}:
+				case <-quit:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	pending := make(map[int]scanResult)
+	next := start
+	for res := range results {
+		if err != nil {
+			continue
+		}
+		if res.err != nil {
+			err = res.err
+			close(quit)
+			continue
+		}
+
+		pending[res.r.start] = res
+		for {
+			p, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			handle(p.blocks)
+			next = p.r.end + 1
+			<-inflight
+		}
+	}
+	return err
+}
+
+type Progress struct {
+	Total      int
+	Done       int
+	Interval   time.Duration
+	started    time.Time
+	lastReport time.Time
+}
+
+func NewProgress(total int, interval time.Duration) *Progress {
+	if total < 0 {
+		total = 0
+	}
+	now := time.Now()
+	return &Progress{Total: total, Interval: interval, started: now, lastReport: now}
+}
+
+func (p *Progress) Add(n int) {
+	p.Done += n
+	if time.Since(p.lastReport) >= p.Interval {
+		p.Report()
+	}
+}
+
+func (p *Progress) Rate() float64 {
+	elapsed := time.Since(p.started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Done) / elapsed
+}
+
+func (p *Progress) ETA() time.Duration {
+	rate := p.Rate()
+	if rate <= 0 {
+		return 0
+	}
+	remaining := float64(p.Total - p.Done)
+	return time.Duration(remaining/rate) * time.Second
+}
+
+func (p *Progress) Report() {
+	p.lastReport = time.Now()
+	var progress float64
+	if p.Total > 0 {
+		progress = float64(p.Done) / float64(p.Total) * 100
+	}
+	log.Printf("%.2f%% complete. %d/%d blocks, %.2f blocks/s, ETA %s", progress, p.Done, p.Total, p.Rate(), p.ETA())
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+)
+
+type rpcRequest struct {
+	ID     interface{}   `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// fakeNode serves getblockchaininfo, getblockhash and getblock for the
+// verbosity 1 blocks in chain, indexed by height. Heights past the end of
+// chain are out of range.
+// Verbosity 2 turns each txid into a transaction object. Batches are
+// answered in reverse order, and delay, if set, is slept before answering a
+// batch starting at the given height.
+type fakeNode struct {
+	chain  []map[string]interface{}
+	byHash map[string]int
+	delay  func(height int) time.Duration
+
+	mtx sync.Mutex
+	// answered holds the first height of each getblock batch in the order
+	// they were answered.
+	answered []int
+}
+
+// newFakeNode starts a fakeNode for blocks and points rpc at it.
+func newFakeNode(t *testing.T, blocks []map[string]interface{}) *fakeNode {
+	n := &fakeNode{chain: blocks, byHash: make(map[string]int)}
+	for height, block := range blocks {
+		n.byHash[block["hash"].(string)] = height
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var batch []rpcRequest
+		if err := json.Unmarshal(body, &batch); err != nil {
+			var req rpcRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Error(err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(n.call(req))
+			return
+		}
+
+		if len(batch) > 0 && n.delay != nil {
+			time.Sleep(n.delay(n.height(batch[0])))
+		}
+		var results []interface{}
+		for i := len(batch) - 1; i >= 0; i-- {
+			results = append(results, n.call(batch[i]))
+		}
+		if len(batch) > 0 && batch[0].Method == "getblock" {
+			n.mtx.Lock()
+			n.answered = append(n.answered, n.height(batch[0]))
+			n.mtx.Unlock()
+		}
+		json.NewEncoder(w).Encode(results)
+	}))
+	t.Cleanup(srv.Close)
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	rpc = litecoinrpc.New(host, p, "u", "p")
+	rpc.BatchSize = BATCH_SIZE
+	chain = nil
+	fetchTransactions = false
+	t.Cleanup(func() { rpc = nil })
+	return n
+}
+
+// height returns the height a getblockhash or getblock request is for.
+func (n *fakeNode) height(req rpcRequest) int {
+	if req.Method == "getblockhash" {
+		return int(req.Params[0].(float64))
+	}
+	return n.byHash[req.Params[0].(string)]
+}
+
+func (n *fakeNode) call(req rpcRequest) map[string]interface{} {
+	var result interface{}
+	switch req.Method {
+	case "getblockchaininfo":
+		result = map[string]interface{}{"chain": "main", "blocks": len(n.chain) - 1}
+	case "getblockhash":
+		height := n.height(req)
+		if height >= len(n.chain) {
+			return map[string]interface{}{"id": req.ID, "result": nil, "error": map[string]interface{}{"code": -8, "message": "Block height out of range"}}
+		}
+		result = n.chain[height]["hash"]
+	case "getblock":
+		block := n.chain[n.height(req)]
+		if req.Params[1].(float64) == 2 {
+			verbose := make(map[string]interface{})
+			for k, v := range block {
+				verbose[k] = v
+			}
+			var txs []map[string]interface{}
+			for _, txid := range block["tx"].([]interface{}) {
+				txs = append(txs, map[string]interface{}{"txid": txid, "hash": txid})
+			}
+			verbose["tx"] = txs
+			result = verbose
+		} else {
+			result = block
+		}
+	}
+	return map[string]interface{}{"id": req.ID, "result": result, "error": nil}
+}
+
+// syntheticBlock is a verbosity 1 block at height with the given size and
+// number of transactions.
+func syntheticBlock(height, size, txs int) map[string]interface{} {
+	var tx []interface{}
+	for i := 0; i < txs; i++ {
+		tx = append(tx, fmt.Sprintf("%032x%032x", height, i))
+	}
+	return map[string]interface{}{
+		"hash":         fmt.Sprintf("%064x", height+1),
+		"height":       height,
+		"time":         1500000000 + height*150,
+		"size":         size,
+		"strippedsize": size,
+		"weight":       size * 4,
+		"tx":           tx,
+	}
+}
+
+func TestScanBlocksOutOfOrder(t *testing.T) {
+	const tip = 6*BATCH_SIZE - 1
+
+	// The biggest size and the most transactions are each shared by three
+	// blocks in different batches.
+	var blocks []map[string]interface{}
+	for height := 0; height <= tip; height++ {
+		size, txs := 1000+height%100, 1+height%7
+		switch height {
+		case 2900, 1700, 320:
+			size = 5000
+		case 2600, 2001, 777:
+			txs = 50
+		}
+		blocks = append(blocks, syntheticBlock(height, size, txs))
+	}
+	node := newFakeNode(t, blocks)
+
+	// Earlier batches are slower, so with several workers the later ones
+	// are answered first.
+	node.delay = func(height int) time.Duration {
+		return time.Duration(tip-height) / BATCH_SIZE * 10 * time.Millisecond
+	}
+
+	var result BiggestBlockInfo
+	var heights []int
+	err := ScanBlocks(0, tip, 4, func(blocks []BlockInfo) {
+		for _, bi := range blocks {
+			heights = append(heights, bi.Height)
+			result.Update(bi)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sort.IntsAreSorted(node.answered) {
+		t.Fatalf("batches were answered in order %v, want some out of order", node.answered)
+	}
+	if len(heights) != tip+1 || !sort.IntsAreSorted(heights) {
+		t.Fatalf("handle saw %d heights, sorted %v", len(heights), sort.IntsAreSorted(heights))
+	}
+	if b := result.BiggestBlock; b.BlockHeight != 320 || b.BlockSize != 5000 || b.BlockHash != blocks[320]["hash"] {
+		t.Fatalf("BiggestBlock = %+v, want height 320", b)
+	}
+	if b := result.BiggestBlockTX; b.BlockHeight != 777 || b.TXCount != 50 {
+		t.Fatalf("BiggestBlockTX = %+v, want height 777", b)
+	}
+	if b := result.HeaviestBlock; b.BlockHeight != 320 || b.BlockWeight != 20000 {
+		t.Fatalf("HeaviestBlock = %+v, want height 320", b)
+	}
+}
+
+func TestUpdateTies(t *testing.T) {
+	// Ties go to the lowest height whatever order blocks are seen in.
+	blocks := []BlockInfo{
+		{Height: 2900, Hash: "c", Size: 5000, Weight: 100, TXCount: 3, LargestTXVSize: 9},
+		{Height: 320, Hash: "a", Size: 5000, Weight: 100, TXCount: 3, LargestTXVSize: 9},
+		{Height: 1700, Hash: "b", Size: 5000, Weight: 100, TXCount: 3, LargestTXVSize: 9},
+	}
+	orders := [][]int{{0, 1, 2}, {2, 1, 0}, {1, 0, 2}, {0, 2, 1}}
+	for _, order := range orders {
+		var result BiggestBlockInfo
+		for _, i := range order {
+			result.Update(blocks[i])
+		}
+		if result.BiggestBlock.BlockHeight != 320 || result.BiggestBlockTX.BlockHeight != 320 || result.HeaviestBlock.BlockHeight != 320 || result.LargestTX.BlockHeight != 320 {
+			t.Errorf("order %v: winners %d, %d, %d, %d, want 320", order, result.BiggestBlock.BlockHeight,
+				result.BiggestBlockTX.BlockHeight, result.HeaviestBlock.BlockHeight, result.LargestTX.BlockHeight)
+		}
+	}
+}
+
+func TestScanBlocksError(t *testing.T) {
+	var blocks []map[string]interface{}
+	for height := 0; height < 3*BATCH_SIZE; height++ {
+		blocks = append(blocks, syntheticBlock(height, 1000, 1))
+	}
+	newFakeNode(t, blocks)
+
+	// The scan stops at the first failed batch. Batches answered before it
+	// may be dropped, but handle only ever sees a contiguous prefix.
+	next := 0
+	err := ScanBlocks(0, 4*BATCH_SIZE-1, 3, func(blocks []BlockInfo) {
+		for _, bi := range blocks {
+			if bi.Height != next {
+				t.Fatalf("handle saw height %d, want %d", bi.Height, next)
+			}
+			next++
+		}
+	})
+	if err == nil || next > 3*BATCH_SIZE {
+		t.Fatalf("ScanBlocks past the tip = %v, handled %d heights", err, next)
+	}
+}
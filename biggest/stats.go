@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	PERIOD_DAILY   = "daily"
+	PERIOD_MONTHLY = "monthly"
+	PERIOD_BLOCKS  = "10k"
+
+	PERIOD_BLOCK_COUNT = 10000
+)
+
+var (
+	SizeHistogramBounds    = []int64{1000, 10000, 50000, 100000, 250000, 500000, 1000000, 2000000}
+	WeightHistogramBounds  = []int64{4000, 40000, 200000, 400000, 1000000, 2000000, 3000000, 4000000}
+	TXCountHistogramBounds = []int64{1, 10, 50, 100, 250, 500, 1000, 2500, 5000}
+	Percentiles            = []float64{25, 50, 75, 90, 99}
+	TopMetrics             = []string{"size", "weight", "tx_count", "witness_tx_count", "tx_vsize", "fees", "mweb_outputs", "mweb_kernels", "pegin", "pegout"}
+)
+
+func MetricValue(bi BlockInfo, metric string) int64 {
+	switch metric {
+	case "size":
+		return int64(bi.Size)
+	case "weight":
+		return int64(bi.Weight)
+	case "tx_count":
+		return int64(bi.TXCount)
+	case "witness_tx_count":
+		return int64(bi.WitnessTXCount)
+	case "tx_vsize":
+		return int64(bi.LargestTXVSize)
+	case "fees":
+		return bi.Fees
+	case "mweb_outputs":
+		return int64(bi.MWEBOutputs)
+	case "mweb_kernels":
+		return int64(bi.MWEBKernels)
+	case "pegin":
+		return bi.PegIn
+	case "pegout":
+		return bi.PegOut
+	}
+	return 0
+}
+
+// TopBlocks keeps the N blocks with the highest non-zero value for Metric,
+// highest first. Ties are ordered by lowest height.
+type TopBlocks struct {
+	Metric string      `json:"metric"`
+	N      int         `json:"n"`
+	Blocks []BlockInfo `json:"blocks"`
+}
+
+func (t *TopBlocks) Add(bi BlockInfo) {
+	v := MetricValue(bi, t.Metric)
+	if v == 0 {
+		return
+	}
+	i := sort.Search(len(t.Blocks), func(i int) bool {
+		bv := MetricValue(t.Blocks[i], t.Metric)
+		return bv < v || (bv == v && t.Blocks[i].Height > bi.Height)
+	})
+	if i >= t.N {
+		return
+	}
+
+	t.Blocks = append(t.Blocks, BlockInfo{})
+	copy(t.Blocks[i+1:], t.Blocks[i:])
+	t.Blocks[i] = bi
+	if len(t.Blocks) > t.N {
+		t.Blocks = t.Blocks[:t.N]
+	}
+}
+
+// Summary describes the distribution of a value over a period. Histogram[i]
+// counts values <= Bounds[i], with the final bucket counting everything above
+// the last bound.
+type Summary struct {
+	Min         int64            `json:"min"`
+	Max         int64            `json:"max"`
+	Mean        float64          `json:"mean"`
+	Percentiles map[string]int64 `json:"percentiles"`
+	Bounds      []int64          `json:"histogram_bounds"`
+	Histogram   []int            `json:"histogram"`
+}
+
+func PercentileKey(p float64) string {
+	return fmt.Sprintf("p%g", p)
+}
+
+func Summarise(values []int64, bounds []int64) Summary {
+	s := Summary{
+		Percentiles: make(map[string]int64),
+		Bounds:      bounds,
+		Histogram:   make([]int, len(bounds)+1),
+	}
+	if len(values) == 0 {
+		return s
+	}
+
+	sorted := make([]int64, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total int64
+	for _, x := range sorted {
+		total += x
+		s.Histogram[sort.Search(len(bounds), func(i int) bool { return x <= bounds[i] })]++
+	}
+
+	s.Min = sorted[0]
+	s.Max = sorted[len(sorted)-1]
+	s.Mean = float64(total) / float64(len(sorted))
+
+	// Nearest-rank percentiles.
+	for _, p := range Percentiles {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		s.Percentiles[PercentileKey(p)] = sorted[rank-1]
+	}
+	return s
+}
+
+// PeriodStats aggregates the blocks in one period. Sizes, Weights and
+// TXCounts hold the raw values while the period is open and are cleared once
+// it is summarised.
+type PeriodStats struct {
+	Index          int64    `json:"index"`
+	Period         string   `json:"period"`
+	StartHeight    int      `json:"start_height"`
+	EndHeight      int      `json:"end_height"`
+	Blocks         int      `json:"blocks"`
+	TotalFees      int64    `json:"total_fees"`
+	TotalTXs       int64    `json:"total_txs"`
+	TotalWitnessTX int64    `json:"total_witness_txs"`
+	HogExBlocks    int      `json:"hogex_blocks"`
+	MWEBInputs     int64    `json:"mweb_inputs"`
+	MWEBOutputs    int64    `json:"mweb_outputs"`
+	MWEBKernels    int64    `json:"mweb_kernels"`
+	TotalPegIn     int64    `json:"total_pegin"`
+	TotalPegOut    int64    `json:"total_pegout"`
+	Size           *Summary `json:"size,omitempty"`
+	Weight         *Summary `json:"weight,omitempty"`
+	TXCount        *Summary `json:"tx_count,omitempty"`
+	Sizes          []int64  `json:"sizes,omitempty"`
+	Weights        []int64  `json:"weights,omitempty"`
+	TXCounts       []int64  `json:"tx_counts,omitempty"`
+}
+
+// WitnessShare is the percentage of transactions in the period carrying
+// witness data.
+func (p *PeriodStats) WitnessShare() float64 {
+	if p.TotalTXs == 0 {
+		return 0
+	}
+	return float64(p.TotalWitnessTX) / float64(p.TotalTXs) * 100
+}
+
+func (p *PeriodStats) Closed() bool {
+	return p.Size != nil
+}
+
+func (p *PeriodStats) Close() {
+	size := Summarise(p.Sizes, SizeHistogramBounds)
+	weight := Summarise(p.Weights, WeightHistogramBounds)
+	txCount := Summarise(p.TXCounts, TXCountHistogramBounds)
+	p.Size = &size
+	p.Weight = &weight
+	p.TXCount = &txCount
+	p.Sizes = nil
+	p.Weights = nil
+	p.TXCounts = nil
+}
+
+// Stats holds the -stats results. Dropped counts blocks left out of the
+// period totals because their period had already been summarised.
+type Stats struct {
+	PeriodType string         `json:"period_type"`
+	Top        []*TopBlocks   `json:"top"`
+	Periods    []*PeriodStats `json:"periods"`
+	Dropped    int            `json:"dropped"`
+}
+
+func NewStats(topN int, periodType string) (*Stats, error) {
+	if periodType != PERIOD_DAILY && periodType != PERIOD_MONTHLY && periodType != PERIOD_BLOCKS {
+		return nil, fmt.Errorf("unknown period %q", periodType)
+	}
+
+	s := &Stats{PeriodType: periodType}
+	for _, x := range TopMetrics {
+		s.Top = append(s.Top, &TopBlocks{Metric: x, N: topN})
+	}
+	return s, nil
+}
+
+func (s *Stats) periodOf(bi BlockInfo) (int64, string) {
+	switch s.PeriodType {
+	case PERIOD_DAILY:
+		tm := time.Unix(bi.Time, 0).UTC()
+		return bi.Time / 86400, tm.Format("2006-01-02")
+	case PERIOD_MONTHLY:
+		tm := time.Unix(bi.Time, 0).UTC()
+		return int64(tm.Year())*12 + int64(tm.Month()) - 1, tm.Format("2006-01")
+	}
+	index := int64(bi.Height / PERIOD_BLOCK_COUNT)
+	start := index * PERIOD_BLOCK_COUNT
+	return index, fmt.Sprintf("%d-%d", start, start+PERIOD_BLOCK_COUNT-1)
+}
+
+// Add records bi. Blocks must be added in height order. Block times are not
+// strictly increasing, so the previous period stays open until a block two
+// periods later is seen.
+func (s *Stats) Add(bi BlockInfo) {
+	for _, x := range s.Top {
+		x.Add(bi)
+	}
+
+	index, name := s.periodOf(bi)
+	i := sort.Search(len(s.Periods), func(i int) bool { return s.Periods[i].Index >= index })
+	if i == len(s.Periods) || s.Periods[i].Index != index {
+		s.Periods = append(s.Periods, nil)
+		copy(s.Periods[i+1:], s.Periods[i:])
+		s.Periods[i] = &PeriodStats{Index: index, Period: name, StartHeight: bi.Height}
+	}
+
+	p := s.Periods[i]
+	if p.Closed() {
+		// Median-time-past keeps block times within hours of each other, so
+		// this needs a timestamp more than a full period behind. The block
+		// still counts towards the top blocks.
+		s.Dropped++
+		log.Printf("Block %d has time %d in closed period %s, leaving it out of the period totals.", bi.Height, bi.Time, p.Period)
+		return
+	}
+	if bi.Height < p.StartHeight {
+		p.StartHeight = bi.Height
+	}
+	if bi.Height > p.EndHeight {
+		p.EndHeight = bi.Height
+	}
+	p.Blocks++
+	p.TotalFees += bi.Fees
+	p.TotalTXs += int64(bi.TXCount)
+	p.TotalWitnessTX += int64(bi.WitnessTXCount)
+	if bi.HogEx {
+		p.HogExBlocks++
+		p.MWEBInputs += int64(bi.MWEBInputs)
+		p.MWEBOutputs += int64(bi.MWEBOutputs)
+		p.MWEBKernels += int64(bi.MWEBKernels)
+		p.TotalPegIn += bi.PegIn
+		p.TotalPegOut += bi.PegOut
+	}
+	p.Sizes = append(p.Sizes, int64(bi.Size))
+	p.Weights = append(p.Weights, int64(bi.Weight))
+	p.TXCounts = append(p.TXCounts, int64(bi.TXCount))
+
+	// Open periods are always the last ones.
+	for j := len(s.Periods) - 1; j >= 0 && !s.Periods[j].Closed(); j-- {
+		if s.Periods[j].Index < index-1 {
+			s.Periods[j].Close()
+		}
+	}
+}
+
+// Finish summarises every period still open.
+func (s *Stats) Finish() {
+	for _, x := range s.Periods {
+		if !x.Closed() {
+			x.Close()
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 2021-01-01 00:00:00 UTC.
+const day0 = 1609459200
+
+func TestTopBlocks(t *testing.T) {
+	// Added out of height order, so ties have to be placed by height.
+	blocks := []BlockInfo{
+		{Height: 4, Size: 200, Weight: 400, TXCount: 5, Fees: 20},
+		{Height: 2, Size: 300, Weight: 400, TXCount: 5, Fees: 10},
+		{Height: 3, Size: 300, Weight: 1000, TXCount: 1, Fees: 20},
+		{Height: 1, Size: 100, Weight: 400, TXCount: 5},
+		{Height: 5, Size: 50, Weight: 200, TXCount: 9},
+	}
+
+	tests := []struct {
+		metric string
+		n      int
+		want   []int
+	}{
+		{"size", 2, []int{2, 3}},
+		{"size", 10, []int{2, 3, 4, 1, 5}},
+		{"weight", 2, []int{3, 1}},
+		{"tx_count", 3, []int{5, 1, 2}},
+		{"fees", 10, []int{3, 4, 2}},
+		{"pegin", 10, nil},
+	}
+	for _, test := range tests {
+		top := TopBlocks{Metric: test.metric, N: test.n}
+		for _, x := range blocks {
+			top.Add(x)
+		}
+		var got []int
+		for _, x := range top.Blocks {
+			got = append(got, x.Height)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("top %d %s = %v, want %v", test.n, test.metric, got, test.want)
+		}
+	}
+}
+
+func TestSummarise(t *testing.T) {
+	var hundred []int64
+	for i := int64(100); i >= 1; i-- {
+		hundred = append(hundred, i)
+	}
+
+	tests := []struct {
+		name      string
+		values    []int64
+		min, max  int64
+		mean      float64
+		p         map[string]int64
+		histogram []int
+	}{
+		{"empty", nil, 0, 0, 0, map[string]int64{}, []int{0, 0, 0}},
+		{"single", []int64{70}, 70, 70, 70, map[string]int64{"p25": 70, "p50": 70, "p75": 70, "p90": 70, "p99": 70}, []int{0, 0, 1}},
+		{"1 to 100", hundred, 1, 100, 50.5, map[string]int64{"p25": 25, "p50": 50, "p75": 75, "p90": 90, "p99": 99}, []int{10, 40, 50}},
+		{"four", []int64{10, 40, 20, 30}, 10, 40, 25, map[string]int64{"p25": 10, "p50": 20, "p75": 30, "p90": 40, "p99": 40}, []int{1, 3, 0}},
+	}
+	for _, test := range tests {
+		s := Summarise(test.values, []int64{10, 50})
+		if s.Min != test.min || s.Max != test.max || s.Mean != test.mean {
+			t.Errorf("%s: min %d max %d mean %g, want %d %d %g", test.name, s.Min, s.Max, s.Mean, test.min, test.max, test.mean)
+		}
+		if !reflect.DeepEqual(s.Percentiles, test.p) {
+			t.Errorf("%s: percentiles %v, want %v", test.name, s.Percentiles, test.p)
+		}
+		if !reflect.DeepEqual(s.Histogram, test.histogram) {
+			t.Errorf("%s: histogram %v, want %v", test.name, s.Histogram, test.histogram)
+		}
+	}
+}
+
+type periodWant struct {
+	period             string
+	blocks, start, end int
+}
+
+func TestStatsPeriods(t *testing.T) {
+	tests := []struct {
+		period  string
+		blocks  []BlockInfo
+		want    []periodWant
+		dropped int
+	}{
+		{
+			// Height 2 is timestamped back into the first day while it is
+			// still open.
+			PERIOD_DAILY,
+			[]BlockInfo{
+				{Height: 0, Time: day0 + 100},
+				{Height: 1, Time: day0 + 86400 + 10},
+				{Height: 2, Time: day0 + 86000},
+				{Height: 3, Time: day0 + 2*86400 + 5},
+				{Height: 4, Time: day0 + 86400 + 100},
+			},
+			[]periodWant{{"2021-01-01", 2, 0, 2}, {"2021-01-02", 2, 1, 4}, {"2021-01-03", 1, 3, 3}},
+			0,
+		},
+		{
+			// Height 2 is timestamped back into the first day after the
+			// third day has closed it.
+			PERIOD_DAILY,
+			[]BlockInfo{
+				{Height: 0, Time: day0 + 100},
+				{Height: 1, Time: day0 + 2*86400},
+				{Height: 2, Time: day0 + 200},
+				{Height: 3, Time: day0 + 2*86400 + 300},
+			},
+			[]periodWant{{"2021-01-01", 1, 0, 0}, {"2021-01-03", 2, 1, 3}},
+			1,
+		},
+		{
+			PERIOD_MONTHLY,
+			[]BlockInfo{
+				{Height: 0, Time: day0 - 3600},
+				{Height: 1, Time: day0 + 31*86400 - 1},
+				{Height: 2, Time: day0 + 31*86400},
+				{Height: 3, Time: day0 + 59*86400},
+			},
+			[]periodWant{{"2020-12", 1, 0, 0}, {"2021-01", 1, 1, 1}, {"2021-02", 1, 2, 2}, {"2021-03", 1, 3, 3}},
+			0,
+		},
+		{
+			PERIOD_BLOCKS,
+			[]BlockInfo{
+				{Height: 0, Time: day0 + 500},
+				{Height: 9999, Time: day0},
+				{Height: 10000, Time: day0},
+				{Height: 25000, Time: day0},
+			},
+			[]periodWant{{"0-9999", 2, 0, 9999}, {"10000-19999", 1, 10000, 10000}, {"20000-29999", 1, 25000, 25000}},
+			0,
+		},
+	}
+	for _, test := range tests {
+		stats, err := NewStats(10, test.period)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, x := range test.blocks {
+			x.Size = 1000 + x.Height
+			stats.Add(x)
+		}
+		stats.Finish()
+
+		var got []periodWant
+		for _, x := range stats.Periods {
+			if !x.Closed() {
+				t.Errorf("%s: period %s still open after Finish", test.period, x.Period)
+			}
+			got = append(got, periodWant{x.Period, x.Blocks, x.StartHeight, x.EndHeight})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: periods %v, want %v", test.period, got, test.want)
+		}
+		if stats.Dropped != test.dropped {
+			t.Errorf("%s: dropped %d, want %d", test.period, stats.Dropped, test.dropped)
+		}
+		// Dropped blocks still count towards the top blocks.
+		if n := len(stats.Top[0].Blocks); n != len(test.blocks) {
+			t.Errorf("%s: %d top size blocks, want %d", test.period, n, len(test.blocks))
+		}
+	}
+
+	if _, err := NewStats(10, "weekly"); err == nil {
+		t.Error("NewStats accepted an unknown period")
+	}
+}
+
+func exportStats(t *testing.T) *Stats {
+	stats, err := NewStats(2, PERIOD_BLOCKS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := []BlockInfo{
+		{Height: 5, Hash: "aa", Time: day0, Size: 900, Weight: 3600, TXCount: 2, WitnessTXCount: 1, Fees: 100},
+		{Height: 6, Hash: "bb", Time: day0 + 150, Size: 1100, Weight: 4400, TXCount: 4, WitnessTXCount: 3, Fees: 300},
+		{Height: 10001, Hash: "cc", Time: day0 + 300, Size: 200, Weight: 800, TXCount: 1, HogEx: true, MWEBOutputs: 2, MWEBKernels: 1, PegIn: 5000},
+	}
+	for _, x := range blocks {
+		stats.Add(x)
+	}
+	stats.Finish()
+	return stats
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestWriteStatsCSV(t *testing.T) {
+	stats := exportStats(t)
+	base := filepath.Join(t.TempDir(), "out.csv")
+	if err := WriteStats(BiggestBlockInfo{}, stats, "csv", base); err != nil {
+		t.Fatal(err)
+	}
+	base = base[:len(base)-len(".csv")]
+
+	top := readCSV(t, base+"_top.csv")
+	want := [][]string{
+		{"metric", "rank", "block_height", "block_hash", "block_time", "value"},
+		{"size", "1", "6", "bb", "1609459350", "1100"},
+		{"size", "2", "5", "aa", "1609459200", "900"},
+	}
+	if !reflect.DeepEqual(top[:3], want) {
+		t.Errorf("top csv starts %v, want %v", top[:3], want)
+	}
+	var pegin [][]string
+	for _, x := range top {
+		if x[0] == "pegin" {
+			pegin = append(pegin, x)
+		}
+	}
+	if len(pegin) != 1 || pegin[0][2] != "10001" || pegin[0][5] != "5000" {
+		t.Errorf("top csv pegin rows %v, want block 10001 with 5000", pegin)
+	}
+
+	periods := readCSV(t, base+"_periods.csv")
+	if len(periods) != 3 {
+		t.Fatalf("periods csv has %d rows, want 3", len(periods))
+	}
+	column := make(map[string]int)
+	for i, x := range periods[0] {
+		column[x] = i
+	}
+	tests := []struct {
+		row    int
+		column string
+		want   string
+	}{
+		{1, "period", "0-9999"},
+		{1, "start_height", "5"},
+		{1, "end_height", "6"},
+		{1, "blocks", "2"},
+		{1, "total_fees", "400"},
+		{1, "witness_tx_share", "66.67"},
+		{1, "size_min", "900"},
+		{1, "size_max", "1100"},
+		{1, "size_mean", "1000.00"},
+		{1, "size_p50", "900"},
+		{1, "size_p99", "1100"},
+		{1, "size_le_1000", "1"},
+		{1, "size_le_10000", "1"},
+		{1, "hogex_blocks", "0"},
+		{2, "period", "10000-19999"},
+		{2, "hogex_blocks", "1"},
+		{2, "mweb_outputs", "2"},
+		{2, "mweb_kernels", "1"},
+		{2, "total_pegin", "5000"},
+		{2, "size_le_1000", "1"},
+	}
+	for _, test := range tests {
+		i, ok := column[test.column]
+		if !ok {
+			t.Errorf("periods csv has no %s column", test.column)
+			continue
+		}
+		if got := periods[test.row][i]; got != test.want {
+			t.Errorf("periods csv row %d %s = %s, want %s", test.row, test.column, got, test.want)
+		}
+	}
+}
+
+func TestWriteStatsJSON(t *testing.T) {
+	stats := exportStats(t)
+	path := filepath.Join(t.TempDir(), "out.json")
+	if err := WriteStats(BiggestBlockInfo{}, stats, "json", path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got StatsResult
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Stats, stats) {
+		t.Errorf("json stats = %+v, want %+v", got.Stats, stats)
+	}
+	if p := got.Stats.Periods[0]; p.Size == nil || p.Size.Percentiles["p50"] != 900 || len(p.Sizes) != 0 {
+		t.Errorf("json period %+v, want a summary without raw sizes", p)
+	}
+}
@@ -1,154 +1,332 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"strings"
+
+	"github.com/thrasher-/litecoin-tools/activation"
+	"github.com/thrasher-/litecoin-tools/checkpoint"
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+	"github.com/thrasher-/litecoin-tools/rawblock"
 )
 
 var (
-	version, block int
+	block          int
 	verbose        bool
 	RPCHost        string
 	RPCPort        int
 	RPCUsername    string
 	RPCPassword    string
 	BIP16target    int64
+	RPCCookieFile  string
+	batchSize      int
+	checkpointPath string
+	resume         bool
+	search         bool
+	datadir        string
+	chainName      string
+	network        string
+	deploymentList string
+	rpc            *litecoinrpc.Client
+	chain          *rawblock.Chain
 )
 
-func BuildURL() string {
-	return fmt.Sprintf("http://%s:%s@%s:%d", RPCUsername, RPCPassword, RPCHost, RPCPort)
+type ScanState struct {
+	Chain       string
+	Deployments string
+	BIP16target int64
+	Finder      *activation.Finder
 }
 
-func JSONDecode(data []byte, to interface{}) error {
-	err := json.Unmarshal(data, &to)
-
+func GetBlockHeight() (int, error) {
+	if chain != nil {
+		return int(chain.Height()), nil
+	}
+	info, err := rpc.GetBlockchainInfo()
 	if err != nil {
-		return err
+		return 0, err
 	}
-
-	return nil
+	return int(info.Blocks), nil
 }
 
-func SendRPCRequest(method, req interface{}) (map[string]interface{}, error) {
-	var params []interface{}
-	if req != nil {
-		params = append(params, req)
-	} else {
-		params = nil
+// GetBlockHash returns the hash at height from the block files or the node.
+func GetBlockHash(height int64) (string, error) {
+	if chain != nil {
+		return chain.Hash(height)
 	}
+	return rpc.GetBlockHash(height)
+}
 
-	data, err := json.Marshal(map[string]interface{}{
-		"method": method,
-		"id":     1,
-		"params": params,
-	})
-
-	if err != nil {
-		return nil, err
+func GetBlockTimes(start, end int) ([]activation.Block, error) {
+	if chain != nil {
+		var result []activation.Block
+		for height := start; height <= end; height++ {
+			header, err := chain.Header(int64(height))
+			if err != nil {
+				return nil, err
+			}
+			hash := header.Hash()
+			result = append(result, activation.Block{Height: int64(height), Hash: rawblock.HashString(hash), Time: int64(header.Time), Version: header.Version})
+		}
+		return result, nil
 	}
 
-	resp, err := http.Post(BuildURL(), "application/json", strings.NewReader(string(data)))
+	hashes, err := rpc.GetBlockHashes(int64(start), int64(end))
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	var blockHashes []string
+	for _, x := range hashes {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block hash for height %d: %s", x.Height, x.Err)
+		}
+		blockHashes = append(blockHashes, x.Hash)
 	}
 
-	result := make(map[string]interface{})
-	err = json.Unmarshal(body, &result)
+	headers, err := rpc.GetBlockHeaders(blockHashes)
 	if err != nil {
 		return nil, err
 	}
 
-	if result["error"] != nil {
-		errorMsg := result["error"].(map[string]interface{})
-		return nil, fmt.Errorf("Error code: %v, message: %v\n", errorMsg["code"], errorMsg["message"])
+	var result []activation.Block
+	for i, x := range headers {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block header %s at height %d: %s", x.Hash, hashes[i].Height, x.Err)
+		}
+		result = append(result, activation.Block{Height: hashes[i].Height, Hash: x.Hash, Time: x.Header.Time, Version: x.Header.Version})
 	}
 	return result, nil
 }
 
-func GetBlockHeight() (int, error) {
-	result, err := SendRPCRequest("getinfo", nil)
-	if err != nil {
-		return 0, err
+func describe(params *activation.ChainParams, d activation.Deployment) string {
+	switch d.Type {
+	case activation.TIME:
+		return fmt.Sprintf("Checking for %s target block timestamp >= %d", strings.ToUpper(d.Name), d.Time)
+	case activation.HEIGHT:
+		return fmt.Sprintf("Checking %s supermajority of version >= %d blocks and buried height %d", strings.ToUpper(d.Name), d.MinVersion, d.Height)
 	}
-
-	result = result["result"].(map[string]interface{})
-	block := result["blocks"].(float64)
-	return int(block), nil
-}
-
-func GetBlockHash(block int) (string, error) {
-	result, err := SendRPCRequest("getblockhash", block)
-	if err != nil {
-		return "", err
+	start := fmt.Sprintf("start time %d timeout %d", d.StartTime, d.Timeout)
+	if d.StartHeight > 0 {
+		start = fmt.Sprintf("start height %d timeout height %d", d.StartHeight, d.TimeoutHeight)
 	}
-
-	return result["result"].(string), nil
+	return fmt.Sprintf("Checking %s signalling on bit %d, %s, threshold %d/%d", strings.ToUpper(d.Name), d.Bit, start, params.DeploymentThreshold(d), params.Window)
 }
 
-type BlockInfo struct {
-	Hash      string
-	BlockTime int64
+func reportEvent(d activation.Deployment, e activation.Event) {
+	switch {
+	case d.Type == activation.TIME:
+		log.Printf("Block: %s height: %d time: %d which has >= %s target timestamp %d", e.Hash, e.Height, e.Time, strings.ToUpper(d.Name), d.Time)
+	case e.State == activation.ENFORCED || e.State == activation.REJECT_OLD:
+		log.Printf("%s %s at block: %s height: %d time: %d, %d/%d previous blocks have version >= %d", strings.ToUpper(d.Name), e.State, e.Hash, e.Height, e.Time, e.Count, e.Total, d.MinVersion)
+	case e.Total > 0:
+		log.Printf("%s %s at block: %s height: %d mediantime: %d, %d/%d blocks signalled in the previous window", strings.ToUpper(d.Name), e.State, e.Hash, e.Height, e.MedianTime, e.Count, e.Total)
+	default:
+		log.Printf("%s %s at block: %s height: %d time: %d mediantime: %d", strings.ToUpper(d.Name), e.State, e.Hash, e.Height, e.Time, e.MedianTime)
+	}
 }
 
-func GetBlockTime(block int) (BlockInfo, error) {
-	var blockInfo BlockInfo
-	blockHash, err := GetBlockHash(block)
-	if err != nil {
-		return blockInfo, err
+func reportSearch(d activation.Deployment, r SearchResult, linear int64) {
+	name := strings.ToUpper(d.Name)
+	log.Printf("Block: %s height: %d time: %d which has >= %s target timestamp %d", r.Raw.Hash, r.Raw.Height, r.Raw.Time, name, r.Target)
+	log.Printf("This is best effort: an earlier block timestamped more than %d seconds ahead of the median time past would be missed, which only a full scan rules out.", RAW_SEARCH_MARGIN)
+	if e := r.Enforced; e != nil {
+		log.Printf("Block: %s height: %d time: %d is the first whose previous block has median time past %d >= %s target timestamp %d", e.Hash, e.Height, e.Time, r.MedianTime, name, r.Target)
+	} else {
+		log.Printf("The next block is the first whose previous block has median time past %d >= %s target timestamp %d", r.MedianTime, name, r.Target)
 	}
 
-	result, err := SendRPCRequest("getblock", blockHash)
-	if err != nil {
-		return blockInfo, err
+	if rpc != nil {
+		log.Printf("Found in %d median time lookups using %d RPC calls in %d HTTP requests, instead of reading %d blocks.", r.Probes, rpc.Calls(), rpc.Requests(), linear)
+	} else {
+		log.Printf("Found in %d median time lookups, instead of reading %d blocks.", r.Probes, linear)
 	}
+}
 
-	result = result["result"].(map[string]interface{})
-	blockInfo.BlockTime = int64(result["time"].(float64))
-	blockInfo.Hash = result["hash"].(string)
-	return blockInfo, nil
+func deploymentByName(deployments []activation.Deployment, name string) activation.Deployment {
+	for _, d := range deployments {
+		if d.Name == name {
+			return d
+		}
+	}
+	return activation.Deployment{Name: name}
 }
 
 func main() {
 	flag.StringVar(&RPCHost, "rpchost", "127.0.0.1", "The RPC host to connect to.")
-	flag.IntVar(&RPCPort, "rpcport", 9332, "The RPC port to connect to.")
+	flag.IntVar(&RPCPort, "rpcport", 0, "The RPC port to connect to. Defaults to the -chain and -network's port.")
 	flag.StringVar(&RPCUsername, "rpcuser", "user", "The RPC username.")
 	flag.StringVar(&RPCPassword, "rpcpass", "pass", "The RPC password.")
-	flag.IntVar(&block, "block", 218570, "Block height to start checking from.")
+	flag.StringVar(&RPCCookieFile, "rpccookiefile", "", "The RPC cookie file to authenticate with instead of -rpcuser/-rpcpass.")
+	flag.StringVar(&chainName, "chain", "litecoin", "Chain whose deployment presets are used: litecoin or bitcoin.")
+	flag.StringVar(&network, "network", "main", "Network whose deployment presets are used: main or test.")
+	flag.StringVar(&deploymentList, "deployment", "bip16", "Comma separated deployments to find: bip16, bip34, bip65, bip66, csv, segwit, taproot, mweb or all.")
+	flag.IntVar(&block, "block", -1, "Block height to start checking from. Defaults to a height before the earliest selected deployment.")
 	flag.BoolVar(&verbose, "verbose", false, "Toggle verbose reporting.")
-	flag.Int64Var(&BIP16target, "bip16target", 1349049600, "Target timestamp for BIP16 activation.")
+	flag.IntVar(&batchSize, "batchsize", litecoinrpc.DefaultBatchSize, "Number of blocks to request per RPC batch.")
+	flag.Int64Var(&BIP16target, "bip16target", 0, "Target timestamp for BIP16 activation. Defaults to the preset's switch time.")
+	flag.StringVar(&checkpointPath, "checkpoint", "bip16.checkpoint.json", "The checkpoint file to save scan progress to.")
+	flag.BoolVar(&resume, "resume", false, "Resume the scan from the checkpoint file.")
+	flag.BoolVar(&search, "search", false, "Binary search for time based deployments using median time past instead of scanning every block.")
+	flag.StringVar(&datadir, "datadir", "", "Read block headers from the blk*.dat files in this Litecoin data directory instead of over RPC.")
 	flag.Parse()
 
-	log.Printf("RPC URL: %s", BuildURL())
+	if batchSize <= 0 {
+		batchSize = litecoinrpc.DefaultBatchSize
+	}
+
+	params, err := activation.GetChainParams(chainName, network)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	deployments, err := params.Select(deploymentList)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	context := activation.MEDIAN_TIME_SPAN
+	scanFrom := int64(-1)
+	for i, d := range deployments {
+		if d.Name == "bip16" && BIP16target > 0 {
+			deployments[i].Time = BIP16target
+		}
+		if d.Type == activation.HEIGHT && params.MajorityWindow > context {
+			context = params.MajorityWindow
+		}
+		if scanFrom < 0 || d.ScanFrom < scanFrom {
+			scanFrom = d.ScanFrom
+		}
+	}
+	if block < 0 {
+		block = int(scanFrom)
+	}
+	if d, ok := params.Deployment("bip16"); ok && BIP16target <= 0 {
+		BIP16target = d.Time
+	}
+
+	if datadir != "" {
+		if chainName != "litecoin" {
+			log.Fatal("-datadir only reads Litecoin block files.")
+		}
+		p, err := rawblock.GetParams(network)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Indexing block files in %s", rawblock.BlocksDir(datadir))
+		chain, err = rawblock.OpenChain(datadir, p)
+		if err != nil {
+			log.Fatalf("Failed to read block files. Err: %s", err)
+		}
+	} else {
+		if RPCPort <= 0 {
+			RPCPort = params.RPCPort
+		}
+		rpc = litecoinrpc.New(RPCHost, RPCPort, RPCUsername, RPCPassword)
+		rpc.CookieFile = RPCCookieFile
+		rpc.BatchSize = batchSize
+		log.Printf("RPC URL: %s", rpc.URL())
+	}
+
 	currentHeight, err := GetBlockHeight()
 	if err != nil {
 		log.Fatalf("Failed to retrieve current block height. Err: %s", err)
 	}
 	log.Printf("Current block height: %d\n", currentHeight)
-	log.Printf("Checking for BIP16 target block timestamp >= %d", BIP16target)
+	for _, d := range deployments {
+		log.Println(describe(params, d))
+	}
+
+	if search {
+		for _, d := range deployments {
+			if d.Type != activation.TIME {
+				log.Fatalf("-search only supports time based deployments, %s is %s based.", d.Name, d.Type)
+			}
+			result, err := SearchActivation(int64(block), int64(currentHeight), d.Time)
+			if err != nil {
+				log.Fatalf("Search for %s failed. Err: %s", strings.ToUpper(d.Name), err)
+			}
+			reportSearch(d, result, int64(currentHeight)-int64(block)+1)
+		}
+		return
+	}
 
-	for i := block; i < currentHeight; i++ {
-		b, err := GetBlockTime(i)
+	finder := activation.NewFinder(params, deployments, int64(block))
+	from := block - context
+	if from < 0 {
+		from = 0
+	}
+
+	cpFile := &checkpoint.File{Keep: checkpoint.DefaultKeep}
+	if resume {
+		cpFile, err = checkpoint.Load(checkpointPath)
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("Failed to load checkpoint file %s. Err: %s", checkpointPath, err)
+		}
+
+		var state ScanState
+		cp, ok, err := cpFile.Resume(int64(currentHeight), GetBlockHash, &state)
+		if err != nil {
+			log.Fatalf("Failed to validate checkpoint. Err: %s", err)
+		}
+
+		switch {
+		case !ok:
+			log.Println("No valid checkpoint found, starting from -block.")
+		case state.Finder == nil || state.Chain != params.Name || state.Deployments != deploymentList || state.BIP16target != BIP16target:
+			log.Println("Checkpoint was made for a different scan, starting from -block.")
+			cpFile.Checkpoints = nil
+		case int(cp.Height) >= block:
+			finder = state.Finder
+			finder.Params = params
+			finder.Deployments = deployments
+			from = int(cp.Height) + 1
+			log.Printf("Resuming from checkpoint at height %d hash %s.", cp.Height, cp.Hash)
+		}
+	}
+
+	for i := from; i <= currentHeight; i += batchSize {
+		end := i + batchSize - 1
+		if end > currentHeight {
+			end = currentHeight
+		}
+
+		blocks, err := GetBlockTimes(i, end)
+		if err != nil {
+			log.Fatalf("Scan failed at height %d, rerun with -resume to continue. Err: %s", i, err)
+		}
+
+		for _, b := range blocks {
+			events, periods := len(finder.Events), len(finder.Periods)
+			finder.Add(b)
+
+			for _, p := range finder.Periods[periods:] {
+				log.Printf("%s window %d-%d: %d/%d blocks signalled (%.2f%%)", strings.ToUpper(p.Deployment), p.Height, p.Height+p.Total-1, p.Count, p.Total, float64(p.Count)*100/float64(p.Total))
+			}
+			for _, e := range finder.Events[events:] {
+				reportEvent(deploymentByName(deployments, e.Deployment), e)
+			}
+			if verbose && b.Height >= int64(block) {
+				log.Printf("Block: %s height: %d time: %d version: %08x\n", b.Hash, b.Height, b.Time, uint32(b.Version))
+			}
+			if finder.Done() {
+				return
+			}
 		}
 
-		if b.BlockTime >= BIP16target {
-			log.Printf("Block: %s height: %d time: %d which has >= BIP16 target timestamp %d", b.Hash, i, b.BlockTime, BIP16target)
-			break
+		last := blocks[len(blocks)-1]
+		err = cpFile.Add(last.Height, last.Hash, ScanState{Chain: params.Name, Deployments: deploymentList, BIP16target: BIP16target, Finder: finder})
+		if err == nil {
+			err = cpFile.Save(checkpointPath)
 		}
-		if verbose {
-			log.Printf("Block: %s height: %d time: %d\n", b.Hash, i, b.BlockTime)
+		if err != nil {
+			log.Printf("Failed to save checkpoint. Err: %s", err)
 		}
 	}
+
+	for _, d := range deployments {
+		log.Printf("%s is %s at height %d", strings.ToUpper(d.Name), finder.State(d.Name), currentHeight)
+	}
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/thrasher-/litecoin-tools/activation"
+)
+
+// SearchResult holds both answers for a time based deployment. Raw is the
+// first block with a timestamp at or after the target, as far as
+// RAW_SEARCH_MARGIN holds. Enforced is the first
+// block whose parent's median time past is at or after the target, which is
+// nil when that block has not been mined yet.
+type SearchResult struct {
+	Target     int64
+	Raw        activation.Block
+	Enforced   *activation.Block
+	MedianTime int64
+	Probes     int
+}
+
+// GetMedianTime returns the median time past of the block at height, the
+// median timestamp of it and the 10 blocks before it.
+func GetMedianTime(height int64) (int64, error) {
+	if chain == nil {
+		hash, err := rpc.GetBlockHash(height)
+		if err != nil {
+			return 0, err
+		}
+		header, err := rpc.GetBlockHeader(hash)
+		if err != nil {
+			return 0, err
+		}
+		return header.MedianTime, nil
+	}
+
+	var times []int64
+	for h := height - activation.MEDIAN_TIME_SPAN + 1; h <= height; h++ {
+		if h < 0 {
+			continue
+		}
+		header, err := chain.Header(h)
+		if err != nil {
+			return 0, err
+		}
+		times = append(times, int64(header.Time))
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times[len(times)/2], nil
+}
+
+// MAX_FUTURE_BLOCK_TIME is how far ahead of the network time a block's
+// timestamp may be.
+const MAX_FUTURE_BLOCK_TIME = 2 * 60 * 60
+
+// RAW_SEARCH_MARGIN is how far a block's timestamp is assumed to be ahead of
+// the median time past of it and the 10 blocks after it. That median lags
+// the network time by up to an hour on top of MAX_FUTURE_BLOCK_TIME, and
+// the future limit is only checked against each node's clock, so this is
+// not a consensus guarantee.
+const RAW_SEARCH_MARGIN = MAX_FUTURE_BLOCK_TIME + 60*60
+
+// searchMedianTime binary searches for the first height after lo whose
+// median time past reaches target, given that lo's is before it and hi's,
+// median, is at or after it.
+func searchMedianTime(lo, hi, median, target int64, mtp func(int64) (int64, error)) (int64, int64, error) {
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		t, err := mtp(mid)
+		if err != nil {
+			return 0, 0, err
+		}
+		if t >= target {
+			hi, median = mid, t
+		} else {
+			lo = mid
+		}
+	}
+	return hi, median, nil
+}
+
+// SearchActivation binary searches lo through tip for the first block whose
+// median time past reaches target, which works because the median time past
+// never decreases. Raw timestamps are not ordered, and a block may be
+// timestamped hours ahead, so it can reach the target long before the median
+// does. The raw scan starts from the 11 blocks behind the first one whose
+// median time past reaches target-RAW_SEARCH_MARGIN, found with a second
+// binary search. A block before that timestamped further ahead than the
+// margin is missed, so Raw is best effort where a full scan is exact.
+func SearchActivation(lo, tip, target int64) (SearchResult, error) {
+	result := SearchResult{Target: target}
+	mtp := func(height int64) (int64, error) {
+		result.Probes++
+		return GetMedianTime(height)
+	}
+
+	hi := tip
+	t, err := mtp(hi)
+	if err != nil {
+		return result, err
+	}
+	if t < target {
+		return result, fmt.Errorf("the median time past at the tip %d is %d, before the target %d", tip, t, target)
+	}
+	result.MedianTime = t
+
+	loTime, err := mtp(lo)
+	if err != nil {
+		return result, err
+	}
+	if loTime >= target {
+		if lo > 0 {
+			return result, fmt.Errorf("the median time past at the start block %d is %d, already at or after the target %d", lo, loTime, target)
+		}
+		hi = 0
+		result.MedianTime = loTime
+	}
+
+	hi, result.MedianTime, err = searchMedianTime(lo, hi, result.MedianTime, target, mtp)
+	if err != nil {
+		return result, err
+	}
+
+	rawFrom := lo
+	if loTime < target-RAW_SEARCH_MARGIN {
+		rawFrom, _, err = searchMedianTime(lo, hi, result.MedianTime, target-RAW_SEARCH_MARGIN, mtp)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	from := rawFrom - activation.MEDIAN_TIME_SPAN + 1
+	if from < 0 {
+		from = 0
+	}
+	to := hi + 1
+	if to > tip {
+		to = tip
+	}
+	blocks, err := GetBlockTimes(int(from), int(to))
+	if err != nil {
+		return result, err
+	}
+
+	found := false
+	for _, b := range blocks {
+		if !found && b.Time >= target && b.Height <= hi {
+			result.Raw = b
+			found = true
+		}
+		if b.Height == hi+1 {
+			enforced := b
+			result.Enforced = &enforced
+		}
+	}
+	if !found {
+		return result, fmt.Errorf("no block at heights %d-%d has a timestamp at or after %d", from, hi, target)
+	}
+	return result, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/thrasher-/litecoin-tools/activation"
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+)
+
+// syntheticChain serves getblockhash and getblockheader for blocks with the
+// given timestamps over a fake RPC server, and points rpc at it.
+type syntheticChain struct {
+	times []int64
+}
+
+func (c *syntheticChain) medianTime(height int) int64 {
+	var times []int64
+	for h := height - 10; h <= height; h++ {
+		if h >= 0 {
+			times = append(times, c.times[h])
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times[len(times)/2]
+}
+
+// answer is a full scan for the first block with a timestamp at or after
+// target and the first block whose parent's median time past is.
+func (c *syntheticChain) answer(target int64) (raw, enforced int64) {
+	raw, enforced = -1, -1
+	for i, t := range c.times {
+		if raw < 0 && t >= target {
+			raw = int64(i)
+		}
+		if enforced < 0 && i > 0 && c.medianTime(i-1) >= target {
+			enforced = int64(i)
+		}
+	}
+	return raw, enforced
+}
+
+func (c *syntheticChain) call(req map[string]interface{}) map[string]interface{} {
+	params := req["params"].([]interface{})
+	var result interface{}
+	switch req["method"] {
+	case "getblockhash":
+		result = fmt.Sprintf("%064d", int(params[0].(float64)))
+	case "getblockheader":
+		height, _ := strconv.Atoi(params[0].(string))
+		result = map[string]interface{}{
+			"hash":       params[0],
+			"height":     height,
+			"time":       c.times[height],
+			"mediantime": c.medianTime(height),
+			"version":    2,
+		}
+	}
+	return map[string]interface{}{"id": req["id"], "result": result, "error": nil}
+}
+
+func (c *syntheticChain) serve(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var batch []map[string]interface{}
+		if json.Unmarshal(body, &batch) == nil {
+			var results []interface{}
+			for _, x := range batch {
+				results = append(results, c.call(x))
+			}
+			json.NewEncoder(w).Encode(results)
+			return
+		}
+		var req map[string]interface{}
+		json.Unmarshal(body, &req)
+		json.NewEncoder(w).Encode(c.call(req))
+	}))
+	t.Cleanup(srv.Close)
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	rpc = litecoinrpc.New(host, p, "u", "p")
+	chain = nil
+	t.Cleanup(func() { rpc = nil })
+}
+
+func newSyntheticChain(n int) *syntheticChain {
+	c := &syntheticChain{times: make([]int64, n)}
+	for i := range c.times {
+		// 150 second blocks with some jitter, so timestamps are not in
+		// order.
+		c.times[i] = 1000000 + int64(i)*150 + int64((i*7919)%13)*100 - 600
+	}
+	return c
+}
+
+func checkSearch(t *testing.T, c *syntheticChain, lo, target int64) {
+	result, err := SearchActivation(lo, int64(len(c.times)-1), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, enforced := c.answer(target)
+	if result.Raw.Height != raw {
+		t.Errorf("raw block %d, want %d", result.Raw.Height, raw)
+	}
+	if result.Enforced == nil || result.Enforced.Height != enforced {
+		t.Errorf("enforced block %+v, want %d", result.Enforced, enforced)
+	}
+	if result.MedianTime < target {
+		t.Errorf("MedianTime %d is before the target %d", result.MedianTime, target)
+	}
+}
+
+func TestSearchActivation(t *testing.T) {
+	c := newSyntheticChain(2000)
+	c.serve(t)
+	checkSearch(t, c, 100, c.times[1234]+50)
+}
+
+func TestSearchActivationFutureBlock(t *testing.T) {
+	// Block 1000 is timestamped ahead of its neighbours, so it is the first
+	// to reach the target while the median time past is still far behind.
+	// 10000 seconds is past MAX_FUTURE_BLOCK_TIME but inside
+	// RAW_SEARCH_MARGIN.
+	for _, ahead := range []int64{6000, 10000} {
+		c := newSyntheticChain(2000)
+		target := c.times[1000] + ahead
+		c.times[1000] = target
+		c.serve(t)
+
+		raw, enforced := c.answer(target)
+		if raw != 1000 || enforced-raw <= activation.MEDIAN_TIME_SPAN {
+			t.Fatalf("synthetic chain answers %d, %d, want the raw block well before the enforced one", raw, enforced)
+		}
+		checkSearch(t, c, 100, target)
+	}
+}
+
+func TestSearchActivationErrors(t *testing.T) {
+	c := newSyntheticChain(200)
+	c.serve(t)
+
+	if _, err := SearchActivation(0, 199, c.times[199]+10000); err == nil {
+		t.Fatal("expected an error for a target after the tip's median time past")
+	}
+	if _, err := SearchActivation(150, 199, c.times[10]); err == nil {
+		t.Fatal("expected an error for a start block already past the target")
+	}
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
-	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+	"github.com/thrasher-/litecoin-tools/rawblock"
 )
 
 const (
@@ -16,116 +23,317 @@ const (
 	RPC_USERNAME = "user"
 	RPC_PASSWORD = "pass"
 	RPC_HOST     = "127.0.0.1"
+
+	FORMAT_RAW = "raw"
+	FORMAT_HEX = "hex"
+	FORMAT_BLK = "blk"
 )
 
-func BuildURL() string {
-	return fmt.Sprintf("http://%s:%s@%s:%d", RPC_USERNAME, RPC_PASSWORD, RPC_HOST, RPC_PORT)
+var rpc = litecoinrpc.New(RPC_HOST, RPC_PORT, RPC_USERNAME, RPC_PASSWORD)
+
+// chain is set when blocks are read from the node's blk*.dat files with
+// -datadir instead of over RPC.
+var chain *rawblock.Chain
+
+type BlockRef struct {
+	Height int64
+	Hash   string
 }
 
-func JSONDecode(data []byte, to interface{}) error {
-	err := json.Unmarshal(data, &to)
+// ResolveBlocks turns a height range and a list of block hashes into the
+// blocks to dump. An end below zero means the current chain tip.
+func ResolveBlocks(start, end int64, hashes []string) ([]BlockRef, error) {
+	var refs []BlockRef
 
-	if err != nil {
-		return err
+	if start >= 0 {
+		if end < 0 {
+			count, err := getBlockCount()
+			if err != nil {
+				return nil, err
+			}
+			end = count
+		}
+		if end < start {
+			return nil, fmt.Errorf("end block %d is before start block %d", end, start)
+		}
+
+		rangeRefs, err := resolveRange(start, end)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, rangeRefs...)
 	}
 
-	return nil
+	for _, hash := range hashes {
+		ref, err := resolveHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
 }
 
-func SendRPCRequest(method string, req []interface{}) (map[string]interface{}, error) {
-	var params []interface{}
-	if req != nil {
-		params = append(params, req...)
-	} else {
-		params = nil
+func getBlockCount() (int64, error) {
+	if chain != nil {
+		return chain.Height(), nil
 	}
+	return rpc.GetBlockCount()
+}
 
-	data, err := json.Marshal(map[string]interface{}{
-		"method": method,
-		"id":     1,
-		"params": params,
-	})
+func resolveRange(start, end int64) ([]BlockRef, error) {
+	var refs []BlockRef
+	if chain != nil {
+		for height := start; height <= end; height++ {
+			hash, err := chain.Hash(height)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, BlockRef{Height: height, Hash: hash})
+		}
+		return refs, nil
+	}
 
+	results, err := rpc.GetBlockHashes(start, end)
 	if err != nil {
 		return nil, err
 	}
+	for _, result := range results {
+		if result.Err != nil {
+			return nil, fmt.Errorf("block %d: %s", result.Height, result.Err)
+		}
+		refs = append(refs, BlockRef{Height: result.Height, Hash: result.Hash})
+	}
+	return refs, nil
+}
 
-	resp, err := http.Post(BuildURL(), "application/json", strings.NewReader(string(data)))
-	if err != nil {
-		return nil, err
+func resolveHash(hash string) (BlockRef, error) {
+	if chain != nil {
+		height, ok := chain.HeightOf(hash)
+		if !ok {
+			return BlockRef{}, fmt.Errorf("block %s is not on the best chain in the block files", hash)
+		}
+		return BlockRef{Height: height, Hash: hash}, nil
 	}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
+	header, err := rpc.GetBlockHeader(hash)
 	if err != nil {
-		return nil, err
+		return BlockRef{}, fmt.Errorf("block %s: %s", hash, err)
 	}
+	return BlockRef{Height: header.Height, Hash: header.Hash}, nil
+}
+
+type gzipFile struct {
+	*gzip.Writer
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		g.file.Close()
+		return err
+	}
+	return g.file.Close()
+}
 
-	result := make(map[string]interface{})
-	err = json.Unmarshal(body, &result)
+// CreateOutput creates path, adding a .gz suffix and gzip compression when
+// compress is set. It returns the name of the file actually created.
+func CreateOutput(path string, compress bool) (io.WriteCloser, string, error) {
+	if compress {
+		path += ".gz"
+	}
+
+	file, err := os.Create(path)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	if result["error"] != nil {
-		errorMsg := result["error"].(map[string]interface{})
-		return nil, fmt.Errorf("Error code: %v, message: %v\n", errorMsg["code"], errorMsg["message"])
+	if !compress {
+		return file, path, nil
 	}
-	return result, nil
+	return &gzipFile{Writer: gzip.NewWriter(file), file: file}, path, nil
 }
 
-func GetBlockHash(block int) (string, error) {
-	var request []interface{}
-	request = append(request, block)
-	result, err := SendRPCRequest("getblockhash", request)
+func writeBlock(w io.Writer, format string, magic [4]byte, data []byte) error {
+	switch format {
+	case FORMAT_HEX:
+		_, err := fmt.Fprintln(w, hex.EncodeToString(data))
+		return err
+	case FORMAT_BLK:
+		return rawblock.WriteBlkRecord(w, magic, data)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+func fetchBlock(ref BlockRef) ([]byte, error) {
+	if chain != nil {
+		return chain.BlockBytes(ref.Height)
+	}
+
+	blockHex, err := rpc.GetBlockHex(ref.Hash)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("block %d: %s", ref.Height, err)
+	}
+	return hex.DecodeString(blockHex)
+}
+
+// DumpBlocks writes each block to its own file in the output directory for the
+// raw and hex formats, or all blocks to the single output file for blk.
+func DumpBlocks(refs []BlockRef, format, output string, compress bool, params rawblock.Params) error {
+	if format == FORMAT_BLK {
+		w, path, err := CreateOutput(output, compress)
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			data, err := fetchBlock(ref)
+			if err == nil {
+				err = writeBlock(w, format, params.Magic, data)
+			}
+			if err != nil {
+				w.Close()
+				return err
+			}
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		log.Printf("Wrote %d blocks to %s", len(refs), path)
+		return nil
 	}
-	return result["result"].(string), nil
+
+	for _, ref := range refs {
+		data, err := fetchBlock(ref)
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Join(output, fmt.Sprintf("block%d.%s", ref.Height, format))
+		w, path, err := CreateOutput(name, compress)
+		if err != nil {
+			return err
+		}
+		err = writeBlock(w, format, params.Magic, data)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		log.Println("Wrote output file to", path)
+	}
+	return nil
 }
 
-func GetBlockHex(block int) (string, error) {
-	blockHash, err := GetBlockHash(block)
+// DecodeBlockFile prints a dumped block as getblock verbosity 2 style JSON.
+// Raw and hex encoded dumps are accepted, optionally gzip compressed.
+// minHeight is the lowest height the block is known to be at, or -1, and
+// decides whether the coinbase BIP34 height is trusted.
+func DecodeBlockFile(path string, params rawblock.Params, minHeight int64) error {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		data, err = ioutil.ReadAll(gz)
+		if err != nil {
+			return err
+		}
+	}
+
+	if decoded, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil {
+		data = decoded
 	}
 
-	var request []interface{}
-	request = append(request, blockHash)
-	request = append(request, false)
-	result, err := SendRPCRequest("getblock", request)
+	block, err := rawblock.Parse(data)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("%s: %s", path, err)
 	}
 
-	hexData := result["result"].(string)
-	return hexData, nil
+	output, err := json.MarshalIndent(block.Verbose(params, minHeight), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(os.Stdout, string(output))
+	return err
 }
 
 func main() {
 	var blockNum = flag.Int("block", 0, "block number to hexdump")
+	var start = flag.Int64("start", -1, "first block of a range to dump")
+	var end = flag.Int64("end", -1, "last block of a range to dump, -1 for the chain tip")
+	var hashes = flag.String("hash", "", "comma separated block hashes to dump")
+	var format = flag.String("format", FORMAT_RAW, "output format (raw, hex, blk)")
+	var output = flag.String("output", "", "output directory for raw and hex dumps (default \".\"), or output file for blk dumps (default \"blocks.dat\")")
+	var compress = flag.Bool("gzip", false, "gzip compress the output files")
+	var decodeFile = flag.String("decode", "", "decode a dumped block file and print it as JSON instead of dumping")
+	var minHeight = flag.Int64("minheight", -1, "lowest height the decoded block is known to be at; the coinbase height is only shown past BIP34 activation")
+	var network = flag.String("network", "main", "network used for the blk magic and for address encoding when decoding (main, test, regtest)")
+	var datadir = flag.String("datadir", "", "read blocks from the blk*.dat files in this node data directory instead of over RPC")
 	flag.Parse()
 
-	if *blockNum < 0 {
-		flag.Usage()
+	params, err := rawblock.GetParams(*network)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *decodeFile != "" {
+		if err := DecodeBlockFile(*decodeFile, params, *minHeight); err != nil {
+			log.Fatal(err)
+		}
 		return
 	}
 
-	blockHex, err := GetBlockHex(*blockNum)
-	if err != nil {
-		log.Fatal(err)
+	switch *format {
+	case FORMAT_RAW, FORMAT_HEX, FORMAT_BLK:
+	default:
+		log.Fatalf("Unknown output format %q", *format)
+	}
+
+	if *output == "" {
+		*output = "."
+		if *format == FORMAT_BLK {
+			*output = "blocks.dat"
+		}
 	}
 
-	data, err := hex.DecodeString(blockHex)
+	if *datadir != "" {
+		log.Printf("Indexing block files in %s", rawblock.BlocksDir(*datadir))
+		chain, err = rawblock.OpenChain(*datadir, params)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var hashList []string
+	for _, hash := range strings.Split(*hashes, ",") {
+		if hash = strings.TrimSpace(hash); hash != "" {
+			hashList = append(hashList, hash)
+		}
+	}
+
+	if *start < 0 && len(hashList) == 0 {
+		if *blockNum < 0 {
+			flag.Usage()
+			return
+		}
+		*start = int64(*blockNum)
+		*end = *start
+	}
+
+	refs, err := ResolveBlocks(*start, *end, hashList)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	outputFile := fmt.Sprintf("block%d.raw", *blockNum)
-	err = ioutil.WriteFile(outputFile, data, 0644)
+	err = DumpBlocks(refs, *format, *output, *compress, params)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	log.Println("Wrote output file to", outputFile)
 }
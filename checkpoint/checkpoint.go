@@ -0,0 +1,124 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	DefaultKeep = 10
+)
+
+// Checkpoint records the last block a scan processed along with the scan's
+// running state at that height.
+type Checkpoint struct {
+	Height  int64           `json:"height"`
+	Hash    string          `json:"hash"`
+	Created int64           `json:"created"`
+	State   json.RawMessage `json:"state,omitempty"`
+}
+
+// File holds the most recent checkpoints, oldest first. Several are kept so
+// that a reorg past the newest one only discards the stale part of the scan.
+type File struct {
+	Checkpoints []Checkpoint `json:"checkpoints"`
+	Keep        int          `json:"-"`
+}
+
+// Load reads a checkpoint file. A missing file is not an error and returns
+// an empty File.
+func Load(path string) (*File, error) {
+	f := &File{Keep: DefaultKeep}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, f)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) Add(height int64, hash string, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	f.Checkpoints = append(f.Checkpoints, Checkpoint{
+		Height:  height,
+		Hash:    hash,
+		Created: time.Now().Unix(),
+		State:   data,
+	})
+
+	keep := f.Keep
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	if len(f.Checkpoints) > keep {
+		f.Checkpoints = f.Checkpoints[len(f.Checkpoints)-keep:]
+	}
+	return nil
+}
+
+// Save writes the file to a temporary path first and renames it into place
+// so a crash mid-write never leaves a truncated checkpoint behind.
+func (f *File) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", " ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Resume returns the newest checkpoint whose hash still matches the chain
+// according to hashAt, decoding its state into state. Checkpoints above it
+// were invalidated by a reorg, or are above tip because the node is behind
+// or on a shorter chain, and are dropped. ok is false when no checkpoint is
+// usable and the scan has to start over. err is only set when hashAt fails.
+func (f *File) Resume(tip int64, hashAt func(height int64) (string, error), state interface{}) (cp Checkpoint, ok bool, err error) {
+	for i := len(f.Checkpoints) - 1; i >= 0; i-- {
+		cp = f.Checkpoints[i]
+		if cp.Height > tip {
+			log.Printf("Checkpoint at height %d is stale, the chain tip is %d.", cp.Height, tip)
+			continue
+		}
+
+		hash, err := hashAt(cp.Height)
+		if err != nil {
+			return cp, false, err
+		}
+
+		if hash != cp.Hash {
+			log.Printf("Checkpoint at height %d is stale, block hash is now %s (was %s).", cp.Height, hash, cp.Hash)
+			continue
+		}
+
+		f.Checkpoints = f.Checkpoints[:i+1]
+		if state != nil && len(cp.State) > 0 {
+			err = json.Unmarshal(cp.State, state)
+			if err != nil {
+				return cp, false, err
+			}
+		}
+		return cp, true, nil
+	}
+
+	f.Checkpoints = nil
+	return Checkpoint{}, false, nil
+}
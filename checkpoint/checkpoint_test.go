@@ -0,0 +1,94 @@
+package checkpoint
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+type scanState struct {
+	Max int64 `json:"max"`
+}
+
+// newFile returns a file with checkpoints at heights 100 to 500.
+func newFile(t *testing.T) *File {
+	f := &File{Keep: DefaultKeep}
+	for h := int64(100); h <= 500; h += 100 {
+		if err := f.Add(h, fmt.Sprint("hash", h), scanState{Max: h}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+// chainTo returns a hashAt for a chain whose blocks above fork have been
+// replaced.
+func chainTo(fork int64) func(int64) (string, error) {
+	return func(height int64) (string, error) {
+		if height > fork {
+			return fmt.Sprint("reorged", height), nil
+		}
+		return fmt.Sprint("hash", height), nil
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	f, err := Load(path)
+	if err != nil || len(f.Checkpoints) != 0 {
+		t.Fatalf("Load of a missing file = %+v, %v", f, err)
+	}
+
+	f = newFile(t)
+	f.Keep = 3
+	f.Add(600, "hash600", scanState{Max: 600})
+	if err := f.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = Load(path)
+	if err != nil || len(f.Checkpoints) != 3 || f.Checkpoints[0].Height != 400 {
+		t.Fatalf("Load = %+v, %v", f, err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	tests := []struct {
+		name   string
+		tip    int64
+		fork   int64
+		height int64
+		ok     bool
+		kept   int
+	}{
+		{"unchanged", 1000, 1000, 500, true, 5},
+		{"reorg", 1000, 350, 300, true, 3},
+		{"shorter chain", 420, 1000, 400, true, 4},
+		{"shorter chain and reorg", 420, 150, 100, true, 1},
+		{"reorg below all", 1000, 50, 0, false, 0},
+		{"tip below all", 50, 1000, 0, false, 0},
+	}
+
+	for _, test := range tests {
+		f := newFile(t)
+		var state scanState
+		cp, ok, err := f.Resume(test.tip, chainTo(test.fork), &state)
+		if err != nil || ok != test.ok || cp.Height != test.height || len(f.Checkpoints) != test.kept {
+			t.Errorf("%s: Resume = %d, %v, %v with %d kept, want %d, %v with %d kept",
+				test.name, cp.Height, ok, err, len(f.Checkpoints), test.height, test.ok, test.kept)
+		}
+		if ok && state.Max != test.height {
+			t.Errorf("%s: state = %+v, want max %d", test.name, state, test.height)
+		}
+	}
+}
+
+func TestResumeTransportError(t *testing.T) {
+	f := newFile(t)
+	failed := errors.New("connection refused")
+	_, ok, err := f.Resume(1000, func(int64) (string, error) { return "", failed }, nil)
+	if err != failed || ok {
+		t.Fatalf("Resume = %v, %v, want the hashAt error", ok, err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/thrasher-/litecoin-tools/pow"
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+type HashRate struct {
+	Window   int     `json:"window"`
+	HashRate float64 `json:"hashrate"`
+}
+
+type Sample struct {
+	Height     int64      `json:"height"`
+	Hash       string     `json:"hash"`
+	Time       int64      `json:"time"`
+	Bits       string     `json:"bits"`
+	Difficulty float64    `json:"difficulty"`
+	HashRates  []HashRate `json:"hashrates"`
+}
+
+// Retarget is the predicted outcome of the next difficulty retarget,
+// assuming blocks keep arriving at the rate seen so far in the period.
+type Retarget struct {
+	Height              int64   `json:"height"`
+	BlocksLeft          int64   `json:"blocks_left"`
+	EstimatedTime       int64   `json:"estimated_time"`
+	CurrentBits         string  `json:"current_bits"`
+	CurrentDifficulty   float64 `json:"current_difficulty"`
+	PredictedBits       string  `json:"predicted_bits"`
+	PredictedDifficulty float64 `json:"predicted_difficulty"`
+	ChangePercent       float64 `json:"change_percent"`
+}
+
+type entry struct {
+	height    int64
+	header    rawblock.Header
+	chainWork float64
+}
+
+// Estimator follows a run of headers and samples the difficulty and the
+// hashrate over each window every Step blocks from Start onwards. Only as
+// many headers as the largest window or a retarget interval are kept.
+type Estimator struct {
+	Params  *pow.ConsensusParams
+	Windows []int
+	Step    int64
+	Start   int64
+	Samples []Sample
+
+	keep    int
+	entries []entry
+}
+
+func NewEstimator(params *pow.ConsensusParams, windows []int, step, start int64) *Estimator {
+	keep := int(params.Interval())
+	for _, w := range windows {
+		if w > keep {
+			keep = w
+		}
+	}
+	if step < 1 {
+		step = 1
+	}
+	return &Estimator{Params: params, Windows: windows, Step: step, Start: start, keep: keep + 1}
+}
+
+func workFloat(bits uint32) float64 {
+	f, _ := new(big.Float).SetInt(rawblock.BlockWork(bits)).Float64()
+	return f
+}
+
+// Add appends the header at height, which must follow the previous one.
+func (e *Estimator) Add(height int64, h rawblock.Header) {
+	var chainWork float64
+	if n := len(e.entries); n > 0 {
+		chainWork = e.entries[n-1].chainWork
+	}
+	chainWork += workFloat(h.Bits)
+
+	e.entries = append(e.entries, entry{height: height, header: h, chainWork: chainWork})
+	if len(e.entries) > 2*e.keep {
+		drop := len(e.entries) - e.keep
+		e.entries = append(e.entries[:0], e.entries[drop:]...)
+	}
+
+	if height >= e.Start && (height-e.Start)%e.Step == 0 {
+		e.Samples = append(e.Samples, e.sample())
+	}
+}
+
+func (e *Estimator) tip() entry {
+	return e.entries[len(e.entries)-1]
+}
+
+func (e *Estimator) entry(height int64) (entry, bool) {
+	i := int(height - e.entries[0].height)
+	if i < 0 || i >= len(e.entries) {
+		return entry{}, false
+	}
+	return e.entries[i], true
+}
+
+func (e *Estimator) sample() Sample {
+	tip := e.tip()
+	hash := tip.header.Hash()
+	s := Sample{
+		Height:     tip.height,
+		Hash:       rawblock.HashString(hash),
+		Time:       int64(tip.header.Time),
+		Bits:       fmt.Sprintf("%08x", tip.header.Bits),
+		Difficulty: rawblock.Difficulty(tip.header.Bits),
+	}
+	for _, w := range e.Windows {
+		s.HashRates = append(s.HashRates, HashRate{Window: w, HashRate: e.HashRate(w)})
+	}
+	return s
+}
+
+// HashRate estimates the hashes per second over the last window blocks the
+// same way as litecoind's getnetworkhashps: the work done divided by the
+// spread of the block times. It returns 0 without enough headers.
+func (e *Estimator) HashRate(window int) float64 {
+	if len(e.entries) == 0 {
+		return 0
+	}
+	tip := e.tip()
+	first, ok := e.entry(tip.height - int64(window))
+	if !ok || window < 1 {
+		return 0
+	}
+
+	minTime, maxTime := int64(tip.header.Time), int64(tip.header.Time)
+	for i := len(e.entries) - 1; i >= len(e.entries)-1-window; i-- {
+		t := int64(e.entries[i].header.Time)
+		if t < minTime {
+			minTime = t
+		}
+		if t > maxTime {
+			maxTime = t
+		}
+	}
+	if maxTime == minTime {
+		return 0
+	}
+	return (tip.chainWork - first.chainWork) / float64(maxTime-minTime)
+}
+
+// NextRetarget predicts the next retarget from the blocks of the current
+// period. ok is false when the start of the period is not available.
+func (e *Estimator) NextRetarget() (r Retarget, ok bool) {
+	if len(e.entries) == 0 {
+		return r, false
+	}
+
+	params := e.Params
+	interval := params.Interval()
+	tip := e.tip()
+
+	// The Litecoin retarget measures from the last block of the previous
+	// period, except for the first retarget after the genesis block.
+	next := (tip.height/interval + 1) * interval
+	firstHeight := next - 1 - interval
+	if next == interval {
+		firstHeight = 0
+	}
+	first, ok := e.entry(firstHeight)
+	done := tip.height - firstHeight
+	if !ok || done <= 0 {
+		return r, false
+	}
+
+	elapsed := int64(tip.header.Time) - int64(first.header.Time)
+	projected := elapsed * (next - 1 - firstHeight) / done
+
+	predicted := tip.header.Bits
+	if !params.NoRetargeting {
+		predicted = pow.CalculateNextWorkRequired(params, tip.header.Bits, projected)
+	}
+
+	r = Retarget{
+		Height:              next,
+		BlocksLeft:          next - tip.height,
+		EstimatedTime:       int64(tip.header.Time) + elapsed*(next-tip.height)/done,
+		CurrentBits:         fmt.Sprintf("%08x", tip.header.Bits),
+		CurrentDifficulty:   rawblock.Difficulty(tip.header.Bits),
+		PredictedBits:       fmt.Sprintf("%08x", predicted),
+		PredictedDifficulty: rawblock.Difficulty(predicted),
+	}
+	r.ChangePercent = (r.PredictedDifficulty/r.CurrentDifficulty - 1) * 100
+	return r, true
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/thrasher-/litecoin-tools/pow"
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+// diff1Work is the work of a difficulty 1 block, 2^256 / (target + 1) for
+// nBits 1d00ffff.
+const diff1Work = 4295032833
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Abs(b)
+}
+
+// syntheticChain feeds e the headers from 0 to tip, spaced spacing seconds
+// apart at difficulty 1.
+func syntheticChain(e *Estimator, tip, spacing int64) {
+	for height := int64(0); height <= tip; height++ {
+		e.Add(height, rawblock.Header{Time: uint32(1000000 + height*spacing), Bits: 0x1d00ffff})
+	}
+}
+
+func TestHashRate(t *testing.T) {
+	e := NewEstimator(&pow.MainNetConsensus, []int{10, 100}, 50, 100)
+	syntheticChain(e, 3000, 300)
+
+	// Heights 100, 150, ... 3000.
+	if len(e.Samples) != 59 || e.Samples[0].Height != 100 || e.Samples[58].Height != 3000 {
+		t.Fatalf("got %d samples from %d", len(e.Samples), e.Samples[0].Height)
+	}
+	for _, s := range e.Samples {
+		if s.Difficulty != 1 || s.Bits != "1d00ffff" {
+			t.Fatalf("sample %d: difficulty %v, bits %s", s.Height, s.Difficulty, s.Bits)
+		}
+		for _, x := range s.HashRates {
+			if !closeTo(x.HashRate, diff1Work/300.0) {
+				t.Fatalf("sample %d: hashrate over %d blocks is %v, want %v", s.Height, x.Window, x.HashRate, diff1Work/300.0)
+			}
+		}
+	}
+
+	// Only the headers needed for the largest window and the retarget are
+	// kept, and a window reaching past them has no estimate.
+	if len(e.entries) > 2*e.keep {
+		t.Fatalf("kept %d headers, want at most %d", len(e.entries), 2*e.keep)
+	}
+	if x := e.HashRate(5000); x != 0 {
+		t.Fatalf("HashRate(5000) = %v, want 0", x)
+	}
+}
+
+func TestNextRetarget(t *testing.T) {
+	// Blocks twice as slow as the 150 second target halve the difficulty.
+	e := NewEstimator(&pow.MainNetConsensus, []int{10}, 1, 0)
+	syntheticChain(e, 3000, 300)
+
+	r, ok := e.NextRetarget()
+	if !ok {
+		t.Fatal("no retarget estimate")
+	}
+	if r.Height != 4032 || r.BlocksLeft != 1032 || r.EstimatedTime != 1000000+4032*300 {
+		t.Fatalf("NextRetarget = %+v", r)
+	}
+	if r.CurrentDifficulty != 1 || !closeTo(r.PredictedDifficulty, 0.5) || !closeTo(r.ChangePercent, -50) {
+		t.Fatalf("predicted difficulty %v (%v%%), want 0.5 (-50%%)", r.PredictedDifficulty, r.ChangePercent)
+	}
+
+	// The first period measures from the genesis block. Blocks twice as
+	// fast as the target nearly double the difficulty.
+	e = NewEstimator(&pow.MainNetConsensus, []int{10}, 1, 0)
+	syntheticChain(e, 1000, 75)
+
+	r, ok = e.NextRetarget()
+	if !ok || r.Height != 2016 || r.BlocksLeft != 1016 {
+		t.Fatalf("NextRetarget = %+v, %v", r, ok)
+	}
+	if math.Abs(r.ChangePercent-100) > 0.1 {
+		t.Fatalf("ChangePercent = %v, want about 100", r.ChangePercent)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	e := NewEstimator(&pow.MainNetConsensus, []int{10, 100}, 50, 100)
+	syntheticChain(e, 100, 300)
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, e.Samples); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[0] != "height,hash,time,bits,difficulty,hashrate_10,hashrate_100" {
+		t.Fatalf("WriteCSV = %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "100,"+e.Samples[0].Hash+",1030000,1d00ffff,1,") {
+		t.Fatalf("WriteCSV row = %q", lines[1])
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+	"github.com/thrasher-/litecoin-tools/pow"
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+const (
+	HEADER_BATCH = 1000
+)
+
+var (
+	RPCHost       string
+	RPCPort       int
+	RPCUsername   string
+	RPCPassword   string
+	RPCCookieFile string
+	rpc           *litecoinrpc.Client
+
+	// chain is set when headers are read from the node's blk*.dat files
+	// with -datadir instead of over RPC.
+	chain *rawblock.Chain
+)
+
+func GetBlockHeight() (int64, error) {
+	if chain != nil {
+		return chain.Height(), nil
+	}
+	return rpc.GetBlockCount()
+}
+
+// GetHeaders returns the headers start through end inclusive.
+func GetHeaders(start, end int64) ([]rawblock.Header, error) {
+	var headers []rawblock.Header
+	if chain != nil {
+		for height := start; height <= end; height++ {
+			header, err := chain.Header(height)
+			if err != nil {
+				return nil, err
+			}
+			headers = append(headers, header)
+		}
+		return headers, nil
+	}
+
+	hashes, err := rpc.GetBlockHashes(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var blockHashes []string
+	for _, x := range hashes {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block hash for height %d: %s", x.Height, x.Err)
+		}
+		blockHashes = append(blockHashes, x.Hash)
+	}
+
+	results, err := rpc.GetBlockHeadersHex(blockHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, x := range results {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block header %s at height %d: %s", x.Hash, hashes[i].Height, x.Err)
+		}
+		data, err := hex.DecodeString(x.Hex)
+		if err != nil {
+			return nil, err
+		}
+		header, err := rawblock.ParseHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+func ParseWindows(s string) ([]int, error) {
+	var windows []int
+	for _, x := range strings.Split(s, ",") {
+		if x = strings.TrimSpace(x); x == "" {
+			continue
+		}
+		w, err := strconv.Atoi(x)
+		if err != nil || w < 1 {
+			return nil, fmt.Errorf("invalid window %q", x)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func main() {
+	var start, end, step, fileHeight int64
+	var network, windowList, headersFile, datadir, format, outputFile string
+	flag.StringVar(&RPCHost, "rpchost", "127.0.0.1", "The RPC host to connect to.")
+	flag.IntVar(&RPCPort, "rpcport", 9332, "The RPC port to connect to.")
+	flag.StringVar(&RPCUsername, "rpcuser", "user", "The RPC username.")
+	flag.StringVar(&RPCPassword, "rpcpass", "pass", "The RPC password.")
+	flag.StringVar(&RPCCookieFile, "rpccookiefile", "", "The RPC cookie file to authenticate with instead of -rpcuser/-rpcpass.")
+	flag.StringVar(&network, "network", "main", "Network whose difficulty rules are used: main, test or regtest.")
+	flag.Int64Var(&start, "start", 0, "First block height to sample.")
+	flag.Int64Var(&end, "end", -1, "Last block height to read, inclusive. Defaults to the current tip.")
+	flag.Int64Var(&step, "step", 2016, "Number of blocks between samples.")
+	flag.StringVar(&windowList, "windows", "120,2016", "Comma separated block windows to estimate the hashrate over.")
+	flag.StringVar(&headersFile, "headersfile", "", "File of consecutive raw or hex encoded headers to read instead of using RPC.")
+	flag.Int64Var(&fileHeight, "fileheight", 0, "Block height of the first header in -headersfile.")
+	flag.StringVar(&datadir, "datadir", "", "Read headers from the blk*.dat files in this node data directory instead of over RPC.")
+	flag.StringVar(&format, "format", "text", "Output format of the result: text, json or csv.")
+	flag.StringVar(&outputFile, "output", "", "File to write the result to. Defaults to stdout for json and csv.")
+	flag.Parse()
+
+	if format != "text" && format != "json" && format != "csv" {
+		log.Fatalf("Unknown output format %q.", format)
+	}
+
+	params, err := pow.GetConsensusParams(network)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	windows, err := ParseWindows(windowList)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if headersFile != "" {
+		data, err := ioutil.ReadFile(headersFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		headers, err := rawblock.ParseHeaders(data)
+		if err != nil {
+			log.Fatalf("Failed to read %s. Err: %s", headersFile, err)
+		}
+		if start < fileHeight {
+			start = fileHeight
+		}
+
+		e := NewEstimator(params, windows, step, start)
+		for i, h := range headers {
+			height := fileHeight + int64(i)
+			if end >= 0 && height > end {
+				break
+			}
+			e.Add(height, h)
+		}
+		report(e, format, outputFile)
+		return
+	}
+
+	if datadir != "" {
+		p, err := rawblock.GetParams(network)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Indexing block files in %s", rawblock.BlocksDir(datadir))
+		chain, err = rawblock.OpenChain(datadir, p)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		rpc = litecoinrpc.New(RPCHost, RPCPort, RPCUsername, RPCPassword)
+		rpc.CookieFile = RPCCookieFile
+		log.Printf("RPC URL: %s", rpc.URL())
+	}
+
+	tip, err := GetBlockHeight()
+	if err != nil {
+		log.Fatalf("Failed to retrieve current block height. Err: %s", err)
+	}
+	if end < 0 || end > tip {
+		end = tip
+	}
+	if start < 0 || start > end {
+		log.Fatalf("Invalid block range %d-%d.", start, end)
+	}
+
+	e := NewEstimator(params, windows, step, start)
+
+	// Read enough headers before start to fill the largest window.
+	from := start - int64(e.keep)
+	if from < 0 {
+		from = 0
+	}
+
+	log.Printf("Reading headers %d-%d", from, end)
+	lastReport := time.Now()
+	for i := from; i <= end; i += HEADER_BATCH {
+		last := i + HEADER_BATCH - 1
+		if last > end {
+			last = end
+		}
+		headers, err := GetHeaders(i, last)
+		if err != nil {
+			log.Fatalf("Failed to read headers %d-%d. Err: %s", i, last, err)
+		}
+		for j, h := range headers {
+			e.Add(i+int64(j), h)
+		}
+		if time.Since(lastReport) >= time.Second*30 {
+			log.Printf("Read headers up to height %d/%d", last, end)
+			lastReport = time.Now()
+		}
+	}
+	report(e, format, outputFile)
+}
+
+func report(e *Estimator, format, outputFile string) {
+	if len(e.entries) == 0 {
+		log.Fatal("No headers were read.")
+	}
+
+	result := Result{Samples: e.Samples}
+	if r, ok := e.NextRetarget(); ok {
+		result.NextRetarget = &r
+	}
+
+	if format != "text" {
+		if err := WriteResult(result, format, outputFile); err != nil {
+			log.Fatalf("Failed to write result. Err: %s", err)
+		}
+		return
+	}
+
+	for _, s := range e.Samples {
+		var rates []string
+		for _, x := range s.HashRates {
+			rates = append(rates, fmt.Sprintf("%d blocks: %.2f MH/s", x.Window, x.HashRate/1e6))
+		}
+		log.Printf("Height %d time %s difficulty %.4f hashrate %s", s.Height, time.Unix(s.Time, 0).UTC().Format(time.RFC3339), s.Difficulty, strings.Join(rates, ", "))
+	}
+
+	if r := result.NextRetarget; r != nil {
+		log.Printf("Next retarget at height %d in %d blocks, around %s", r.Height, r.BlocksLeft, time.Unix(r.EstimatedTime, 0).UTC().Format(time.RFC3339))
+		log.Printf("Difficulty is predicted to change from %.4f to %.4f (%+.2f%%), bits %s -> %s", r.CurrentDifficulty, r.PredictedDifficulty, r.ChangePercent, r.CurrentBits, r.PredictedBits)
+	}
+}
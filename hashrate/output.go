@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+)
+
+type Result struct {
+	Samples      []Sample  `json:"samples"`
+	NextRetarget *Retarget `json:"next_retarget,omitempty"`
+}
+
+// WriteResult writes the result as json or csv to path, or to stdout when
+// path is empty. The csv form only holds the samples.
+func WriteResult(result Result, format, path string) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if format == "json" {
+		return WriteJSON(w, result)
+	}
+	return WriteCSV(w, result.Samples)
+}
+
+func WriteJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", " ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
+
+// WriteCSV writes one row per sample with a hashrate column per window.
+func WriteCSV(w io.Writer, samples []Sample) error {
+	cw := csv.NewWriter(w)
+	header := []string{"height", "hash", "time", "bits", "difficulty"}
+	if len(samples) > 0 {
+		for _, x := range samples[0].HashRates {
+			header = append(header, "hashrate_"+strconv.Itoa(x.Window))
+		}
+	}
+	cw.Write(header)
+
+	for _, s := range samples {
+		record := []string{
+			strconv.FormatInt(s.Height, 10),
+			s.Hash,
+			strconv.FormatInt(s.Time, 10),
+			s.Bits,
+			strconv.FormatFloat(s.Difficulty, 'f', -1, 64),
+		}
+		for _, x := range s.HashRates {
+			record = append(record, strconv.FormatFloat(x.HashRate, 'f', 0, 64))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
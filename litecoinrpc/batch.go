@@ -0,0 +1,292 @@
+package litecoinrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+)
+
+const (
+	DefaultBatchSize = 100
+)
+
+type BatchRequest struct {
+	Method string
+	Params []interface{}
+}
+
+// BatchResult holds the outcome of a single request within a batch. Err is
+// set when the node rejected that item, without affecting the others.
+type BatchResult struct {
+	Result json.RawMessage
+	Err    error
+}
+
+type BlockHashResult struct {
+	Height int64
+	Hash   string
+	Err    error
+}
+
+type BlockResult struct {
+	Hash  string
+	Block Block
+	Err   error
+}
+
+type BlockVerboseResult struct {
+	Hash  string
+	Block BlockVerbose
+	Err   error
+}
+
+type BlockHexResult struct {
+	Hash string
+	Hex  string
+	Err  error
+}
+
+type BlockHeaderResult struct {
+	Hash   string
+	Header BlockHeader
+	Err    error
+}
+
+type BlockHeaderHexResult struct {
+	Hash string
+	Hex  string
+	Err  error
+}
+
+func (c *Client) batchSize() int {
+	if c.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return c.BatchSize
+}
+
+// CallBatch sends the requests as JSON-RPC 2.0 batch arrays of at most
+// BatchSize items each and returns one result per request, in request order.
+// The returned error is only set when a whole batch failed.
+func (c *Client) CallBatch(reqs []BatchRequest) ([]BatchResult, error) {
+	results := make([]BatchResult, 0, len(reqs))
+	size := c.batchSize()
+	for i := 0; i < len(reqs); i += size {
+		end := i + size
+		if end > len(reqs) {
+			end = len(reqs)
+		}
+
+		batch, err := c.sendBatch(reqs[i:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+func (c *Client) sendBatch(reqs []BatchRequest) ([]BatchResult, error) {
+	payload := make([]rpcRequest, len(reqs))
+	index := make(map[uint64]int, len(reqs))
+	for i, x := range reqs {
+		params := x.Params
+		if params == nil {
+			params = []interface{}{}
+		}
+		id := c.nextID()
+		payload[i] = rpcRequest{JSONRPC: "2.0", Method: x.Method, ID: id, Params: params}
+		index[id] = i
+	}
+
+	atomic.AddUint64(&c.calls, uint64(len(reqs)))
+	body, err := c.post(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp []rpcResponse
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		// A failed batch is answered with a single error object.
+		var single rpcResponse
+		if json.Unmarshal(body, &single) == nil && single.Error != nil {
+			return nil, single.Error
+		}
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(reqs))
+	seen := make([]bool, len(reqs))
+	var unmatched error
+	for _, x := range resp {
+		var i int
+		ok := x.ID != nil
+		if ok {
+			i, ok = index[*x.ID]
+		}
+		if !ok || seen[i] {
+			// Errors the node couldn't tie to a request, such as a parse
+			// error, come back with a null id. They belong to whichever
+			// items got no response of their own.
+			if x.Error != nil {
+				unmatched = x.Error
+			}
+			continue
+		}
+		seen[i] = true
+		if x.Error != nil {
+			results[i].Err = x.Error
+			continue
+		}
+		results[i].Result = x.Result
+	}
+
+	for i := range results {
+		if !seen[i] {
+			results[i].Err = unmatched
+			if unmatched == nil {
+				results[i].Err = errors.New("no response for batch item")
+			}
+		}
+	}
+	return results, nil
+}
+
+// GetBlockHashes fetches the hashes for the heights start through end
+// inclusive.
+func (c *Client) GetBlockHashes(start, end int64) ([]BlockHashResult, error) {
+	if end < start {
+		return nil, nil
+	}
+
+	reqs := make([]BatchRequest, 0, end-start+1)
+	for h := start; h <= end; h++ {
+		reqs = append(reqs, BatchRequest{Method: "getblockhash", Params: []interface{}{h}})
+	}
+
+	batch, err := c.CallBatch(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BlockHashResult, len(batch))
+	for i, x := range batch {
+		results[i].Height = start + int64(i)
+		results[i].Err = x.Err
+		if x.Err == nil {
+			results[i].Err = json.Unmarshal(x.Result, &results[i].Hash)
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) GetBlocks(hashes []string) ([]BlockResult, error) {
+	reqs := make([]BatchRequest, len(hashes))
+	for i, x := range hashes {
+		reqs[i] = BatchRequest{Method: "getblock", Params: []interface{}{x, 1}}
+	}
+
+	batch, err := c.CallBatch(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BlockResult, len(batch))
+	for i, x := range batch {
+		results[i].Hash = hashes[i]
+		results[i].Err = x.Err
+		if x.Err == nil {
+			results[i].Err = json.Unmarshal(x.Result, &results[i].Block)
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) GetBlocksHex(hashes []string) ([]BlockHexResult, error) {
+	reqs := make([]BatchRequest, len(hashes))
+	for i, x := range hashes {
+		reqs[i] = BatchRequest{Method: "getblock", Params: []interface{}{x, 0}}
+	}
+
+	batch, err := c.CallBatch(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BlockHexResult, len(batch))
+	for i, x := range batch {
+		results[i].Hash = hashes[i]
+		results[i].Err = x.Err
+		if x.Err == nil {
+			results[i].Err = json.Unmarshal(x.Result, &results[i].Hex)
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) GetBlockHeaders(hashes []string) ([]BlockHeaderResult, error) {
+	reqs := make([]BatchRequest, len(hashes))
+	for i, x := range hashes {
+		reqs[i] = BatchRequest{Method: "getblockheader", Params: []interface{}{x, true}}
+	}
+
+	batch, err := c.CallBatch(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BlockHeaderResult, len(batch))
+	for i, x := range batch {
+		results[i].Hash = hashes[i]
+		results[i].Err = x.Err
+		if x.Err == nil {
+			results[i].Err = json.Unmarshal(x.Result, &results[i].Header)
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) GetBlockHeadersHex(hashes []string) ([]BlockHeaderHexResult, error) {
+	reqs := make([]BatchRequest, len(hashes))
+	for i, x := range hashes {
+		reqs[i] = BatchRequest{Method: "getblockheader", Params: []interface{}{x, false}}
+	}
+
+	batch, err := c.CallBatch(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BlockHeaderHexResult, len(batch))
+	for i, x := range batch {
+		results[i].Hash = hashes[i]
+		results[i].Err = x.Err
+		if x.Err == nil {
+			results[i].Err = json.Unmarshal(x.Result, &results[i].Hex)
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) GetBlocksVerbose(hashes []string) ([]BlockVerboseResult, error) {
+	reqs := make([]BatchRequest, len(hashes))
+	for i, x := range hashes {
+		reqs[i] = BatchRequest{Method: "getblock", Params: []interface{}{x, 2}}
+	}
+
+	batch, err := c.CallBatch(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BlockVerboseResult, len(batch))
+	for i, x := range batch {
+		results[i].Hash = hashes[i]
+		results[i].Err = x.Err
+		if x.Err == nil {
+			results[i].Err = json.Unmarshal(x.Result, &results[i].Block)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,132 @@
+package litecoinrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+type testResponse struct {
+	ID     interface{} `json:"id"`
+	Result interface{} `json:"result,omitempty"`
+	Error  *RPCError   `json:"error,omitempty"`
+}
+
+// batchNode answers getblockhash batches in reverse order. Heights in
+// failHeights get a per item error and, when nullID is set, the item for
+// height 2 is dropped in favour of an error with a null id.
+func batchNode(t *testing.T, posts *int, nullID bool, failHeights ...float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*posts++
+		var reqs []rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var resp []testResponse
+		for i := len(reqs) - 1; i >= 0; i-- {
+			height := reqs[i].Params[0].(float64)
+			switch {
+			case nullID && height == 2:
+				resp = append(resp, testResponse{Error: &RPCError{Code: -32700, Message: "Parse error"}})
+			case contains(failHeights, height):
+				resp = append(resp, testResponse{ID: reqs[i].ID, Error: &RPCError{Code: -8, Message: "Block height out of range"}})
+			default:
+				resp = append(resp, testResponse{ID: reqs[i].ID, Result: fmt.Sprintf("hash%d", int(height))})
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func contains(list []float64, x float64) bool {
+	for _, y := range list {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBatchOutOfOrder(t *testing.T) {
+	posts := 0
+	c := newTestClient(t, batchNode(t, &posts, false))
+	c.BatchSize = 3
+
+	results, err := c.GetBlockHashes(0, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posts != 4 || len(results) != 10 {
+		t.Fatalf("got %d results in %d requests, want 10 in 4", len(results), posts)
+	}
+	for i, x := range results {
+		if x.Err != nil || x.Height != int64(i) || x.Hash != fmt.Sprintf("hash%d", i) {
+			t.Errorf("result %d = %+v", i, x)
+		}
+	}
+	if c.Calls() != 10 || c.Requests() != 4 {
+		t.Errorf("Calls = %d, Requests = %d, want 10, 4", c.Calls(), c.Requests())
+	}
+}
+
+func TestBatchPartialFailure(t *testing.T) {
+	posts := 0
+	c := newTestClient(t, batchNode(t, &posts, false, 4, 7))
+
+	results, err := c.GetBlockHashes(0, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, x := range results {
+		if i == 4 || i == 7 {
+			if e, ok := x.Err.(*RPCError); !ok || e.Code != -8 {
+				t.Errorf("result %d error = %v, want RPC error -8", i, x.Err)
+			}
+			continue
+		}
+		if x.Err != nil || x.Hash != fmt.Sprintf("hash%d", i) {
+			t.Errorf("result %d = %+v", i, x)
+		}
+	}
+}
+
+func TestBatchNullID(t *testing.T) {
+	posts := 0
+	c := newTestClient(t, batchNode(t, &posts, true))
+
+	results, err := c.GetBlockHashes(0, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, x := range results {
+		if i == 2 {
+			if e, ok := x.Err.(*RPCError); !ok || e.Code != -32700 {
+				t.Errorf("result 2 error = %v, want the null id parse error", x.Err)
+			}
+			continue
+		}
+		if x.Err != nil || x.Hash != fmt.Sprintf("hash%d", i) {
+			t.Errorf("result %d = %+v", i, x)
+		}
+	}
+}
+
+func TestBatchMissingResponse(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var reqs []rpcRequest
+		json.NewDecoder(r.Body).Decode(&reqs)
+		json.NewEncoder(w).Encode([]testResponse{{ID: reqs[0].ID, Result: "hash0"}, {ID: 99999, Result: "stray"}})
+	})
+
+	results, err := c.GetBlockHashes(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil || results[0].Hash != "hash0" || results[1].Err == nil {
+		t.Fatalf("results = %+v", results)
+	}
+}
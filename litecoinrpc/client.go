@@ -0,0 +1,202 @@
+package litecoinrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultHost     = "127.0.0.1"
+	DefaultPort     = 9332
+	DefaultUsername = "user"
+	DefaultPassword = "pass"
+	DefaultTimeout  = time.Second * 30
+)
+
+// Client talks to a litecoind JSON-RPC server. Credentials are taken from
+// CookieFile when it is set, otherwise Username and Password are used.
+// BatchSize caps the number of requests sent per batch by CallBatch.
+type Client struct {
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	CookieFile string
+	Timeout    time.Duration
+	BatchSize  int
+
+	httpClient *http.Client
+	once       sync.Once
+	id         uint64
+	calls      uint64
+	requests   uint64
+}
+
+// RPCError is an error returned by the node in the response error field.
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("Error code: %d, message: %s", e.Code, e.Message)
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc,omitempty"`
+	Method  string        `json:"method"`
+	ID      uint64        `json:"id"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+	ID     *uint64         `json:"id"`
+}
+
+func New(host string, port int, username, password string) *Client {
+	return &Client{
+		Host:      host,
+		Port:      port,
+		Username:  username,
+		Password:  password,
+		Timeout:   DefaultTimeout,
+		BatchSize: DefaultBatchSize,
+	}
+}
+
+func NewWithCookie(host string, port int, cookieFile string) *Client {
+	return &Client{
+		Host:       host,
+		Port:       port,
+		CookieFile: cookieFile,
+		Timeout:    DefaultTimeout,
+		BatchSize:  DefaultBatchSize,
+	}
+}
+
+// URL returns the endpoint of the node without credentials so it can be
+// logged safely.
+func (c *Client) URL() string {
+	return fmt.Sprintf("http://%s:%d", c.Host, c.Port)
+}
+
+func (c *Client) credentials() (string, string, error) {
+	if c.CookieFile == "" {
+		return c.Username, c.Password, nil
+	}
+
+	data, err := ioutil.ReadFile(c.CookieFile)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to read cookie file: %s", err)
+	}
+
+	cookie := strings.TrimSpace(string(data))
+	i := strings.Index(cookie, ":")
+	if i < 0 {
+		return "", "", errors.New("malformed cookie file")
+	}
+	return cookie[:i], cookie[i+1:], nil
+}
+
+func (c *Client) client() *http.Client {
+	c.once.Do(func() {
+		c.httpClient = &http.Client{Timeout: c.Timeout}
+	})
+	return c.httpClient
+}
+
+func (c *Client) nextID() uint64 {
+	return atomic.AddUint64(&c.id, 1)
+}
+
+// Calls returns the number of RPC calls sent so far, counting every item of
+// a batch.
+func (c *Client) Calls() uint64 {
+	return atomic.LoadUint64(&c.calls)
+}
+
+// Requests returns the number of HTTP requests sent so far. A batch is sent
+// as a single request.
+func (c *Client) Requests() uint64 {
+	return atomic.LoadUint64(&c.requests)
+}
+
+func (c *Client) post(payload interface{}) ([]byte, error) {
+	atomic.AddUint64(&c.requests, 1)
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", c.URL(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	username, password, err := c.credentials()
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// litecoind replies with 404/500 alongside a JSON error body, so only
+	// treat the status as fatal when there is nothing to decode.
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errors.New("RPC authentication failed")
+	}
+	if resp.StatusCode != http.StatusOK && len(body) == 0 {
+		return nil, fmt.Errorf("HTTP status code was not equal to 200. Code: %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// Call sends a single request and decodes the result field into result. A
+// nil result discards the response.
+func (c *Client) Call(method string, params []interface{}, result interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	atomic.AddUint64(&c.calls, 1)
+	body, err := c.post(rpcRequest{Method: method, ID: c.nextID(), Params: params})
+	if err != nil {
+		return err
+	}
+
+	var resp rpcResponse
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
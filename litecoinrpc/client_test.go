@@ -0,0 +1,140 @@
+package litecoinrpc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// newTestClient starts handler as a fake litecoind and returns a client for
+// it using the credentials u:p.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return New(host, p, "u", "p")
+}
+
+func fakeNode(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if u, p, _ := r.BasicAuth(); u != "u" || p != "p" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			// The handler runs on the server's goroutine, where t.Fatal
+			// can't stop the test.
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		switch req.Method {
+		case "getblockchaininfo":
+			w.Write([]byte(`{"result":{"chain":"main","blocks":5,"headers":5,"bestblockhash":"aa"},"error":null,"id":1}`))
+		case "getblockcount":
+			w.Write([]byte(`{"result":5,"error":null,"id":1}`))
+		case "getblockhash":
+			w.Write([]byte(`{"result":"bb","error":null,"id":1}`))
+		case "getblock":
+			if req.Params[1].(float64) == 2 {
+				// litecoind sends RPC errors with a 500 status.
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"result":null,"error":{"code":-5,"message":"Block not found"},"id":1}`))
+				return
+			}
+			w.Write([]byte(`{"result":{"hash":"bb","height":3,"size":100,"tx":["a","b"]},"error":null,"id":1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"result":null,"error":{"code":-32601,"message":"Method not found"},"id":1}`))
+		}
+	}
+}
+
+func TestTypedMethods(t *testing.T) {
+	c := newTestClient(t, fakeNode(t))
+
+	info, err := c.GetBlockchainInfo()
+	if err != nil || info.Chain != "main" || info.Blocks != 5 || info.BestBlockHash != "aa" {
+		t.Fatalf("GetBlockchainInfo = %+v, %v", info, err)
+	}
+
+	count, err := c.GetBlockCount()
+	if err != nil || count != 5 {
+		t.Fatalf("GetBlockCount = %d, %v", count, err)
+	}
+
+	hash, err := c.GetBlockHash(3)
+	if err != nil || hash != "bb" {
+		t.Fatalf("GetBlockHash = %q, %v", hash, err)
+	}
+
+	block, err := c.GetBlock("bb")
+	if err != nil || block.Height != 3 || block.Size != 100 || len(block.Tx) != 2 {
+		t.Fatalf("GetBlock = %+v, %v", block, err)
+	}
+
+	if c.Calls() != 4 || c.Requests() != 4 {
+		t.Fatalf("Calls = %d, Requests = %d, want 4, 4", c.Calls(), c.Requests())
+	}
+}
+
+func TestRPCError(t *testing.T) {
+	c := newTestClient(t, fakeNode(t))
+
+	_, err := c.GetBlockVerbose("bb")
+	e, ok := err.(*RPCError)
+	if !ok || e.Code != -5 || e.Message != "Block not found" {
+		t.Fatalf("GetBlockVerbose error = %v, want RPC error -5", err)
+	}
+
+	err = c.Call("nosuchmethod", nil, nil)
+	if e, ok := err.(*RPCError); !ok || e.Code != -32601 {
+		t.Fatalf("Call error = %v, want RPC error -32601", err)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	c := newTestClient(t, fakeNode(t))
+	c.Password = "wrong"
+
+	_, err := c.GetBlockCount()
+	if err == nil || err.Error() != "RPC authentication failed" {
+		t.Fatalf("GetBlockCount error = %v, want authentication failure", err)
+	}
+}
+
+func TestCookieAuth(t *testing.T) {
+	c := newTestClient(t, fakeNode(t))
+
+	f, err := ioutil.TempFile("", ".cookie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("u:p\n")
+	f.Close()
+
+	cookie := NewWithCookie(c.Host, c.Port, f.Name())
+	if _, err := cookie.GetBlockHash(1); err != nil {
+		t.Fatalf("cookie auth failed: %v", err)
+	}
+
+	ioutil.WriteFile(f.Name(), []byte("nocolon"), 0600)
+	if _, err := cookie.GetBlockHash(1); err == nil {
+		t.Fatal("expected an error for a malformed cookie file")
+	}
+
+	cookie.CookieFile = f.Name() + ".missing"
+	if _, err := cookie.GetBlockHash(1); err == nil {
+		t.Fatal("expected an error for a missing cookie file")
+	}
+}
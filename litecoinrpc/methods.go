@@ -0,0 +1,65 @@
+package litecoinrpc
+
+func (c *Client) GetBlockchainInfo() (BlockchainInfo, error) {
+	var result BlockchainInfo
+	err := c.Call("getblockchaininfo", nil, &result)
+	return result, err
+}
+
+func (c *Client) GetBlockCount() (int64, error) {
+	var result int64
+	err := c.Call("getblockcount", nil, &result)
+	return result, err
+}
+
+func (c *Client) GetBlockHash(height int64) (string, error) {
+	var result string
+	err := c.Call("getblockhash", []interface{}{height}, &result)
+	return result, err
+}
+
+// GetBlockHex returns the serialised block, getblock verbosity 0.
+func (c *Client) GetBlockHex(hash string) (string, error) {
+	var result string
+	err := c.Call("getblock", []interface{}{hash, 0}, &result)
+	return result, err
+}
+
+// GetBlock returns the block with its txids, getblock verbosity 1.
+func (c *Client) GetBlock(hash string) (Block, error) {
+	var result Block
+	err := c.Call("getblock", []interface{}{hash, 1}, &result)
+	return result, err
+}
+
+// GetBlockVerbose returns the block with decoded transactions, getblock
+// verbosity 2.
+func (c *Client) GetBlockVerbose(hash string) (BlockVerbose, error) {
+	var result BlockVerbose
+	err := c.Call("getblock", []interface{}{hash, 2}, &result)
+	return result, err
+}
+
+func (c *Client) GetBlockHeader(hash string) (BlockHeader, error) {
+	var result BlockHeader
+	err := c.Call("getblockheader", []interface{}{hash, true}, &result)
+	return result, err
+}
+
+func (c *Client) GetBlockHeaderHex(hash string) (string, error) {
+	var result string
+	err := c.Call("getblockheader", []interface{}{hash, false}, &result)
+	return result, err
+}
+
+func (c *Client) GetNetworkInfo() (NetworkInfo, error) {
+	var result NetworkInfo
+	err := c.Call("getnetworkinfo", nil, &result)
+	return result, err
+}
+
+func (c *Client) GetPeerInfo() ([]PeerInfo, error) {
+	var result []PeerInfo
+	err := c.Call("getpeerinfo", nil, &result)
+	return result, err
+}
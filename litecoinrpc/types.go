@@ -0,0 +1,189 @@
+package litecoinrpc
+
+import (
+	"encoding/json"
+)
+
+type BlockchainInfo struct {
+	Chain                string  `json:"chain"`
+	Blocks               int64   `json:"blocks"`
+	Headers              int64   `json:"headers"`
+	BestBlockHash        string  `json:"bestblockhash"`
+	Difficulty           float64 `json:"difficulty"`
+	MedianTime           int64   `json:"mediantime"`
+	VerificationProgress float64 `json:"verificationprogress"`
+	InitialBlockDownload bool    `json:"initialblockdownload"`
+	ChainWork            string  `json:"chainwork"`
+	SizeOnDisk           int64   `json:"size_on_disk"`
+	Pruned               bool    `json:"pruned"`
+}
+
+type BlockHeader struct {
+	Hash              string  `json:"hash"`
+	Confirmations     int64   `json:"confirmations"`
+	Height            int64   `json:"height"`
+	Version           int32   `json:"version"`
+	VersionHex        string  `json:"versionHex"`
+	MerkleRoot        string  `json:"merkleroot"`
+	Time              int64   `json:"time"`
+	MedianTime        int64   `json:"mediantime"`
+	Nonce             uint32  `json:"nonce"`
+	Bits              string  `json:"bits"`
+	Difficulty        float64 `json:"difficulty"`
+	ChainWork         string  `json:"chainwork"`
+	NTx               int     `json:"nTx"`
+	PreviousBlockHash string  `json:"previousblockhash,omitempty"`
+	NextBlockHash     string  `json:"nextblockhash,omitempty"`
+}
+
+// Block is the getblock result at verbosity 1, where Tx only holds txids.
+type Block struct {
+	BlockHeader
+	Size         int        `json:"size"`
+	StrippedSize int        `json:"strippedsize"`
+	Weight       int        `json:"weight"`
+	Tx           []string   `json:"tx"`
+	MWEB         *MWEBBlock `json:"mweb,omitempty"`
+}
+
+// BlockVerbose is the getblock result at verbosity 2, where Tx holds the
+// decoded transactions.
+type BlockVerbose struct {
+	BlockHeader
+	Size         int           `json:"size"`
+	StrippedSize int           `json:"strippedsize"`
+	Weight       int           `json:"weight"`
+	Tx           []Transaction `json:"tx"`
+	MWEB         *MWEBBlock    `json:"mweb,omitempty"`
+}
+
+// MWEBBlock is the MimbleWimble extension block attached to blocks after MWEB
+// activation. Amounts are in litoshis. Inputs and outputs are left undecoded
+// since only their counts are used.
+type MWEBBlock struct {
+	Hash          string            `json:"hash"`
+	Height        int64             `json:"height"`
+	KernelOffset  string            `json:"kernel_offset"`
+	StealthOffset string            `json:"stealth_offset"`
+	NumKernels    int64             `json:"num_kernels"`
+	NumTXOs       int64             `json:"num_txos"`
+	KernelRoot    string            `json:"kernel_root"`
+	OutputRoot    string            `json:"output_root"`
+	LeafRoot      string            `json:"leaf_root"`
+	Inputs        []json.RawMessage `json:"inputs"`
+	Outputs       []json.RawMessage `json:"outputs"`
+	Kernels       []MWEBKernel      `json:"kernels"`
+}
+
+type MWEBKernel struct {
+	KernelID   string       `json:"kernel_id"`
+	Fee        int64        `json:"fee"`
+	PegIn      int64        `json:"pegin"`
+	PegOuts    []MWEBPegOut `json:"pegouts"`
+	LockHeight int64        `json:"lock_height"`
+}
+
+// UnmarshalJSON accepts either a decoded kernel or a bare kernel ID, so a
+// node that only lists IDs at lower verbosity still decodes. Only KernelID
+// is set in that case.
+func (k *MWEBKernel) UnmarshalJSON(data []byte) error {
+	var id string
+	if json.Unmarshal(data, &id) == nil {
+		*k = MWEBKernel{KernelID: id}
+		return nil
+	}
+
+	type kernel MWEBKernel
+	return json.Unmarshal(data, (*kernel)(k))
+}
+
+type MWEBPegOut struct {
+	Value        int64  `json:"value"`
+	ScriptPubKey string `json:"scriptPubKey"`
+}
+
+type ScriptSig struct {
+	Asm string `json:"asm"`
+	Hex string `json:"hex"`
+}
+
+type ScriptPubKey struct {
+	Asm       string   `json:"asm"`
+	Hex       string   `json:"hex"`
+	Type      string   `json:"type"`
+	Address   string   `json:"address,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+type TxIn struct {
+	TxID        string     `json:"txid,omitempty"`
+	Vout        uint32     `json:"vout"`
+	Coinbase    string     `json:"coinbase,omitempty"`
+	ScriptSig   *ScriptSig `json:"scriptSig,omitempty"`
+	TxInWitness []string   `json:"txinwitness,omitempty"`
+	Sequence    uint32     `json:"sequence"`
+}
+
+type TxOut struct {
+	Value        float64      `json:"value"`
+	N            uint32       `json:"n"`
+	ScriptPubKey ScriptPubKey `json:"scriptPubKey"`
+}
+
+type Transaction struct {
+	TxID     string  `json:"txid"`
+	Hash     string  `json:"hash"`
+	Version  int32   `json:"version"`
+	Size     int     `json:"size"`
+	VSize    int     `json:"vsize"`
+	Weight   int     `json:"weight"`
+	LockTime uint32  `json:"locktime"`
+	Vin      []TxIn  `json:"vin"`
+	Vout     []TxOut `json:"vout"`
+	Fee      float64 `json:"fee,omitempty"`
+	Hex      string  `json:"hex,omitempty"`
+}
+
+type Network struct {
+	Name                      string `json:"name"`
+	Limited                   bool   `json:"limited"`
+	Reachable                 bool   `json:"reachable"`
+	Proxy                     string `json:"proxy"`
+	ProxyRandomizeCredentials bool   `json:"proxy_randomize_credentials"`
+}
+
+type NetworkInfo struct {
+	Version         int       `json:"version"`
+	SubVersion      string    `json:"subversion"`
+	ProtocolVersion int       `json:"protocolversion"`
+	LocalServices   string    `json:"localservices"`
+	LocalRelay      bool      `json:"localrelay"`
+	TimeOffset      int64     `json:"timeoffset"`
+	Connections     int       `json:"connections"`
+	NetworkActive   bool      `json:"networkactive"`
+	Networks        []Network `json:"networks"`
+	RelayFee        float64   `json:"relayfee"`
+	Warnings        string    `json:"warnings"`
+}
+
+type PeerInfo struct {
+	ID             int64   `json:"id"`
+	Addr           string  `json:"addr"`
+	AddrLocal      string  `json:"addrlocal,omitempty"`
+	Services       string  `json:"services"`
+	RelayTxes      bool    `json:"relaytxes"`
+	LastSend       int64   `json:"lastsend"`
+	LastRecv       int64   `json:"lastrecv"`
+	BytesSent      int64   `json:"bytessent"`
+	BytesRecv      int64   `json:"bytesrecv"`
+	ConnTime       int64   `json:"conntime"`
+	TimeOffset     int64   `json:"timeoffset"`
+	PingTime       float64 `json:"pingtime"`
+	Version        int     `json:"version"`
+	SubVer         string  `json:"subver"`
+	Inbound        bool    `json:"inbound"`
+	StartingHeight int64   `json:"startingheight"`
+	BanScore       int     `json:"banscore"`
+	SyncedHeaders  int64   `json:"synced_headers"`
+	SyncedBlocks   int64   `json:"synced_blocks"`
+}
@@ -0,0 +1,67 @@
+package litecoinrpc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+// The fixtures in testdata are getblock verbosity 1 responses in litecoind's
+// layout with synthetic hashes: a block from before MWEB, the HogEx block
+// MWEB activated at, listing its kernel as a bare ID, and a later MWEB block
+// with decoded kernels.
+func serveFixture(t *testing.T, name string) *Client {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+}
+
+func TestDecodeBlockPreMWEB(t *testing.T) {
+	block, err := serveFixture(t, "getblock_premweb.json").GetBlock("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Height != 2000000 || block.Size != 1500 || block.Weight != 5100 || len(block.Tx) != 3 || block.NTx != 3 {
+		t.Errorf("block = %+v", block)
+	}
+	if block.MWEB != nil {
+		t.Errorf("pre-MWEB block has an MWEB section: %+v", block.MWEB)
+	}
+}
+
+func TestDecodeBlockHogEx(t *testing.T) {
+	block, err := serveFixture(t, "getblock_hogex.json").GetBlock("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mweb := block.MWEB
+	if mweb == nil {
+		t.Fatal("HogEx block has no MWEB section")
+	}
+	if mweb.Height != 2265984 || mweb.NumKernels != 1 || len(mweb.Inputs) != 0 || len(mweb.Outputs) != 1 {
+		t.Errorf("mweb = %+v", mweb)
+	}
+	if len(mweb.Kernels) != 1 || len(mweb.Kernels[0].KernelID) != 64 || mweb.Kernels[0].PegIn != 0 {
+		t.Errorf("kernels = %+v", mweb.Kernels)
+	}
+}
+
+func TestDecodeBlockMWEB(t *testing.T) {
+	block, err := serveFixture(t, "getblock_mweb.json").GetBlock("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mweb := block.MWEB
+	if mweb == nil || len(mweb.Inputs) != 2 || len(mweb.Outputs) != 3 || len(mweb.Kernels) != 2 {
+		t.Fatalf("mweb = %+v", mweb)
+	}
+	k := mweb.Kernels
+	if k[0].Fee != 100 || k[0].PegIn != 500000 || len(k[1].PegOuts) != 1 || k[1].PegOuts[0].Value != 1000 {
+		t.Errorf("kernels = %+v", k)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	DefaultCertExpiryThresholds = []int{30, 7, 1}
+
+	// certAlertState holds the lowest expiry threshold each endpoint has
+	// been alerted for, or certExpired, so each threshold and the expiry
+	// itself are only reported once per certificate.
+	certAlertState = make(map[string]int)
+
+	// certRoots are the roots certificates are verified against, the
+	// system roots when nil.
+	certRoots *x509.CertPool
+)
+
+// certExpired is the alert state of an expired certificate, below every
+// threshold as they can't be negative.
+const certExpired = -1
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+func TLSVersionString(version uint16) string {
+	if name, ok := tlsVersions[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown 0x%04x", version)
+}
+
+// DaysUntil returns the whole days from now until t, negative once t has
+// passed.
+func DaysUntil(t time.Time) int {
+	return int(math.Floor(time.Until(t).Hours() / 24))
+}
+
+// VerifyCertificateChain verifies the certificate presented in a TLS
+// handshake with host against certRoots, the system roots unless set, the
+// way the handshake itself would.
+func VerifyCertificateChain(state tls.ConnectionState, host string) error {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("no certificate presented")
+	}
+	intermediates := x509.NewCertPool()
+	for _, x := range state.PeerCertificates[1:] {
+		intermediates.AddCert(x)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         certRoots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// CertificateFromState records the certificate presented in a TLS
+// handshake with host, including whether its chain is valid for it.
+func CertificateFromState(state tls.ConnectionState, host string) (*CertificateInfo, error) {
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("no certificate presented")
+	}
+	leaf := state.PeerCertificates[0]
+
+	cert := &CertificateInfo{
+		Subject:    leaf.Subject.String(),
+		Issuer:     leaf.Issuer.String(),
+		SANs:       leaf.DNSNames,
+		NotBefore:  leaf.NotBefore.Unix(),
+		NotAfter:   leaf.NotAfter.Unix(),
+		TLSVersion: TLSVersionString(state.Version),
+	}
+	for _, ip := range leaf.IPAddresses {
+		cert.SANs = append(cert.SANs, ip.String())
+	}
+
+	if err := VerifyCertificateChain(state, host); err != nil {
+		cert.ChainError = err.Error()
+	} else {
+		cert.ChainValid = true
+	}
+	return cert, nil
+}
+
+// GetCertExpiryThresholds returns the configured alert thresholds in days,
+// largest first.
+func GetCertExpiryThresholds() []int {
+	var thresholds []int
+	for _, x := range FilterEmptyStrings(strings.Split(config.CertExpiryThresholds, ",")) {
+		days, err := strconv.Atoi(strings.TrimSpace(x))
+		if err != nil || days < 0 {
+			log.Printf("Ignoring invalid certificate expiry threshold %q.\n", x)
+			continue
+		}
+		thresholds = append(thresholds, days)
+	}
+	if len(thresholds) == 0 {
+		thresholds = append([]int(nil), DefaultCertExpiryThresholds...)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(thresholds)))
+	return thresholds
+}
+
+// CertExpiryThreshold returns the lowest threshold that days has reached,
+// or false if it is above all of them.
+func CertExpiryThreshold(days int, thresholds []int) (int, bool) {
+	threshold, reached := 0, false
+	for _, x := range thresholds {
+		if days <= x {
+			threshold, reached = x, true
+		}
+	}
+	return threshold, reached
+}
+
+// CheckCertificates sends a Slack alert the first time each HTTPS
+// endpoint's certificate passes one of the expiry thresholds, and once more
+// when it expires. The state is reset once a renewed certificate is above
+// all of them again.
+func CheckCertificates(sites []Site) {
+	thresholds := GetCertExpiryThresholds()
+	for _, x := range sites {
+		if x.Protocol.HTTPS.Certificate == nil || x.Protocol.HTTPS.DaysUntilExpiry == nil {
+			continue
+		}
+		endpoint := "https://" + x.Name
+		days := *x.Protocol.HTTPS.DaysUntilExpiry
+
+		threshold, reached := CertExpiryThreshold(days, thresholds)
+		if days < 0 {
+			threshold, reached = certExpired, true
+		}
+		if !reached {
+			delete(certAlertState, endpoint)
+			continue
+		}
+		if last, ok := certAlertState[endpoint]; ok && last <= threshold {
+			continue
+		}
+		certAlertState[endpoint] = threshold
+
+		expiry := time.Unix(x.Protocol.HTTPS.Certificate.NotAfter, 0).UTC().Format("2006-01-02 15:04 MST")
+		var result string
+		if days < 0 {
+			result = fmt.Sprintf("%s TLS certificate has expired. Expired on %s.", endpoint, expiry)
+		} else {
+			result = fmt.Sprintf("%s TLS certificate expires in %d day(s) on %s. Issuer: %s", endpoint, days, expiry,
+				x.Protocol.HTTPS.Certificate.Issuer)
+		}
+		log.Println(result)
+		if slack.Connected {
+			slack.SendMessage(slack.Channel, result)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCertExpiryThreshold(t *testing.T) {
+	thresholds := []int{30, 7, 1}
+	tests := []struct {
+		days      int
+		threshold int
+		reached   bool
+	}{
+		{90, 0, false},
+		{31, 0, false},
+		{30, 30, true},
+		{8, 30, true},
+		{7, 7, true},
+		{2, 7, true},
+		{1, 1, true},
+		{0, 1, true},
+		{-5, 1, true},
+	}
+	for _, test := range tests {
+		threshold, reached := CertExpiryThreshold(test.days, thresholds)
+		if threshold != test.threshold || reached != test.reached {
+			t.Errorf("CertExpiryThreshold(%d) = %d, %v, want %d, %v", test.days, threshold, reached, test.threshold, test.reached)
+		}
+	}
+}
+
+func TestGetCertExpiryThresholds(t *testing.T) {
+	defer func() { config = Config{} }()
+	tests := []struct {
+		config string
+		want   []int
+	}{
+		{"", []int{30, 7, 1}},
+		{"7, 30,1", []int{30, 7, 1}},
+		{"14", []int{14}},
+		{"5,soon,60", []int{60, 5}},
+		{"never", []int{30, 7, 1}},
+		{"-1,7", []int{7}},
+	}
+	for _, test := range tests {
+		config.CertExpiryThresholds = test.config
+		if got := GetCertExpiryThresholds(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("GetCertExpiryThresholds(%q) = %v, want %v", test.config, got, test.want)
+		}
+	}
+	if !reflect.DeepEqual(DefaultCertExpiryThresholds, []int{30, 7, 1}) {
+		t.Fatalf("DefaultCertExpiryThresholds changed to %v", DefaultCertExpiryThresholds)
+	}
+}
+
+func TestCheckCertificatesAlertState(t *testing.T) {
+	defer func() { certAlertState = make(map[string]int) }()
+	const endpoint = "https://www.example.org"
+	site := func(days int) []Site {
+		s := Site{Name: "www.example.org"}
+		s.Protocol.HTTPS.Certificate = &CertificateInfo{NotAfter: time.Now().Add(time.Duration(days) * 24 * time.Hour).Unix()}
+		s.Protocol.HTTPS.DaysUntilExpiry = &days
+		return []Site{s}
+	}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	// Each step is the days left on the certificate, the threshold alerted
+	// for afterwards, 0 for none, and whether the step sent an alert.
+	steps := []struct {
+		days  int
+		state int
+		alert bool
+	}{
+		{40, 0, false},
+		{30, 30, true},
+		{20, 30, false},
+		{6, 7, true},
+		{7, 7, false},
+		{0, 1, true},
+		{-3, certExpired, true},
+		{-4, certExpired, false},
+		// Renewed, so the state resets and the next threshold alerts again.
+		{90, 0, false},
+		{29, 30, true},
+	}
+	for _, step := range steps {
+		logged.Reset()
+		CheckCertificates(site(step.days))
+		if state, ok := certAlertState[endpoint]; state != step.state || ok != (step.state != 0) {
+			t.Fatalf("after %d days, alert state = %d, %v, want %d", step.days, state, ok, step.state)
+		}
+		if alerted := logged.Len() > 0; alerted != step.alert {
+			t.Fatalf("after %d days, alerted %v, want %v: %s", step.days, alerted, step.alert, logged.String())
+		}
+		if step.days < 0 && step.alert && !strings.Contains(logged.String(), "TLS certificate has expired") {
+			t.Fatalf("after %d days, alert %q, want an expiry alert", step.days, logged.String())
+		}
+	}
+
+	// An endpoint without a certificate leaves the state alone.
+	CheckCertificates([]Site{{Name: "www.example.org"}})
+	if certAlertState[endpoint] != 30 {
+		t.Fatalf("alert state = %v after a check without a certificate", certAlertState)
+	}
+}
+
+func TestDaysUntil(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		days int
+	}{
+		{-time.Hour, -1},
+		{time.Hour, 0},
+		{25 * time.Hour, 1},
+		{-49 * time.Hour, -3},
+	}
+	for _, test := range tests {
+		if days := DaysUntil(time.Now().Add(test.d)); days != test.days {
+			t.Errorf("DaysUntil(now + %s) = %d, want %d", test.d, days, test.days)
+		}
+	}
+}
+
+// expiredCertificate returns a self-signed certificate for 127.0.0.1 that
+// expired 47 hours ago, which DaysUntil counts as -2.
+func expiredCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "expired"},
+		NotBefore:             time.Now().Add(-48 * time.Hour * 30),
+		NotAfter:              time.Now().Add(-47 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestSendHTTPRequestCertificate(t *testing.T) {
+	defer func() { certRoots = nil }()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	opts := DefaultHTTPRequestOptions()
+
+	// The test server's certificate isn't trusted by the system roots. The
+	// request fails as before, but the certificate is still recorded.
+	resp, err := SendHTTPRequest(context.Background(), srv.URL, opts)
+	if err == nil || resp.TLS == nil {
+		t.Fatalf("untrusted certificate: %v, TLS state %v", err, resp.TLS)
+	}
+	cert, err := CertificateFromState(*resp.TLS, "127.0.0.1")
+	if err != nil || cert.ChainValid || cert.ChainError == "" || len(cert.SANs) == 0 {
+		t.Fatalf("untrusted certificate = %+v, %v", cert, err)
+	}
+
+	certRoots = x509.NewCertPool()
+	certRoots.AddCert(srv.Certificate())
+	resp, err = SendHTTPRequest(context.Background(), srv.URL, opts)
+	if err != nil || resp.TLS == nil {
+		t.Fatalf("trusted certificate: %v, TLS state %v", err, resp.TLS)
+	}
+	cert, err = CertificateFromState(*resp.TLS, "127.0.0.1")
+	if err != nil || !cert.ChainValid || cert.TLSVersion != "TLS 1.3" || cert.NotAfter != srv.Certificate().NotAfter.Unix() {
+		t.Fatalf("trusted certificate = %+v, %v", cert, err)
+	}
+
+	// An expired certificate is reported with the days since it expired.
+	expired := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	expired.TLS = &tls.Config{Certificates: []tls.Certificate{expiredCertificate(t)}}
+	expired.StartTLS()
+	defer expired.Close()
+	leaf, _ := x509.ParseCertificate(expired.TLS.Certificates[0].Certificate[0])
+	certRoots.AddCert(leaf)
+
+	resp, err = SendHTTPRequest(context.Background(), expired.URL, opts)
+	if err == nil || !strings.Contains(err.Error(), "expired") || resp.TLS == nil {
+		t.Fatalf("expired certificate: %v, TLS state %v", err, resp.TLS)
+	}
+	cert, err = CertificateFromState(*resp.TLS, "127.0.0.1")
+	if err != nil || cert.ChainValid || DaysUntil(time.Unix(cert.NotAfter, 0)) != -2 {
+		t.Fatalf("expired certificate = %+v, %v", cert, err)
+	}
+}
+
+func TestSendHTTPRequestPlainHTTP(t *testing.T) {
+	srv := testServer(t, "")
+	resp, err := SendHTTPRequest(context.Background(), srv.URL+"/echo", DefaultHTTPRequestOptions())
+	if err != nil || resp.TLS != nil {
+		t.Fatalf("plain HTTP: %v, TLS state %v", err, resp.TLS)
+	}
+}
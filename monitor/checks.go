@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWorkers      = 8
+	DefaultCheckTimeout = time.Second * 60
+)
+
+func GetWorkers() int {
+	if config.Workers > 0 {
+		return config.Workers
+	}
+	return DefaultWorkers
+}
+
+func GetCheckTimeout() time.Duration {
+	if config.CheckTimeout > 0 {
+		return time.Second * config.CheckTimeout
+	}
+	return DefaultCheckTimeout
+}
+
+func TimeoutError() string {
+	return fmt.Sprintf("check timed out after %s", GetCheckTimeout())
+}
+
+// RunChecks calls check for every index in [0, n) with at most GetWorkers
+// checks running at once, and returns when all of them are done. Checks
+// store their result by index so the output order doesn't depend on which
+// finished first.
+func RunChecks(n int, check func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < GetWorkers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				check(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// RunWithTimeout runs check with a context that is cancelled after
+// GetCheckTimeout, and returns false if it didn't finish by then. Checks
+// must give up their network requests once the context is done. One that
+// times out is not waited for, so it must not write anything the caller
+// reads afterwards.
+func RunWithTimeout(check func(ctx context.Context)) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), GetCheckTimeout())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		check(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunChecks(t *testing.T) {
+	config.Workers = 3
+	defer func() { config = Config{} }()
+
+	var running, peak int32
+	results := make([]int, 20)
+	RunChecks(len(results), func(i int) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		// Later checks finish first.
+		time.Sleep(time.Millisecond * time.Duration(20-i))
+		results[i] = i * i
+		atomic.AddInt32(&running, -1)
+	})
+
+	for i, x := range results {
+		if x != i*i {
+			t.Fatalf("results out of order: %v", results)
+		}
+	}
+	if peak != 3 {
+		t.Fatalf("%d checks ran at once, want 3", peak)
+	}
+}
+
+func TestRunWithTimeout(t *testing.T) {
+	config.CheckTimeout = 1
+	defer func() { config = Config{} }()
+
+	if !RunWithTimeout(func(ctx context.Context) {}) {
+		t.Fatal("RunWithTimeout = false for a check that finished")
+	}
+
+	// A check that times out has its context cancelled, so it can stop.
+	cancelled := make(chan struct{})
+	tm := time.Now()
+	ok := RunWithTimeout(func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+	if ok {
+		t.Fatal("RunWithTimeout = true for a check that timed out")
+	}
+	if elapsed := time.Since(tm); elapsed < time.Second || elapsed > 2*time.Second {
+		t.Fatalf("timed out after %s, want 1s", elapsed)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the check's context was not cancelled")
+	}
+
+	if TimeoutError() != "check timed out after 1s" {
+		t.Fatalf("TimeoutError = %q", TimeoutError())
+	}
+}
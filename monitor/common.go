@@ -1,8 +1,8 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -62,36 +62,27 @@ func GetExternalIP() (string, error) {
 	return strings.Trim(ipAddr, "\n"), nil
 }
 
+// SendHTTPGetRequest fetches url with the default request options, except
+// that a 400 response is accepted as well as a 200, and optionally decodes
+// it as JSON.
 func SendHTTPGetRequest(url string, jsonDecode bool) (result interface{}, contentSize, httpCode int, err error) {
-	res, err := http.Get(url)
-
-	if err != nil {
-		return
-	}
-
-	httpCode = res.StatusCode
-	if httpCode != 200 && httpCode != 400 {
-		err = fmt.Errorf("HTTP Status code was not equal to 200. Code: %d", httpCode)
-		return
-	}
-
-	contents, err := ioutil.ReadAll(res.Body)
-	contentSize = len(contents)
-
+	opts := DefaultHTTPRequestOptions()
+	opts.ExpectedStatus = []int{http.StatusOK, http.StatusBadRequest}
+	resp, err := SendHTTPRequest(context.Background(), url, opts)
+	httpCode = resp.StatusCode
+	contentSize = len(resp.Content)
 	if err != nil {
 		return
 	}
 
-	defer res.Body.Close()
-
 	if jsonDecode {
-		err := JSONDecode(contents, &result)
+		err := JSONDecode(resp.Content, &result)
 
 		if err != nil {
 			return result, contentSize, httpCode, err
 		}
 	} else {
-		result = string(contents)
+		result = string(resp.Content)
 	}
 
 	return
@@ -14,12 +14,17 @@ type Config struct {
 	HTTPServer               string               `json:"http_server"`
 	Slack                    ConfigSlack          `json:"slack"`
 	DNSSeeders               []ConfigDNSSeeders   `json:"dns_seeders"`
+	DNSResolvers             string               `json:"dns_resolvers"`
+	DNSTimeout               time.Duration        `json:"dns_timeout,omitempty"`
 	Websites                 []ConfigWebsites     `json:"websites"`
 	LitecoinServer           ConfigLitecoinServer `json:"litecoin_server"`
 	CheckDelay               time.Duration        `json:"check_delay"`
+	CheckTimeout             time.Duration        `json:"check_timeout,omitempty"`
+	Workers                  int                  `json:"workers,omitempty"`
 	ErrorTransitionThreshold int                  `json:"error_transition_threshold"`
 	KnownErrorEndpoints      string               `json:"known_error_endpoints"`
 	ReportBlocks             bool                 `json:"report_blocks"`
+	CertExpiryThresholds     string               `json:"cert_expiry_thresholds"`
 	APIUrl                   string               `json:"api_url"`
 }
 
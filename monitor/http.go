@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHTTPTimeout  = time.Second * 30
+	DefaultRetryBackoff = time.Second * 2
+	DefaultMaxRedirects = 10
+	DefaultUserAgent    = "litecoin-tools-monitor"
+
+	REDIRECT_FOLLOW    = "follow"
+	REDIRECT_NONE      = "none"
+	REDIRECT_SAME_HOST = "same_host"
+)
+
+// HTTPRequestOptions controls how SendHTTPRequest makes a request and which
+// responses it accepts.
+type HTTPRequestOptions struct {
+	Method         string
+	Headers        map[string]string
+	UserAgent      string
+	Timeout        time.Duration
+	Retries        int
+	RetryBackoff   time.Duration
+	ExpectedStatus []int
+	Redirects      string
+	MaxRedirects   int
+}
+
+type HTTPResponse struct {
+	Content    []byte
+	StatusCode int
+	FinalURL   string
+	Redirects  int
+	Attempts   int
+	// TLS is the handshake of the first connection to the requested host,
+	// kept even when its certificate failed verification.
+	TLS *tls.ConnectionState
+}
+
+// DefaultHTTPRequestOptions is a GET with DefaultHTTPTimeout that follows
+// redirects and only accepts a 200 response.
+func DefaultHTTPRequestOptions() HTTPRequestOptions {
+	return HTTPRequestOptions{
+		Method:         http.MethodGet,
+		UserAgent:      DefaultUserAgent,
+		Timeout:        DefaultHTTPTimeout,
+		RetryBackoff:   DefaultRetryBackoff,
+		ExpectedStatus: []int{http.StatusOK},
+		Redirects:      REDIRECT_FOLLOW,
+		MaxRedirects:   DefaultMaxRedirects,
+	}
+}
+
+// GetHTTPRequestOptions returns the request options for a website, falling
+// back to the defaults for anything it doesn't set.
+func GetHTTPRequestOptions(cfg ConfigWebsites) (HTTPRequestOptions, error) {
+	opts := DefaultHTTPRequestOptions()
+	if cfg.Method != "" {
+		opts.Method = strings.ToUpper(cfg.Method)
+	}
+	if cfg.UserAgent != "" {
+		opts.UserAgent = cfg.UserAgent
+	}
+	opts.Headers = cfg.Headers
+	if cfg.Timeout > 0 {
+		opts.Timeout = time.Second * cfg.Timeout
+	}
+	opts.Retries = cfg.Retries
+	if cfg.RetryBackoff > 0 {
+		opts.RetryBackoff = time.Second * cfg.RetryBackoff
+	}
+	if cfg.MaxRedirects > 0 {
+		opts.MaxRedirects = cfg.MaxRedirects
+	}
+
+	switch cfg.Redirects {
+	case "":
+	case REDIRECT_FOLLOW, REDIRECT_NONE, REDIRECT_SAME_HOST:
+		opts.Redirects = cfg.Redirects
+	default:
+		return opts, fmt.Errorf("unknown redirect policy %q", cfg.Redirects)
+	}
+
+	codes := FilterEmptyStrings(strings.Split(cfg.ExpectedStatus, ","))
+	if len(codes) > 0 {
+		opts.ExpectedStatus = nil
+		for _, x := range codes {
+			code, err := strconv.Atoi(strings.TrimSpace(x))
+			if err != nil {
+				return opts, fmt.Errorf("invalid expected status code %q", x)
+			}
+			opts.ExpectedStatus = append(opts.ExpectedStatus, code)
+		}
+	}
+	return opts, nil
+}
+
+func (opts HTTPRequestOptions) IsExpectedStatus(code int) bool {
+	for _, x := range opts.ExpectedStatus {
+		if x == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Client returns an http.Client with the options' timeout and redirect
+// policy. Certificates are verified by VerifyCertificateChain rather than
+// during the handshake, so onTLS sees every handshake with the host it was
+// for, including those whose certificate is then rejected.
+func (opts HTTPRequestOptions) Client(onTLS func(host string, state tls.ConnectionState)) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialTLSContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		dialer := &tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: opts.Timeout},
+			Config: &tls.Config{
+				ServerName:         host,
+				InsecureSkipVerify: true,
+				VerifyConnection: func(state tls.ConnectionState) error {
+					if onTLS != nil {
+						onTLS(host, state)
+					}
+					return VerifyCertificateChain(state, host)
+				},
+			},
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			switch {
+			case opts.Redirects == REDIRECT_NONE:
+				return http.ErrUseLastResponse
+			case len(via) >= opts.MaxRedirects:
+				return fmt.Errorf("stopped after %d redirects", opts.MaxRedirects)
+			case opts.Redirects == REDIRECT_SAME_HOST && req.URL.Host != via[0].URL.Host:
+				return fmt.Errorf("redirect to another host %s", req.URL.Host)
+			}
+			return nil
+		},
+	}
+}
+
+func sendHTTPRequest(ctx context.Context, client *http.Client, url string, opts HTTPRequestOptions) (*HTTPResponse, error) {
+	req, err := http.NewRequest(opts.Method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
+	}
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resp := &HTTPResponse{StatusCode: res.StatusCode, FinalURL: res.Request.URL.String()}
+	for r := res.Request; r.Response != nil; r = r.Response.Request {
+		resp.Redirects++
+	}
+
+	resp.Content, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return resp, err
+	}
+
+	if !opts.IsExpectedStatus(res.StatusCode) {
+		return resp, fmt.Errorf("HTTP status code %d was not one of %v", res.StatusCode, opts.ExpectedStatus)
+	}
+	return resp, nil
+}
+
+// SendHTTPRequest requests url, retrying up to opts.Retries times with the
+// backoff doubling after each failed attempt, until ctx is done. The last
+// attempt's response is returned with any error.
+func SendHTTPRequest(ctx context.Context, url string, opts HTTPRequestOptions) (*HTTPResponse, error) {
+	var host string
+	if u, err := neturl.Parse(url); err == nil {
+		host = u.Hostname()
+	}
+	var tlsMtx sync.Mutex
+	var tlsState *tls.ConnectionState
+	client := opts.Client(func(serverName string, state tls.ConnectionState) {
+		tlsMtx.Lock()
+		defer tlsMtx.Unlock()
+		if tlsState == nil && serverName == host {
+			tlsState = &state
+		}
+	})
+	defer client.CloseIdleConnections()
+	backoff := opts.RetryBackoff
+
+	var resp *HTTPResponse
+	var err error
+	for attempt := 1; ; attempt++ {
+		resp, err = sendHTTPRequest(ctx, client, url, opts)
+		if resp == nil {
+			resp = &HTTPResponse{}
+		}
+		resp.Attempts = attempt
+		if err == nil || attempt > opts.Retries {
+			tlsMtx.Lock()
+			resp.TLS = tlsState
+			tlsMtx.Unlock()
+			return resp, err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetHTTPRequestOptions(t *testing.T) {
+	cfg := ConfigWebsites{Method: "head", Retries: 2, ExpectedStatus: "200, 204", Redirects: REDIRECT_NONE}
+	opts, err := GetHTTPRequestOptions(cfg)
+	if err != nil || opts.Method != http.MethodHead || len(opts.ExpectedStatus) != 2 || opts.Timeout != DefaultHTTPTimeout || opts.Redirects != REDIRECT_NONE {
+		t.Fatalf("GetHTTPRequestOptions = %+v, %v", opts, err)
+	}
+
+	tests := []ConfigWebsites{
+		{Redirects: "sometimes"},
+		{ExpectedStatus: "200,ok"},
+		{ExpectedStatus: "2xx"},
+	}
+	for _, cfg := range tests {
+		if _, err := GetHTTPRequestOptions(cfg); err == nil {
+			t.Errorf("GetHTTPRequestOptions(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
+
+// testServer answers:
+//
+//	/flaky     500 for the first two requests, then 200
+//	/redirect  a redirect to /echo
+//	/loop      a redirect to itself
+//	/away      a redirect to other
+//	/bad       400
+//	/echo      the method, user agent and X-Test header
+func testServer(t *testing.T, other string) *httptest.Server {
+	var failures int32 = 2
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if atomic.AddInt32(&failures, -1) >= 0 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte("ok"))
+		case "/redirect":
+			http.Redirect(w, r, "/echo", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/away":
+			http.Redirect(w, r, other+"/echo", http.StatusFound)
+		case "/bad":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.Write([]byte(r.Method + " " + r.UserAgent() + " " + r.Header.Get("X-Test")))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSendHTTPRequestRetries(t *testing.T) {
+	srv := testServer(t, "")
+	opts := DefaultHTTPRequestOptions()
+	opts.RetryBackoff = time.Millisecond
+
+	// Without retries the 500 is returned.
+	resp, err := SendHTTPRequest(context.Background(), srv.URL+"/flaky", opts)
+	if err == nil || resp.StatusCode != http.StatusInternalServerError || resp.Attempts != 1 {
+		t.Fatalf("no retries: %+v, %v", resp, err)
+	}
+
+	// The next request gets the second 500, which is retried.
+	opts.Retries = 2
+	resp, err = SendHTTPRequest(context.Background(), srv.URL+"/flaky", opts)
+	if err != nil || resp.StatusCode != http.StatusOK || resp.Attempts != 2 || string(resp.Content) != "ok" {
+		t.Fatalf("with retries: %+v, %v", resp, err)
+	}
+
+	// A cancelled context stops the retries.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts.RetryBackoff = time.Minute
+	if _, err := SendHTTPRequest(ctx, srv.URL+"/bad", opts); err == nil {
+		t.Fatal("SendHTTPRequest succeeded with a cancelled context")
+	}
+}
+
+func TestSendHTTPRequestRedirects(t *testing.T) {
+	other := testServer(t, "")
+	srv := testServer(t, other.URL)
+
+	tests := []struct {
+		path      string
+		redirects string
+		max       int
+		status    int
+		count     int
+		err       string
+	}{
+		{"/redirect", REDIRECT_FOLLOW, 10, http.StatusOK, 1, ""},
+		{"/away", REDIRECT_FOLLOW, 10, http.StatusOK, 1, ""},
+		{"/redirect", REDIRECT_NONE, 10, http.StatusFound, 0, "302 was not one of"},
+		{"/redirect", REDIRECT_SAME_HOST, 10, http.StatusOK, 1, ""},
+		{"/away", REDIRECT_SAME_HOST, 10, 0, 0, "redirect to another host"},
+		{"/loop", REDIRECT_FOLLOW, 3, 0, 0, "stopped after 3 redirects"},
+	}
+	for _, test := range tests {
+		opts := DefaultHTTPRequestOptions()
+		opts.Redirects = test.redirects
+		opts.MaxRedirects = test.max
+		opts.Headers = map[string]string{"X-Test": "hi"}
+		opts.UserAgent = "ua"
+
+		resp, err := SendHTTPRequest(context.Background(), srv.URL+test.path, opts)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s %s: error %v, want %q", test.redirects, test.path, err, test.err)
+			}
+		} else if err != nil || !strings.HasSuffix(resp.FinalURL, "/echo") || string(resp.Content) != "GET ua hi" {
+			t.Errorf("%s %s: %+v, %v", test.redirects, test.path, resp, err)
+		}
+		if resp.StatusCode != test.status || resp.Redirects != test.count {
+			t.Errorf("%s %s: status %d after %d redirects, want %d after %d", test.redirects, test.path, resp.StatusCode, resp.Redirects, test.status, test.count)
+		}
+	}
+}
+
+func TestSendHTTPRequestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-done }))
+	defer srv.Close()
+	defer close(done)
+
+	opts := DefaultHTTPRequestOptions()
+	opts.Timeout = time.Millisecond * 100
+	if _, err := SendHTTPRequest(context.Background(), srv.URL, opts); err == nil {
+		t.Fatal("SendHTTPRequest succeeded against a server that never answers")
+	}
+}
+
+func TestSendHTTPGetRequest(t *testing.T) {
+	srv := testServer(t, "")
+
+	// A 400 is accepted, as it always has been.
+	if _, _, code, err := SendHTTPGetRequest(srv.URL+"/bad", false); err != nil || code != http.StatusBadRequest {
+		t.Fatalf("400 response: code %d, %v", code, err)
+	}
+	if _, _, code, err := SendHTTPGetRequest(srv.URL+"/flaky", false); err == nil || code != http.StatusInternalServerError {
+		t.Fatalf("500 response: code %d, %v", code, err)
+	}
+	result, size, _, err := SendHTTPGetRequest(srv.URL+"/echo", false)
+	if err != nil || result.(string) != "GET "+DefaultUserAgent+" " || size != len(result.(string)) {
+		t.Fatalf("SendHTTPGetRequest = %v, %d, %v", result, size, err)
+	}
+}
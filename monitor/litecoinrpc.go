@@ -1,112 +1,39 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"strings"
 	"time"
-)
-
-func BuildLitecoinServerURL() string {
-	return fmt.Sprintf("http://%s:%s@%s:%d", config.LitecoinServer.RPCUsername, config.LitecoinServer.RPCPassword, config.LitecoinServer.RPCServer, config.LitecoinServer.RPCPort)
-}
-
-func SendRPCRequest(method, req interface{}) (map[string]interface{}, error) {
-	var params []interface{}
-	if req != nil {
-		params = append(params, req)
-	} else {
-		params = nil
-	}
-
-	data, err := json.Marshal(map[string]interface{}{
-		"method": method,
-		"id":     1,
-		"params": params,
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := http.Post(BuildLitecoinServerURL(), "application/json", strings.NewReader(string(data)))
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	result := make(map[string]interface{})
-	err = json.Unmarshal(body, &result)
-	if err != nil {
-		return nil, err
-	}
 
-	if result["error"] != nil {
-		errorMsg := result["error"].(map[string]interface{})
-		return nil, fmt.Errorf("Error code: %v, message: %v\n", errorMsg["code"], errorMsg["message"])
-	}
-	return result, nil
-}
-
-func GetBlockHeight() (int64, error) {
-	result, err := SendRPCRequest("getblockchaininfo", nil)
-	if err != nil {
-		return 0, err
-	}
-	result = result["result"].(map[string]interface{})
-	block := result["blocks"].(float64)
-	return int64(block), nil
-}
-
-func GetBlockHash(block int64) (string, error) {
-	result, err := SendRPCRequest("getblockhash", block)
-	if err != nil {
-		return "", err
-	}
-	return result["result"].(string), nil
-}
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+)
 
-func GetBlockTime(block string) (int64, error) {
-	result, err := SendRPCRequest("getblock", block)
-	if err != nil {
-		return 0, err
+func NewLitecoinRPCClient(cfg ConfigLitecoinServer) *litecoinrpc.Client {
+	client := litecoinrpc.New(cfg.RPCServer, cfg.RPCPort, cfg.RPCUsername, cfg.RPCPassword)
+	client.CookieFile = cfg.RPCCookieFile
+	if cfg.RPCTimeout > 0 {
+		client.Timeout = time.Second * cfg.RPCTimeout
 	}
-
-	result = result["result"].(map[string]interface{})
-	blockTime := result["time"].(float64)
-	return int64(blockTime), nil
+	return client
 }
 
 func TestBlockHeight() (BlockInfo, error) {
 	var blockInfo BlockInfo
-	blockHeight, err := GetBlockHeight()
-	if err != nil {
-		return blockInfo, err
-	}
-
-	blockHash, err := GetBlockHash(blockHeight)
+	chainInfo, err := rpcClient.GetBlockchainInfo()
 	if err != nil {
 		return blockInfo, err
 	}
 
-	blockTime, err := GetBlockTime(blockHash)
+	header, err := rpcClient.GetBlockHeader(chainInfo.BestBlockHash)
 	if err != nil {
 		return blockInfo, err
 	}
 
-	blockInfo.BlockHeight = blockHeight
-	blockInfo.BlockHash = blockHash
-	blockInfo.BlockTime = blockTime
-	blockInfo.TimeElapsed = GetSecondsElapsed(blockTime)
-	blockInfo.Status = TimeSinceLastBlock(blockTime)
+	blockInfo.BlockHeight = header.Height
+	blockInfo.BlockHash = header.Hash
+	blockInfo.BlockTime = header.Time
+	blockInfo.TimeElapsed = GetSecondsElapsed(header.Time)
+	blockInfo.Status = TimeSinceLastBlock(header.Time)
 	return blockInfo, nil
 }
 
@@ -1,49 +1,115 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
-	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
 )
 
 var (
 	output              Output
 	slack               Slack
 	config              Config
+	rpcClient           *litecoinrpc.Client
+	resolver            *Resolver
 	ip                  string
 	endpointErrorState  map[string]int
 	knownErrorEndpoints []string
 )
 
-func TestSeeders(name string, seeders []string) []DNSSeeder {
-	log.Printf("Testing %s seeders..\n", name)
+// TestSeeder looks up seeder x and audits the addresses it returned. The
+// lookup has its own check timeout, separate from the audit's, so a seeder
+// that answered stays ONLINE with its per nameserver results even when the
+// audit runs out of time.
+func TestSeeder(cfg ConfigDNSSeeders, x string) DNSSeeder {
+	seeder := DNSSeeder{Name: x, Type: cfg.Type}
 	tm := time.Now()
-	errCounter := 0
-	var dnsList []DNSSeeder
-	for _, x := range seeders {
-		seeder := DNSSeeder{Name: x, Type: name}
-		tm2 := time.Now()
-		result, err := net.LookupHost(x)
-		if err != nil {
-			errCounter++
-			seeder.Status = GetOnlineOffline(false)
-			seeder.Error = err.Error()
-			log.Printf("%s FAIL.\t\t Test took %s. Error: %s\n", x, time.Since(tm2).String(), err)
-		} else {
-			seeder.Status = GetOnlineOffline(true)
-			seeder.NodeCount = len(result)
-			log.Printf("%s OK\t\t %d hosts returned. Test took %s\n", x, len(result), time.Since(tm2).String())
+	var result []string
+	var resolvers []ResolverResult
+	var err error
+	if !RunWithTimeout(func(ctx context.Context) { result, resolvers, err = resolver.LookupHost(ctx, x) }) {
+		seeder.Status = GetOnlineOffline(false)
+		seeder.Error = TimeoutError()
+		log.Printf("%s FAIL.\t\t Error: %s\n", x, seeder.Error)
+		return seeder
+	}
+	seeder.Resolvers = resolvers
+	if err != nil {
+		seeder.Status = GetOnlineOffline(false)
+		seeder.Error = err.Error()
+		log.Printf("%s FAIL.\t\t Test took %s. Error: %s\n", x, time.Since(tm).String(), err)
+		return seeder
+	}
+
+	seeder.Status = GetOnlineOffline(true)
+	seeder.NodeCount = len(result)
+	for _, r := range resolvers {
+		if r.Server == DNSAuthoritativeLookup {
+			seeder.Warnings = append(seeder.Warnings, fmt.Sprintf("authoritative nameserver lookup failed: %s", r.Error))
+			continue
+		}
+		if r.Error != "" {
+			seeder.Warnings = append(seeder.Warnings, fmt.Sprintf("nameserver %s failed: %s", r.Server, r.Error))
+			continue
+		}
+		for _, qtype := range []string{"A", "AAAA"} {
+			if e, ok := r.QueryErrors[qtype]; ok {
+				seeder.Warnings = append(seeder.Warnings, fmt.Sprintf("nameserver %s %s query failed: %s", r.Server, qtype, e))
+			}
+		}
+	}
+	AuditSeeder(&seeder, cfg, result)
+	log.Printf("%s OK\t\t %d hosts returned (%d IPv4, %d IPv6). Test took %s\n", x, len(result), seeder.IPv4Count, seeder.IPv6Count, time.Since(tm).String())
+	for _, warning := range seeder.Warnings {
+		log.Printf("%s WARNING: %s\n", x, warning)
+	}
+	return seeder
+}
+
+// TestSeeders checks every seeder in cfgs concurrently and returns the
+// results in config order.
+func TestSeeders(cfgs []ConfigDNSSeeders) []DNSSeeder {
+	type job struct {
+		cfg  ConfigDNSSeeders
+		host string
+	}
+	var jobs []job
+	for _, x := range cfgs {
+		for _, y := range strings.Split(x.Hosts, ",") {
+			jobs = append(jobs, job{x, y})
+		}
+	}
+
+	log.Printf("Testing %d DNS seeders..\n", len(jobs))
+	tm := time.Now()
+	dnsList := make([]DNSSeeder, len(jobs))
+	RunChecks(len(jobs), func(i int) {
+		dnsList[i] = TestSeeder(jobs[i].cfg, jobs[i].host)
+	})
+
+	for _, x := range cfgs {
+		online, total := 0, 0
+		for _, y := range dnsList {
+			if y.Type != x.Type {
+				continue
+			}
+			total++
+			if y.Error == "" {
+				online++
+			}
 		}
-		dnsList = append(dnsList, seeder)
+		log.Printf("%d/%d %s DNS seeders online.\n", online, total, x.Type)
 	}
-	log.Printf("%d/%d %s DNS seeders online. Total test duration took %s\n", len(seeders)-errCounter, len(seeders), name, time.Since(tm).String())
+	log.Printf("Total DNS seeder test duration took %s\n", time.Since(tm).String())
 	return dnsList
 }
 
@@ -68,64 +134,122 @@ func CheckContentMatch(endpoint, content string) bool {
 	return false
 }
 
-func TestSites(name string, subdomains []string) []Site {
-	log.Printf("Testing %s site..\n", name)
+func TestSiteProtocol(ctx context.Context, cfg ConfigWebsites, subdomain, prefix string) SiteProtocol {
+	var result SiteProtocol
+	if IsSiteExluded(cfg.Host, prefix) {
+		result.Status = "NA"
+		result.Error = ""
+		return result
+	}
+
+	url := fmt.Sprintf("%s%s.%s", prefix, subdomain, cfg.Host)
+	opts, err := GetHTTPRequestOptions(cfg)
+	if err != nil {
+		result.Status = GetOnlineOffline(false)
+		result.Error = err.Error()
+		log.Printf("%s FAIL.\t\t Error: %s\n", url, err)
+		return result
+	}
+
+	endpoint := StripHTTPPrefix(url)
 	tm := time.Now()
-	errCounter := 0
+	resp, err := SendHTTPRequest(ctx, url, opts)
+	result.Method = opts.Method
+	result.ContentSize = len(resp.Content)
+	result.HTTPCode = resp.StatusCode
+	result.RespTime = time.Since(tm).String()
+	result.Attempts = resp.Attempts
+	result.Redirects = resp.Redirects
+	result.FinalURL = resp.FinalURL
+	result.Status = GetOnlineOffline(true) // default to online
+
+	if prefix == "https://" {
+		var cert *CertificateInfo
+		certErr := fmt.Errorf("no TLS connection was made")
+		if resp.TLS != nil {
+			cert, certErr = CertificateFromState(*resp.TLS, subdomain+"."+cfg.Host)
+		}
+		if certErr != nil {
+			log.Printf("%s certificate check FAIL. Error: %s\n", url, certErr)
+		} else {
+			days := DaysUntil(time.Unix(cert.NotAfter, 0))
+			result.Certificate = cert
+			result.DaysUntilExpiry = &days
+			log.Printf("%s certificate issued by %s expires in %d day(s). %s, chain valid: %v\n", url, cert.Issuer,
+				days, cert.TLSVersion, cert.ChainValid)
+		}
+	}
+
+	contentMatch := CheckContentMatch(endpoint, string(resp.Content))
+
+	if err != nil || !contentMatch {
+		result.Status = GetOnlineOffline(false)
+
+		if err == nil {
+			err = fmt.Errorf("%s content match failed", url)
+		}
+
+		result.Error = err.Error()
+		log.Printf("%s FAIL.\t\t Test took %s. Error: %s\n", url, time.Since(tm).String(), err)
+	} else {
+		log.Println(url, "content match PASSED!")
+		log.Printf("%s OK.\t\t Test took %s\n", url, time.Since(tm).String())
+	}
+	return result
+}
+
+// TestSites checks the http and https endpoints of every website subdomain
+// in cfgs concurrently and returns the results in config order.
+func TestSites(cfgs []ConfigWebsites) []Site {
+	type job struct {
+		site      int
+		cfg       ConfigWebsites
+		subdomain string
+		prefix    string
+	}
+	var jobs []job
 	var siteList []Site
-	httpPrefixes := []string{"http://", "https://"}
-
-	for _, x := range subdomains {
-		var site Site
-		for _, y := range httpPrefixes {
-			url := fmt.Sprintf("%s%s.%s", y, x, name)
-			site.Name = StripHTTPPrefix(url)
-			var result SiteProtocol
-
-			if IsSiteExluded(name, y) {
-				result.Status = "NA"
-				result.Error = ""
-			} else {
-				tm2 := time.Now()
-				content, contentSize, httpCode, err := SendHTTPGetRequest(url, false)
-				result.ContentSize = contentSize
-				result.HTTPCode = httpCode
-				result.RespTime = time.Since(tm2).String()
-				result.Status = GetOnlineOffline(true) // default to online
-
-				var contentMatch bool
-				if content == nil {
-					contentMatch = CheckContentMatch(site.Name, "")
-				} else {
-					contentMatch = CheckContentMatch(site.Name, content.(string))
-				}
-
-				if err != nil || !contentMatch {
-					result.Status = GetOnlineOffline(false)
-
-					if !contentMatch {
-						err = fmt.Errorf("%s content match failed", url)
-					}
-
-					result.Error = err.Error()
-					site.NeedsAttention = true
-					log.Printf("%s FAIL.\t\t Test took %s. Error: %s\n", url, time.Since(tm2).String(), err)
-				} else {
-					log.Println(url, "content match PASSED!")
-					log.Printf("%s OK.\t\t Test took %s\n", url, time.Since(tm2).String())
-				}
+	for _, x := range cfgs {
+		for _, y := range strings.Split(x.Subdomains, ",") {
+			for _, z := range []string{"http://", "https://"} {
+				jobs = append(jobs, job{len(siteList), x, y, z})
 			}
+			siteList = append(siteList, Site{Name: y + "." + x.Host})
+		}
+	}
+
+	log.Printf("Testing %d site endpoints..\n", len(jobs))
+	tm := time.Now()
+	results := make([]SiteProtocol, len(jobs))
+	RunChecks(len(jobs), func(i int) {
+		var result SiteProtocol
+		if RunWithTimeout(func(ctx context.Context) {
+			result = TestSiteProtocol(ctx, jobs[i].cfg, jobs[i].subdomain, jobs[i].prefix)
+		}) {
+			results[i] = result
+			return
+		}
+		results[i] = SiteProtocol{Status: GetOnlineOffline(false), Error: TimeoutError()}
+		log.Printf("%s%s.%s FAIL.\t\t Error: %s\n", jobs[i].prefix, jobs[i].subdomain, jobs[i].cfg.Host, results[i].Error)
+	})
 
-			if y == "http://" {
-				site.Protocol.HTTP = result
-			} else {
-				site.Protocol.HTTPS = result
+	errCounter := 0
+	for i, x := range jobs {
+		site := &siteList[x.site]
+		if x.prefix == "http://" {
+			site.Protocol.HTTP = results[i]
+		} else {
+			site.Protocol.HTTPS = results[i]
+		}
+		if results[i].Error != "" {
+			if !site.NeedsAttention {
+				errCounter++
 			}
+			site.NeedsAttention = true
 		}
-		siteList = append(siteList, site)
 	}
 
-	log.Printf("%d/%d %s site components online. Total test duration took %s\n", len(subdomains)-errCounter, len(subdomains), name, time.Since(tm).String())
+	log.Printf("%d/%d site components online. Total test duration took %s\n", len(siteList)-errCounter, len(siteList), time.Since(tm).String())
 	return siteList
 }
 
@@ -316,34 +440,33 @@ func main() {
 	log.Println("Loaded config.")
 	log.Println("Check delay set to", (config.CheckDelay * time.Minute).Minutes(), "minute(s).")
 	log.Printf("Error transition threshold set to %d.\n", config.ErrorTransitionThreshold)
+	log.Printf("Running up to %d checks at once with a %s timeout.\n", GetWorkers(), GetCheckTimeout())
 
 	knownErrorEndpoints = FilterEmptyStrings(strings.Split(config.KnownErrorEndpoints, ","))
 	log.Printf("Ignoring known error endpoints until resolution: %s\n", knownErrorEndpoints)
 
 	go SlackConnect(config.Slack.Token, config.Slack.Channel)
 
+	resolver, err = NewResolver(FilterEmptyStrings(strings.Split(config.DNSResolvers, ",")), time.Second*config.DNSTimeout)
+	if err != nil {
+		log.Fatalf("Failed to set up the DNS resolver. Err: %s", err)
+	}
+	log.Printf("Querying DNS seeders with nameservers: %s\n", resolver.Nameservers)
+
+	rpcClient = NewLitecoinRPCClient(config.LitecoinServer)
 	go BlockMonitor()
 
 	endpointErrorState = make(map[string]int)
 
 	go func() {
 		for {
-			var seeders []DNSSeeder
-			var sites []Site
-
-			for _, x := range config.DNSSeeders {
-				result := TestSeeders(x.Type, strings.Split(x.Hosts, ","))
-				seeders = append(seeders, result...)
-			}
-
-			for _, x := range config.Websites {
-				result := TestSites(x.Host, strings.Split(x.Subdomains, ","))
-				sites = append(sites, result...)
-			}
+			seeders := TestSeeders(config.DNSSeeders)
+			sites := TestSites(config.Websites)
 
 			if len(output.DNSSeeders) == 0 || len(output.Websites) == 0 {
 				log.Println("Populating output for the first time.")
 				output.Update(seeders, sites)
+				CheckCertificates(sites)
 				time.Sleep(time.Minute * config.CheckDelay)
 				continue
 			}
@@ -352,6 +475,7 @@ func main() {
 			output.Update(seeders, sites)
 			newOutput := output.Get()
 			CheckState(oldOutput, newOutput)
+			CheckCertificates(sites)
 			//	ready <- true
 			time.Sleep(time.Minute * config.CheckDelay)
 		}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	DefaultResolvConf = "/etc/resolv.conf"
+	DefaultDNSTimeout = time.Second * 5
+
+	DNSErrorTimeout = "timeout"
+
+	// DNSAuthoritativeLookup is the Server of the result recording a failed
+	// authoritative nameserver lookup, which has no nameserver of its own.
+	DNSAuthoritativeLookup = "authoritative lookup"
+)
+
+// Resolver queries seeders directly against a list of nameservers, plus the
+// seeder's authoritative nameservers, instead of the host's resolver.
+type Resolver struct {
+	Nameservers   []string
+	Timeout       time.Duration
+	Authoritative bool
+
+	// zones caches the authoritative nameservers found for a zone until
+	// the NS records' TTL runs out, so the service filter subdomains of a
+	// seeder don't repeat the lookup.
+	zonesMtx sync.Mutex
+	zones    map[string]authoritativeZone
+}
+
+type authoritativeZone struct {
+	servers []string
+	expires time.Time
+}
+
+func NewResolver(nameservers []string, timeout time.Duration) (*Resolver, error) {
+	if len(nameservers) == 0 {
+		cfg, err := dns.ClientConfigFromFile(DefaultResolvConf)
+		if err != nil {
+			return nil, err
+		}
+		nameservers = cfg.Servers
+	}
+	if timeout <= 0 {
+		timeout = DefaultDNSTimeout
+	}
+
+	r := &Resolver{Timeout: timeout, Authoritative: true, zones: make(map[string]authoritativeZone)}
+	for _, x := range nameservers {
+		r.Nameservers = append(r.Nameservers, NameserverAddress(x))
+	}
+	return r, nil
+}
+
+// NameserverAddress adds port 53 to a nameserver with no port.
+func NameserverAddress(server string) string {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		return net.JoinHostPort(server, "53")
+	}
+	return server
+}
+
+// DNSErrorString names a failed query: the response code, such as NXDOMAIN
+// or SERVFAIL, or timeout.
+func DNSErrorString(err error) string {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return DNSErrorTimeout
+	}
+	return err.Error()
+}
+
+// Exchange sends a single question to server, retrying over TCP when the UDP
+// answer is truncated. A response code other than NOERROR is returned as an
+// error named after the code.
+func (r *Resolver) Exchange(ctx context.Context, server, name string, qtype uint16) (*dns.Msg, time.Duration, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+
+	client := &dns.Client{Timeout: r.Timeout}
+	resp, rtt, err := client.ExchangeContext(ctx, msg, server)
+	if err == nil && resp.Truncated {
+		client.Net = "tcp"
+		resp, rtt, err = client.ExchangeContext(ctx, msg, server)
+	}
+	if err != nil {
+		return nil, rtt, err
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return resp, rtt, fmt.Errorf("%s", dns.RcodeToString[resp.Rcode])
+	}
+	return resp, rtt, nil
+}
+
+// Query looks up the A and AAAA records of host on server. A failed query
+// is recorded in QueryErrors under its type and the answers of the other
+// are kept. Error is only set when both fail.
+func (r *Resolver) Query(ctx context.Context, server, host string) ResolverResult {
+	result := ResolverResult{Server: server}
+	var elapsed time.Duration
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		tm := time.Now()
+		resp, _, err := r.Exchange(ctx, server, host, qtype)
+		elapsed += time.Since(tm)
+		if err != nil {
+			if result.QueryErrors == nil {
+				result.QueryErrors = make(map[string]string)
+			}
+			result.QueryErrors[dns.TypeToString[qtype]] = DNSErrorString(err)
+			continue
+		}
+
+		for _, rr := range resp.Answer {
+			var ip net.IP
+			switch record := rr.(type) {
+			case *dns.A:
+				ip = record.A
+			case *dns.AAAA:
+				ip = record.AAAA
+			default:
+				continue
+			}
+			result.Answers = append(result.Answers, ip.String())
+			if ttl := rr.Header().Ttl; result.TTL == 0 || ttl < result.TTL {
+				result.TTL = ttl
+			}
+		}
+	}
+	if len(result.QueryErrors) == 2 {
+		result.Error = result.QueryErrors[dns.TypeToString[dns.TypeA]]
+	}
+	result.RespTime = elapsed.String()
+	return result
+}
+
+// cachedZone returns the cached nameservers of the closest zone containing
+// host that hasn't expired.
+func (r *Resolver) cachedZone(labels []string) ([]string, bool) {
+	r.zonesMtx.Lock()
+	defer r.zonesMtx.Unlock()
+	for i := range labels {
+		zone, ok := r.zones[strings.Join(labels[i:], ".")]
+		if ok && time.Now().Before(zone.expires) {
+			return zone.servers, true
+		}
+	}
+	return nil, false
+}
+
+// AuthoritativeNameservers returns the addresses of the nameservers for the
+// zone containing host, walking up from host until a zone with NS records
+// is found. Nameservers already found for a zone containing host are reused
+// until their NS records expire.
+func (r *Resolver) AuthoritativeNameservers(ctx context.Context, host string) ([]string, error) {
+	if len(r.Nameservers) == 0 {
+		return nil, fmt.Errorf("no nameservers configured")
+	}
+
+	labels := dns.SplitDomainName(host)
+	if servers, ok := r.cachedZone(labels); ok {
+		return servers, nil
+	}
+
+	var names []string
+	var zone string
+	var ttl uint32
+	var err error
+	for i := 0; i < len(labels) && len(names) == 0; i++ {
+		zone = strings.Join(labels[i:], ".")
+		var resp *dns.Msg
+		for _, server := range r.Nameservers {
+			resp, _, err = r.Exchange(ctx, server, zone, dns.TypeNS)
+			if err == nil {
+				break
+			}
+		}
+		if err != nil {
+			continue
+		}
+		for _, rr := range append(resp.Answer, resp.Ns...) {
+			if ns, ok := rr.(*dns.NS); ok {
+				names = append(names, ns.Ns)
+				if len(names) == 1 || rr.Header().Ttl < ttl {
+					ttl = rr.Header().Ttl
+				}
+			}
+		}
+	}
+	if len(names) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no NS records found")
+		}
+		return nil, err
+	}
+
+	var servers []string
+	for _, name := range names {
+		for _, server := range r.Nameservers {
+			result := r.Query(ctx, server, name)
+			if result.Error != "" {
+				continue
+			}
+			for _, x := range result.Answers {
+				servers = append(servers, NameserverAddress(x))
+			}
+			break
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("unable to resolve the authoritative nameservers %s", strings.Join(names, ", "))
+	}
+
+	r.zonesMtx.Lock()
+	if r.zones == nil {
+		r.zones = make(map[string]authoritativeZone)
+	}
+	r.zones[zone] = authoritativeZone{servers: servers, expires: time.Now().Add(time.Duration(ttl) * time.Second)}
+	r.zonesMtx.Unlock()
+	return servers, nil
+}
+
+// LookupHost queries host on every nameserver and, when Authoritative is
+// set, its authoritative nameservers. It returns the unique addresses
+// answered by any of them with the per nameserver results. The error lists
+// the distinct failures when no nameserver answered.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, []ResolverResult, error) {
+	var results []ResolverResult
+	for _, server := range r.Nameservers {
+		results = append(results, r.Query(ctx, server, host))
+	}
+
+	if r.Authoritative {
+		servers, err := r.AuthoritativeNameservers(ctx, host)
+		if err != nil {
+			results = append(results, ResolverResult{Server: DNSAuthoritativeLookup, Authoritative: true, Error: DNSErrorString(err)})
+		}
+		for _, server := range servers {
+			result := r.Query(ctx, server, host)
+			result.Authoritative = true
+			results = append(results, result)
+		}
+	}
+
+	var addresses, errors []string
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result.Error != "" {
+			if !seen[result.Error] {
+				seen[result.Error] = true
+				errors = append(errors, result.Error)
+			}
+			continue
+		}
+		for _, x := range result.Answers {
+			if !seen[x] {
+				seen[x] = true
+				addresses = append(addresses, x)
+			}
+		}
+	}
+
+	if len(addresses) == 0 {
+		if len(errors) == 0 {
+			return nil, results, fmt.Errorf("no addresses returned")
+		}
+		return nil, results, fmt.Errorf("%s", strings.Join(errors, ", "))
+	}
+	return addresses, results, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// startDNSServer runs an in-process nameserver for the example zone and
+// returns its address and a count of the NS queries it answered.
+//
+//	seed.example        NS ns.example, A 1.2.3.4 5.6.7.8 9.9.9.9, AAAA 2a01::1
+//	v4.seed.example     A records, SERVFAIL for AAAA
+//	nx.example          NXDOMAIN
+//	fail.example        SERVFAIL
+//	hang.example and    no reply
+//	*.slow.example
+func startDNSServer(t *testing.T) (string, *int32) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nsQueries := new(int32)
+	answer := func(m *dns.Msg, record string) {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		m.Answer = append(m.Answer, rr)
+	}
+
+	mux := dns.NewServeMux()
+	mux.HandleFunc(".", func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		if q.Name == "hang.example." || strings.HasSuffix(q.Name, ".slow.example.") {
+			return
+		}
+		switch {
+		case q.Name == "nx.example.":
+			m.Rcode = dns.RcodeNameError
+		case q.Name == "fail.example.":
+			m.Rcode = dns.RcodeServerFailure
+		case q.Qtype == dns.TypeNS:
+			atomic.AddInt32(nsQueries, 1)
+			if q.Name == "seed.example." {
+				answer(m, "seed.example. 60 IN NS ns.example.")
+			}
+		case q.Name == "ns.example." && q.Qtype == dns.TypeA:
+			answer(m, "ns.example. 60 IN A 127.0.0.2")
+		case q.Name == "ns.example.":
+		case q.Qtype == dns.TypeA:
+			answer(m, q.Name+" 300 IN A 1.2.3.4")
+			answer(m, q.Name+" 300 IN A 5.6.7.8")
+			answer(m, q.Name+" 120 IN A 9.9.9.9")
+		case q.Qtype == dns.TypeAAAA && strings.HasPrefix(q.Name, "v4."):
+			m.Rcode = dns.RcodeServerFailure
+		case q.Qtype == dns.TypeAAAA:
+			answer(m, q.Name+" 200 IN AAAA 2a01::1")
+		}
+		w.WriteMsg(m)
+	})
+
+	srv := &dns.Server{PacketConn: pc, Handler: mux}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return pc.LocalAddr().String(), nsQueries
+}
+
+func TestResolverLookupHost(t *testing.T) {
+	addr, _ := startDNSServer(t)
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	r, err := NewResolver([]string{addr, silent.LocalAddr().String()}, time.Millisecond*200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Authoritative = false
+
+	addresses, results, err := r.LookupHost(context.Background(), "seed.example")
+	if err != nil || strings.Join(addresses, ",") != "1.2.3.4,5.6.7.8,9.9.9.9,2a01::1" {
+		t.Fatalf("LookupHost = %v, %v", addresses, err)
+	}
+	if results[0].Server != addr || results[0].TTL != 120 || results[0].Error != "" {
+		t.Fatalf("first nameserver result = %+v", results[0])
+	}
+	if results[1].Error != DNSErrorTimeout || results[1].QueryErrors["AAAA"] != DNSErrorTimeout {
+		t.Fatalf("silent nameserver result = %+v", results[1])
+	}
+
+	tests := []struct {
+		host string
+		err  string
+	}{
+		{"nx.example", "NXDOMAIN, timeout"},
+		{"fail.example", "SERVFAIL, timeout"},
+	}
+	for _, test := range tests {
+		if _, _, err := r.LookupHost(context.Background(), test.host); err == nil || err.Error() != test.err {
+			t.Errorf("LookupHost(%s) error = %v, want %s", test.host, err, test.err)
+		}
+	}
+}
+
+func TestResolverAuthoritativeFailure(t *testing.T) {
+	// other.test has no NS records, so only the configured nameserver
+	// answers and the failed lookup is labelled as such.
+	addr, _ := startDNSServer(t)
+	r, err := NewResolver([]string{addr}, time.Millisecond*200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addresses, results, err := r.LookupHost(context.Background(), "other.test")
+	if err != nil || len(addresses) != 4 || len(results) != 2 {
+		t.Fatalf("LookupHost = %v, %+v, %v", addresses, results, err)
+	}
+	if x := results[1]; x.Server != DNSAuthoritativeLookup || !x.Authoritative || x.Error != "no NS records found" {
+		t.Fatalf("authoritative result = %+v", x)
+	}
+}
+
+func TestResolverQueryKeepsAnswers(t *testing.T) {
+	// A failed AAAA query doesn't throw away the A answers.
+	addr, _ := startDNSServer(t)
+	r, err := NewResolver([]string{addr}, time.Millisecond*200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := r.Query(context.Background(), addr, "v4.seed.example")
+	if result.Error != "" || len(result.Answers) != 3 || result.QueryErrors["AAAA"] != "SERVFAIL" || result.QueryErrors["A"] != "" {
+		t.Fatalf("Query = %+v", result)
+	}
+}
+
+func TestResolverAuthoritativeCache(t *testing.T) {
+	addr, nsQueries := startDNSServer(t)
+	r, err := NewResolver([]string{addr}, time.Millisecond*200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, host := range []string{"seed.example", "x9.seed.example", "x5.seed.example", "seed.example"} {
+		servers, err := r.AuthoritativeNameservers(context.Background(), host)
+		if err != nil || len(servers) != 1 || servers[0] != "127.0.0.2:53" {
+			t.Fatalf("AuthoritativeNameservers(%s) = %v, %v", host, servers, err)
+		}
+	}
+	if n := atomic.LoadInt32(nsQueries); n != 1 {
+		t.Fatalf("sent %d NS queries, want 1", n)
+	}
+
+	// An expired zone is looked up again.
+	r.zones["seed.example"] = authoritativeZone{expires: time.Now().Add(-time.Second)}
+	if _, err := r.AuthoritativeNameservers(context.Background(), "x1.seed.example"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(nsQueries); n != 3 {
+		t.Fatalf("sent %d NS queries after expiry, want 3", n)
+	}
+}
+
+func TestNameserverAddress(t *testing.T) {
+	for server, want := range map[string]string{"8.8.8.8": "8.8.8.8:53", "::1": "[::1]:53", "1.1.1.1:5353": "1.1.1.1:5353"} {
+		if got := NameserverAddress(server); got != want {
+			t.Errorf("NameserverAddress(%s) = %s, want %s", server, got, want)
+		}
+	}
+}
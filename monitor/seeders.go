@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thrasher-/litecoin-tools/p2p"
+	"github.com/thrasher-/litecoin-tools/rawblock"
+	"github.com/thrasher-/litecoin-tools/services"
+)
+
+// DefaultServiceFilters are the x-prefixed subdomains queried when a seeder
+// config doesn't list its own. Litecoin Core asks seeders for x9 (NETWORK |
+// WITNESS) nodes.
+var DefaultServiceFilters = []string{"x1", "x5", "x9", "xd"}
+
+var bogonNetworks []*net.IPNet
+
+func init() {
+	for _, cidr := range []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"192.88.99.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"100::/64",
+		"2001::/32",
+		"2001:db8::/32",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		bogonNetworks = append(bogonNetworks, network)
+	}
+}
+
+// IsBogon reports whether ip is in a private, reserved or otherwise
+// unroutable range and so should never be handed out by a seeder.
+func IsBogon(ip net.IP) bool {
+	for _, network := range bogonNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditAddresses counts the IPv4, IPv6 and bogon addresses in result and
+// returns the routable ones.
+func AuditAddresses(seeder *DNSSeeder, result []string) []string {
+	var valid []string
+	for _, x := range result {
+		ip := net.ParseIP(x)
+		if ip == nil || IsBogon(ip) {
+			seeder.Bogons = append(seeder.Bogons, x)
+			continue
+		}
+		if ip.To4() != nil {
+			seeder.IPv4Count++
+		} else {
+			seeder.IPv6Count++
+		}
+		valid = append(valid, x)
+	}
+	return valid
+}
+
+// ParseServiceFilter returns the service mask of an x-prefixed subdomain
+// label such as x9.
+func ParseServiceFilter(prefix string) (uint64, error) {
+	if !strings.HasPrefix(prefix, "x") {
+		return 0, fmt.Errorf("service filter %q must start with x", prefix)
+	}
+	mask, err := strconv.ParseUint(prefix[1:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid service filter %q", prefix)
+	}
+	return mask, nil
+}
+
+// TestServiceFilters looks up each x-prefixed subdomain of host.
+func TestServiceFilters(ctx context.Context, host string, filters []string) []SeederServiceFilter {
+	var results []SeederServiceFilter
+	for _, x := range filters {
+		filter := SeederServiceFilter{Prefix: x}
+		mask, err := ParseServiceFilter(x)
+		if err != nil {
+			filter.Error = err.Error()
+			results = append(results, filter)
+			continue
+		}
+		filter.Services = services.Format(mask)
+
+		result, _, err := resolver.LookupHost(ctx, x+"."+host)
+		if err != nil {
+			filter.Error = err.Error()
+		} else {
+			filter.NodeCount = len(result)
+		}
+		results = append(results, filter)
+	}
+	return results
+}
+
+// TestHandshakes performs a version handshake with up to sample of the
+// addresses, chosen at random, to check they are reachable nodes on the
+// seeder's network.
+func TestHandshakes(ctx context.Context, network string, addresses []string, sample int, timeout time.Duration) (*SeederHandshakes, error) {
+	params, err := rawblock.GetParams(network)
+	if err != nil {
+		return nil, err
+	}
+
+	prober := p2p.NewProber(params)
+	if timeout > 0 {
+		prober.Timeout = timeout
+	}
+
+	if sample > len(addresses) {
+		sample = len(addresses)
+	}
+	handshakes := &SeederHandshakes{Sampled: sample, Peers: make([]SeederPeer, sample)}
+
+	var wg sync.WaitGroup
+	for i, x := range rand.Perm(len(addresses))[:sample] {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			peer := SeederPeer{Address: prober.Address(address)}
+			info, err := prober.ProbeContext(ctx, address)
+			if err != nil {
+				peer.Error = err.Error()
+				if e, ok := err.(*p2p.WrongNetworkError); ok {
+					peer.WrongNetwork = !e.Closed
+					peer.PossibleWrongNetwork = e.Closed
+				}
+			} else {
+				peer.UserAgent = info.UserAgent
+				peer.StartHeight = info.StartHeight
+				peer.Services = info.ServiceNames
+			}
+			handshakes.Peers[i] = peer
+		}(i, addresses[x])
+	}
+	wg.Wait()
+
+	for _, x := range handshakes.Peers {
+		if x.Error == "" {
+			handshakes.Reachable++
+		} else if x.WrongNetwork {
+			handshakes.WrongNetwork++
+		} else if x.PossibleWrongNetwork {
+			handshakes.PossibleWrongNetwork++
+		}
+	}
+	return handshakes, nil
+}
+
+// AuditSeeder checks the quality of the addresses a seeder returned, rather
+// than only whether it answered. Problems are recorded in seeder.Warnings
+// and don't change its status. The service filter lookups and handshakes
+// run under their own check timeout, and if that runs out the seeder keeps
+// its address counts and only gains a warning.
+func AuditSeeder(seeder *DNSSeeder, cfg ConfigDNSSeeders, result []string) {
+	valid := AuditAddresses(seeder, result)
+	if len(seeder.Bogons) > 0 {
+		seeder.Warnings = append(seeder.Warnings, fmt.Sprintf("%d private or bogon addresses returned", len(seeder.Bogons)))
+	}
+	if seeder.IPv4Count == 0 {
+		seeder.Warnings = append(seeder.Warnings, "no IPv4 addresses returned")
+	}
+	if seeder.IPv6Count == 0 {
+		seeder.Warnings = append(seeder.Warnings, "no IPv6 addresses returned")
+	}
+
+	audit := DNSSeeder{Name: seeder.Name}
+	if !RunWithTimeout(func(ctx context.Context) { AuditSeederNodes(ctx, &audit, cfg, valid) }) {
+		seeder.Warnings = append(seeder.Warnings, "service filter and handshake "+TimeoutError())
+		return
+	}
+	seeder.ServiceFilters = audit.ServiceFilters
+	seeder.Handshakes = audit.Handshakes
+	seeder.Warnings = append(seeder.Warnings, audit.Warnings...)
+}
+
+// AuditSeederNodes looks up the seeder's service filters and handshakes with
+// a sample of the valid addresses it returned.
+func AuditSeederNodes(ctx context.Context, seeder *DNSSeeder, cfg ConfigDNSSeeders, valid []string) {
+	filters := FilterEmptyStrings(strings.Split(cfg.ServiceFilters, ","))
+	if len(filters) == 0 {
+		filters = DefaultServiceFilters
+	}
+	seeder.ServiceFilters = TestServiceFilters(ctx, seeder.Name, filters)
+	for _, x := range seeder.ServiceFilters {
+		if x.Error != "" || x.NodeCount == 0 {
+			seeder.Warnings = append(seeder.Warnings, fmt.Sprintf("%s service filter returned no addresses", x.Prefix))
+		}
+	}
+
+	if cfg.HandshakeSample <= 0 || len(valid) == 0 {
+		return
+	}
+
+	handshakes, err := TestHandshakes(ctx, cfg.Type, valid, cfg.HandshakeSample, time.Second*cfg.HandshakeTimeout)
+	if err != nil {
+		seeder.Warnings = append(seeder.Warnings, err.Error())
+		return
+	}
+	seeder.Handshakes = handshakes
+	if handshakes.Reachable < handshakes.Sampled {
+		seeder.Warnings = append(seeder.Warnings, fmt.Sprintf("%d/%d sampled peers completed the handshake", handshakes.Reachable, handshakes.Sampled))
+	}
+	if handshakes.WrongNetwork > 0 {
+		seeder.Warnings = append(seeder.Warnings, fmt.Sprintf("%d sampled peers are on a different network", handshakes.WrongNetwork))
+	}
+	if handshakes.PossibleWrongNetwork > 0 {
+		seeder.Warnings = append(seeder.Warnings, fmt.Sprintf("%d sampled peers closed the connection without answering and may be on a different network", handshakes.PossibleWrongNetwork))
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsBogon(t *testing.T) {
+	tests := []struct {
+		ip    string
+		bogon bool
+	}{
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"0.1.2.3", true},
+		{"10.1.2.3", true},
+		{"100.64.0.1", true},
+		{"127.0.0.1", true},
+		{"169.254.1.1", true},
+		{"172.16.0.1", true},
+		{"172.32.0.1", false},
+		{"192.0.2.1", true},
+		{"192.88.99.1", true},
+		{"192.168.1.1", true},
+		{"198.18.0.1", true},
+		{"203.0.113.5", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"2a01:4f8::1", false},
+		{"2001:470::1", false},
+		{"::", true},
+		{"::1", true},
+		{"2001::1", true},
+		{"2001:0:4136:e378::1", true},
+		{"2001:db8::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"ff02::1", true},
+	}
+	for _, test := range tests {
+		if got := IsBogon(net.ParseIP(test.ip)); got != test.bogon {
+			t.Errorf("IsBogon(%s) = %v, want %v", test.ip, got, test.bogon)
+		}
+	}
+}
+
+func TestAuditAddresses(t *testing.T) {
+	var seeder DNSSeeder
+	valid := AuditAddresses(&seeder, []string{"8.8.8.8", "10.1.2.3", "192.88.99.1", "2a01:4f8::1", "2001::1", "not an ip", "1.1.1.1"})
+	if strings.Join(valid, ",") != "8.8.8.8,2a01:4f8::1,1.1.1.1" {
+		t.Fatalf("valid addresses = %v", valid)
+	}
+	if seeder.IPv4Count != 2 || seeder.IPv6Count != 1 || strings.Join(seeder.Bogons, ",") != "10.1.2.3,192.88.99.1,2001::1,not an ip" {
+		t.Fatalf("IPv4 %d, IPv6 %d, bogons %v", seeder.IPv4Count, seeder.IPv6Count, seeder.Bogons)
+	}
+}
+
+func TestParseServiceFilter(t *testing.T) {
+	tests := []struct {
+		prefix string
+		mask   uint64
+		fails  bool
+	}{
+		{"x1", 1, false},
+		{"x9", 9, false},
+		{"xd", 13, false},
+		{"x1000009", 0x1000009, false},
+		{"9", 0, true},
+		{"x", 0, true},
+		{"xzz", 0, true},
+	}
+	for _, test := range tests {
+		mask, err := ParseServiceFilter(test.prefix)
+		if test.fails != (err != nil) || mask != test.mask {
+			t.Errorf("ParseServiceFilter(%q) = %x, %v", test.prefix, mask, err)
+		}
+	}
+}
+
+func TestServiceFiltersInvalid(t *testing.T) {
+	// Invalid filters are reported without a lookup.
+	results := TestServiceFilters(context.Background(), "seed.example.com", []string{"9", "xzz"})
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for _, x := range results {
+		if x.Error == "" || x.NodeCount != 0 || x.Services != "" {
+			t.Errorf("filter %s = %+v, want an error", x.Prefix, x)
+		}
+	}
+}
+
+func TestSeederTimeouts(t *testing.T) {
+	addr, _ := startDNSServer(t)
+	r, err := NewResolver([]string{addr}, time.Second*3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Authoritative = false
+	// resolver is left set, as lookups that timed out are not waited for
+	// and may still be using it.
+	resolver = r
+	config.CheckTimeout = 1
+	defer func() { config = Config{} }()
+
+	cfg := ConfigDNSSeeders{Type: "mainnet", ServiceFilters: "x1"}
+	online := func(seeder DNSSeeder) bool {
+		return seeder.Status == "ONLINE" && seeder.Error == "" && seeder.NodeCount == 4 && seeder.IPv4Count == 3 &&
+			seeder.IPv6Count == 1 && len(seeder.Resolvers) == 1
+	}
+
+	seeder := TestSeeder(cfg, "seed.example")
+	if !online(seeder) || len(seeder.ServiceFilters) != 1 || seeder.ServiceFilters[0].NodeCount != 4 || len(seeder.Warnings) != 0 {
+		t.Errorf("TestSeeder(seed.example) = %+v", seeder)
+	}
+
+	// The service filter lookup outlasts the audit's timeout, but the seeder
+	// answered, so it stays online. The cancelled lookup may also return
+	// just as the timeout is noticed.
+	seeder = TestSeeder(cfg, "slow.example")
+	warnings := strings.Join(seeder.Warnings, ", ")
+	if !online(seeder) || (warnings != "service filter and handshake check timed out after 1s" && warnings != "x1 service filter returned no addresses") {
+		t.Errorf("TestSeeder(slow.example) = %+v", seeder)
+	}
+
+	seeder = TestSeeder(cfg, "hang.example")
+	if seeder.Status != "OFFLINE" || (seeder.Error != "check timed out after 1s" && seeder.Error != DNSErrorTimeout) {
+		t.Errorf("TestSeeder(hang.example) = %+v", seeder)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"sync"
+	"time"
 )
 
 // Main types
@@ -14,19 +15,78 @@ type BlockInfo struct {
 }
 
 type DNSSeeder struct {
-	Name      string `json:"name"`
-	Type      string `json:"type"`
+	Name           string                `json:"name"`
+	Type           string                `json:"type"`
+	NodeCount      int                   `json:"node_count"`
+	IPv4Count      int                   `json:"ipv4_count"`
+	IPv6Count      int                   `json:"ipv6_count"`
+	Bogons         []string              `json:"bogons,omitempty"`
+	ServiceFilters []SeederServiceFilter `json:"service_filters,omitempty"`
+	Handshakes     *SeederHandshakes     `json:"handshakes,omitempty"`
+	Resolvers      []ResolverResult      `json:"resolvers,omitempty"`
+	Warnings       []string              `json:"warnings,omitempty"`
+	Status         string                `json:"status"`
+	Error          string                `json:"error"`
+}
+
+type ResolverResult struct {
+	Server        string            `json:"server"`
+	Authoritative bool              `json:"authoritative"`
+	Answers       []string          `json:"answers"`
+	TTL           uint32            `json:"ttl"`
+	RespTime      string            `json:"response_time"`
+	QueryErrors   map[string]string `json:"query_errors,omitempty"`
+	Error         string            `json:"error"`
+}
+
+type SeederServiceFilter struct {
+	Prefix    string `json:"prefix"`
+	Services  string `json:"services"`
 	NodeCount int    `json:"node_count"`
-	Status    string `json:"status"`
-	Error     string `json:"error"`
+	Error     string `json:"error,omitempty"`
+}
+
+type SeederHandshakes struct {
+	Sampled              int          `json:"sampled"`
+	Reachable            int          `json:"reachable"`
+	WrongNetwork         int          `json:"wrong_network"`
+	PossibleWrongNetwork int          `json:"possible_wrong_network"`
+	Peers                []SeederPeer `json:"peers"`
+}
+
+type SeederPeer struct {
+	Address              string `json:"address"`
+	UserAgent            string `json:"user_agent,omitempty"`
+	StartHeight          int32  `json:"start_height,omitempty"`
+	Services             string `json:"services,omitempty"`
+	WrongNetwork         bool   `json:"wrong_network,omitempty"`
+	PossibleWrongNetwork bool   `json:"possible_wrong_network,omitempty"`
+	Error                string `json:"error,omitempty"`
 }
 
 type SiteProtocol struct {
-	Status      string `json:"status"`
-	HTTPCode    int    `json:"http_code"`
-	ContentSize int    `json:"content_size"`
-	RespTime    string `json:"response_time"`
-	Error       string `json:"error"`
+	Status          string           `json:"status"`
+	Method          string           `json:"method,omitempty"`
+	HTTPCode        int              `json:"http_code"`
+	ContentSize     int              `json:"content_size"`
+	RespTime        string           `json:"response_time"`
+	Attempts        int              `json:"attempts,omitempty"`
+	Redirects       int              `json:"redirects,omitempty"`
+	FinalURL        string           `json:"final_url,omitempty"`
+	Certificate     *CertificateInfo `json:"certificate,omitempty"`
+	DaysUntilExpiry *int             `json:"days_until_expiry,omitempty"`
+	Error           string           `json:"error"`
+}
+
+type CertificateInfo struct {
+	Subject    string   `json:"subject"`
+	Issuer     string   `json:"issuer"`
+	SANs       []string `json:"sans"`
+	NotBefore  int64    `json:"not_before"`
+	NotAfter   int64    `json:"not_after"`
+	ChainValid bool     `json:"chain_valid"`
+	ChainError string   `json:"chain_error,omitempty"`
+	TLSVersion string   `json:"tls_version"`
 }
 
 type Site struct {
@@ -49,8 +109,11 @@ type Output struct {
 
 // Config types
 type ConfigDNSSeeders struct {
-	Hosts string `json:"host"`
-	Type  string `json:"type"`
+	Hosts            string        `json:"host"`
+	Type             string        `json:"type"`
+	ServiceFilters   string        `json:"service_filters,omitempty"`
+	HandshakeSample  int           `json:"handshake_sample,omitempty"`
+	HandshakeTimeout time.Duration `json:"handshake_timeout,omitempty"`
 }
 
 type ConfigWebsites struct {
@@ -60,14 +123,25 @@ type ConfigWebsites struct {
 		Subdomains  string `json:"subdomains"`
 		StringCheck string `json:"string_check"`
 	} `json:"content_match"`
-	Exclusions string `json:"exclusions,omitempty"`
+	Exclusions     string            `json:"exclusions,omitempty"`
+	Method         string            `json:"method,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	UserAgent      string            `json:"user_agent,omitempty"`
+	Timeout        time.Duration     `json:"timeout,omitempty"`
+	Retries        int               `json:"retries,omitempty"`
+	RetryBackoff   time.Duration     `json:"retry_backoff,omitempty"`
+	ExpectedStatus string            `json:"expected_status,omitempty"`
+	Redirects      string            `json:"redirects,omitempty"`
+	MaxRedirects   int               `json:"max_redirects,omitempty"`
 }
 
 type ConfigLitecoinServer struct {
-	RPCPort     int    `json:"rpc_port"`
-	RPCServer   string `json:"rpc_server"`
-	RPCUsername string `json:"rpc_username"`
-	RPCPassword string `json:"rpc_password"`
+	RPCPort       int           `json:"rpc_port"`
+	RPCServer     string        `json:"rpc_server"`
+	RPCUsername   string        `json:"rpc_username"`
+	RPCPassword   string        `json:"rpc_password"`
+	RPCCookieFile string        `json:"rpc_cookie_file,omitempty"`
+	RPCTimeout    time.Duration `json:"rpc_timeout,omitempty"`
 }
 
 type ConfigSlack struct {
@@ -1,56 +1,61 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
-	"strings"
-)
+	"log"
+	"os"
 
-const (
-	NODE_NONE    uint64 = 0
-	NODE_NETWORK uint64 = (1 << 0)
-	NODE_GETUTXO uint64 = (1 << 1)
-	NODE_BLOOM   uint64 = (1 << 2)
-	NODE_WITNESS uint64 = (1 << 3)
-	NODE_XTHIN   uint64 = (1 << 4)
+	"github.com/thrasher-/litecoin-tools/services"
 )
 
-func FormatServices(mask uint64) string {
-	services := []string{}
-	for i := 0; i < 8; i++ {
-		var check uint64 = 1 << uint64(i)
-		if mask&check != 0 {
-			switch check {
-			case NODE_NETWORK:
-				services = append(services, "NETWORK")
-				break
-			case NODE_GETUTXO:
-				services = append(services, "GETUTXO")
-				break
-			case NODE_BLOOM:
-				services = append(services, "BLOOM")
-				break
-			case NODE_WITNESS:
-				services = append(services, "WITNESS")
-				break
-			case NODE_XTHIN:
-				services = append(services, "XTHIN")
-				break
-			default:
-				services = append(services, fmt.Sprintf("UNKNOWN[%v]", check))
-			}
+// FormatServices returns the service flag names and their mask for a value
+// given as a number or as names.
+func FormatServices(value string) (string, error) {
+	mask, err := services.Parse(value)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("0x%016x %s", mask, services.Format(mask)), nil
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s: [mask ...]\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Each mask is a decimal or 0x prefixed hex number, or flag names such as NETWORK|WITNESS. Prefix the hex getpeerinfo shows with 0x. Masks are read one per line from stdin when none are given.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	failed := false
+	check := func(value string) {
+		result, err := FormatServices(value)
+		if err != nil {
+			log.Printf("Unable to parse %q. Err: %s", value, err)
+			failed = true
+			return
 		}
+		fmt.Println(result)
 	}
-	if len(services) > 0 {
-		return strings.Join(services, " & ")
+
+	if flag.NArg() > 0 {
+		for _, arg := range flag.Args() {
+			check(arg)
+		}
 	} else {
-		return "None"
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				check(line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatal(err)
+		}
 	}
-}
 
-func main() {
-	fmt.Println(FormatServices(NODE_NETWORK))
-	fmt.Println(FormatServices(13))
-	nServices := NODE_NETWORK | NODE_GETUTXO | NODE_BLOOM | NODE_WITNESS | NODE_XTHIN
-	fmt.Println(FormatServices(nServices))
-	fmt.Println(FormatServices(0))
+	if failed {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thrasher-/litecoin-tools/p2p"
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+type Result struct {
+	Address string        `json:"address"`
+	Peer    *p2p.PeerInfo `json:"peer,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// ReadAddresses returns the addresses in path, one per line. Blank lines and
+// lines starting with # are skipped. A path of - reads stdin.
+func ReadAddresses(path string) ([]string, error) {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		f, err = os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+	}
+
+	var addresses []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addresses = append(addresses, line)
+	}
+	return addresses, scanner.Err()
+}
+
+// ProbeAll probes the addresses with up to workers handshakes at a time and
+// returns the results in the order of addresses.
+func ProbeAll(prober *p2p.Prober, addresses []string, workers int) []Result {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]Result, len(addresses))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				address := prober.Address(addresses[i])
+				peer, err := prober.Probe(address)
+				results[i] = Result{Address: address, Peer: peer}
+				if err != nil {
+					results[i].Error = err.Error()
+				}
+			}
+		}()
+	}
+
+	for i := range addresses {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func main() {
+	var network, file, format, userAgent string
+	var timeout time.Duration
+	var workers, startHeight int
+	flag.StringVar(&network, "network", "main", "Network whose magic and default port are used: main, test or regtest.")
+	flag.StringVar(&file, "file", "", "File of host:port addresses to probe, one per line, or - for stdin.")
+	flag.DurationVar(&timeout, "timeout", p2p.DefaultTimeout, "Time allowed to connect and complete the handshake with each peer.")
+	flag.IntVar(&workers, "workers", 8, "Number of peers to probe at once.")
+	flag.StringVar(&format, "format", "text", "Output format of the results: text or json.")
+	flag.StringVar(&userAgent, "useragent", p2p.DefaultUserAgent, "User agent to send in our version message.")
+	flag.IntVar(&startHeight, "startheight", 0, "Start height to send in our version message.")
+	flag.Parse()
+
+	if format != "text" && format != "json" {
+		log.Fatalf("Unknown output format %q.", format)
+	}
+
+	params, err := rawblock.GetParams(network)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	addresses := flag.Args()
+	if file != "" {
+		list, err := ReadAddresses(file)
+		if err != nil {
+			log.Fatalf("Failed to read %s. Err: %s", file, err)
+		}
+		addresses = append(addresses, list...)
+	}
+	if len(addresses) == 0 {
+		log.Fatal("No addresses to probe, pass them as arguments or with -file.")
+	}
+
+	prober := p2p.NewProber(params)
+	prober.Timeout = timeout
+	prober.UserAgent = userAgent
+	prober.StartHeight = int32(startHeight)
+
+	results := ProbeAll(prober, addresses, workers)
+
+	if format == "json" {
+		data, err := json.MarshalIndent(results, "", " ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	for _, r := range results {
+		if r.Error != "" {
+			log.Printf("%s: handshake failed. Err: %s", r.Address, r.Error)
+			continue
+		}
+		p := r.Peer
+		log.Printf("%s: protocol %d, user agent %q, start height %d, services %016x %s, relay %v, %s",
+			r.Address, p.ProtocolVersion, p.UserAgent, p.StartHeight, p.Services, p.ServiceNames, p.Relay, p.Latency.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,95 @@
+package p2p
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+const (
+	HEADER_SIZE      = 24
+	COMMAND_SIZE     = 12
+	MAX_PAYLOAD_SIZE = 32 * 1024 * 1024
+)
+
+// WrongNetworkError is returned when a message starts with another
+// network's magic bytes. Litecoin Core doesn't answer a version message with
+// the wrong magic, it drops the connection, so a peer that closes the
+// connection before sending anything during a handshake is reported with
+// Closed set. That is only a possible mismatch, as a full or banning node
+// closes the connection the same way.
+type WrongNetworkError struct {
+	Magic  [4]byte
+	Closed bool
+}
+
+func (e *WrongNetworkError) Error() string {
+	if e.Closed {
+		return "peer closed the connection without answering, it may be on a different network"
+	}
+	return fmt.Sprintf("peer is on a different network, magic %x", e.Magic[:])
+}
+
+type Message struct {
+	Command string
+	Payload []byte
+}
+
+func checksum(payload []byte) [4]byte {
+	hash := rawblock.DoubleSHA256(payload)
+	var sum [4]byte
+	copy(sum[:], hash[:4])
+	return sum
+}
+
+// WriteMessage frames payload with the network magic, command, length and
+// checksum and writes it to w.
+func WriteMessage(w io.Writer, magic [4]byte, command string, payload []byte) error {
+	if len(command) > COMMAND_SIZE {
+		return fmt.Errorf("command %q is too long", command)
+	}
+
+	header := make([]byte, HEADER_SIZE)
+	copy(header[0:4], magic[:])
+	copy(header[4:16], command)
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(payload)))
+	sum := checksum(payload)
+	copy(header[20:24], sum[:])
+
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+// ReadMessage reads the next message from r. A message with the wrong magic
+// returns a *WrongNetworkError.
+func ReadMessage(r io.Reader, magic [4]byte) (*Message, error) {
+	header := make([]byte, HEADER_SIZE)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(header[0:4], magic[:]) {
+		e := &WrongNetworkError{}
+		copy(e.Magic[:], header[0:4])
+		return nil, e
+	}
+
+	command := string(bytes.TrimRight(header[4:16], "\x00"))
+	length := binary.LittleEndian.Uint32(header[16:20])
+	if length > MAX_PAYLOAD_SIZE {
+		return nil, fmt.Errorf("%s message payload of %d bytes is too large", command, length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	if sum := checksum(payload); !bytes.Equal(header[20:24], sum[:]) {
+		return nil, fmt.Errorf("%s message checksum mismatch", command)
+	}
+	return &Message{Command: command, Payload: payload}, nil
+}
@@ -0,0 +1,175 @@
+package p2p
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/thrasher-/litecoin-tools/rawblock"
+	"github.com/thrasher-/litecoin-tools/services"
+)
+
+const (
+	DefaultUserAgent = "/litecoin-tools:0.1/"
+	DefaultTimeout   = time.Second * 10
+)
+
+// PeerInfo is what a peer reported about itself during the handshake.
+type PeerInfo struct {
+	Address         string        `json:"address"`
+	ProtocolVersion int32         `json:"protocol_version"`
+	Services        uint64        `json:"services"`
+	ServiceNames    string        `json:"service_names"`
+	UserAgent       string        `json:"user_agent"`
+	StartHeight     int32         `json:"start_height"`
+	Relay           bool          `json:"relay"`
+	Timestamp       int64         `json:"timestamp"`
+	Latency         time.Duration `json:"latency_ns"`
+}
+
+// Prober performs version handshakes with peers of one network.
+type Prober struct {
+	Params      rawblock.Params
+	Timeout     time.Duration
+	UserAgent   string
+	StartHeight int32
+}
+
+func NewProber(params rawblock.Params) *Prober {
+	return &Prober{Params: params, Timeout: DefaultTimeout, UserAgent: DefaultUserAgent}
+}
+
+// Address adds the network's default port to address when it has none.
+func (p *Prober) Address(address string) string {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(address, p.Params.DefaultPort)
+	}
+	return address
+}
+
+// Probe connects to address and performs the version/verack handshake. The
+// whole exchange must complete within Timeout.
+func (p *Prober) Probe(address string) (*PeerInfo, error) {
+	return p.ProbeContext(context.Background(), address)
+}
+
+// ProbeContext is Probe, giving up early if ctx is done first.
+func (p *Prober) ProbeContext(ctx context.Context, address string) (*PeerInfo, error) {
+	address = p.Address(address)
+	startTime := time.Now()
+	dialer := &net.Dialer{Timeout: p.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if p.Timeout > 0 && (!ok || startTime.Add(p.Timeout).Before(deadline)) {
+		deadline, ok = startTime.Add(p.Timeout), true
+	}
+	if ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	v, err := p.Handshake(conn, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PeerInfo{
+		Address:         address,
+		ProtocolVersion: v.ProtocolVersion,
+		Services:        v.Services,
+		ServiceNames:    services.Format(v.Services),
+		UserAgent:       v.UserAgent,
+		StartHeight:     v.StartHeight,
+		Relay:           v.Relay,
+		Timestamp:       v.Timestamp,
+		Latency:         time.Since(startTime),
+	}, nil
+}
+
+// Handshake sends our version on conn and waits for the peer's version and
+// verack, answering its version with a verack. Other messages sent during
+// the handshake, such as wtxidrelay and sendaddrv2, are ignored. A peer that
+// closes the connection before sending any message returns a
+// *WrongNetworkError with Closed set.
+func (p *Prober) Handshake(conn net.Conn, address string) (*Version, error) {
+	var nonce [8]byte
+	rand.Read(nonce[:])
+
+	ours := &Version{
+		ProtocolVersion: PROTOCOL_VERSION,
+		Timestamp:       time.Now().Unix(),
+		AddrRecv:        NewNetAddress(address),
+		Nonce:           binary.LittleEndian.Uint64(nonce[:]),
+		UserAgent:       p.UserAgent,
+		StartHeight:     p.StartHeight,
+	}
+	err := WriteMessage(conn, p.Params.Magic, "version", ours.Serialize())
+	if err != nil {
+		return nil, err
+	}
+
+	var theirs *Version
+	verack := false
+	for theirs == nil || !verack {
+		msg, err := ReadMessage(conn, p.Params.Magic)
+		if closed(err) {
+			if theirs == nil {
+				return nil, &WrongNetworkError{Closed: true}
+			}
+			return nil, fmt.Errorf("peer closed the connection during the handshake")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg.Command {
+		case "version":
+			if theirs != nil {
+				return nil, fmt.Errorf("peer sent a second version message")
+			}
+			theirs, err = ParseVersion(msg.Payload)
+			if err != nil {
+				return nil, err
+			}
+			if theirs.Nonce == ours.Nonce {
+				return nil, fmt.Errorf("connected to ourselves")
+			}
+			err = WriteMessage(conn, p.Params.Magic, "verack", nil)
+			if err != nil {
+				return nil, err
+			}
+		case "verack":
+			if theirs == nil {
+				return nil, fmt.Errorf("peer sent verack before version")
+			}
+			verack = true
+		case "reject":
+			return nil, fmt.Errorf("peer rejected the connection")
+		}
+	}
+	return theirs, nil
+}
+
+// closed reports whether err means the peer closed or reset the connection.
+func closed(err error) bool {
+	return err == io.EOF || err == io.ErrUnexpectedEOF || errors.Is(err, syscall.ECONNRESET)
+}
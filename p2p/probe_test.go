@@ -0,0 +1,133 @@
+package p2p
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+var testVersion = &Version{
+	ProtocolVersion: 70017,
+	Services:        0x1000409,
+	UserAgent:       "/LitecoinCore:0.21.3/",
+	StartHeight:     2700000,
+	Relay:           true,
+	Nonce:           42,
+}
+
+// fakePeer handshakes with the prober on the other end of a net.Pipe. It
+// reads our version and then runs answer, while draining anything else we
+// send so writes on the synchronous pipe don't block.
+func fakePeer(t *testing.T, answer func(conn net.Conn)) net.Conn {
+	ours, theirs := net.Pipe()
+	t.Cleanup(func() { ours.Close() })
+	go func() {
+		defer theirs.Close()
+		msg, err := ReadMessage(theirs, rawblock.MainNetParams.Magic)
+		if err != nil || msg.Command != "version" {
+			t.Errorf("fake peer expected a version message, got %v %v", msg, err)
+			return
+		}
+		if _, err := ParseVersion(msg.Payload); err != nil {
+			t.Errorf("fake peer couldn't parse our version: %v", err)
+			return
+		}
+		go io.Copy(ioutil.Discard, theirs)
+		answer(theirs)
+	}()
+	return ours
+}
+
+func TestHandshake(t *testing.T) {
+	conn := fakePeer(t, func(conn net.Conn) {
+		magic := rawblock.MainNetParams.Magic
+		WriteMessage(conn, magic, "version", testVersion.Serialize())
+		WriteMessage(conn, magic, "wtxidrelay", nil)
+		WriteMessage(conn, magic, "sendaddrv2", nil)
+		WriteMessage(conn, magic, "verack", nil)
+	})
+
+	p := NewProber(rawblock.MainNetParams)
+	v, err := p.Handshake(conn, "127.0.0.1:9333")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.ProtocolVersion != testVersion.ProtocolVersion || v.Services != testVersion.Services || v.UserAgent != testVersion.UserAgent || v.StartHeight != testVersion.StartHeight || !v.Relay {
+		t.Fatalf("Handshake = %+v, want %+v", v, testVersion)
+	}
+}
+
+func TestHandshakeWrongMagic(t *testing.T) {
+	conn := fakePeer(t, func(conn net.Conn) {
+		WriteMessage(conn, rawblock.TestNetParams.Magic, "version", testVersion.Serialize())
+	})
+
+	_, err := NewProber(rawblock.MainNetParams).Handshake(conn, "127.0.0.1:9333")
+	e, ok := err.(*WrongNetworkError)
+	if !ok || e.Closed || e.Magic != rawblock.TestNetParams.Magic {
+		t.Fatalf("Handshake error = %v, want a wrong network error with the testnet magic", err)
+	}
+}
+
+func TestHandshakeClosed(t *testing.T) {
+	// Litecoin Core drops the connection on a magic mismatch without
+	// answering.
+	conn := fakePeer(t, func(conn net.Conn) {})
+
+	_, err := NewProber(rawblock.MainNetParams).Handshake(conn, "127.0.0.1:9333")
+	if e, ok := err.(*WrongNetworkError); !ok || !e.Closed {
+		t.Fatalf("Handshake error = %v, want a possible wrong network error", err)
+	}
+
+	// Closing after the peer's version is not a network mismatch.
+	conn = fakePeer(t, func(conn net.Conn) {
+		WriteMessage(conn, rawblock.MainNetParams.Magic, "version", testVersion.Serialize())
+	})
+	_, err = NewProber(rawblock.MainNetParams).Handshake(conn, "127.0.0.1:9333")
+	if _, ok := err.(*WrongNetworkError); ok || err == nil {
+		t.Fatalf("Handshake error = %v, want a closed connection error", err)
+	}
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	conn := fakePeer(t, func(conn net.Conn) { <-done })
+	conn.SetDeadline(time.Now().Add(50 * time.Millisecond))
+
+	_, err := NewProber(rawblock.MainNetParams).Handshake(conn, "127.0.0.1:9333")
+	if e, ok := err.(net.Error); !ok || !e.Timeout() {
+		t.Fatalf("Handshake error = %v, want a timeout", err)
+	}
+}
+
+func TestProbeContextCancel(t *testing.T) {
+	// A peer that accepts the connection and never answers.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			io.Copy(ioutil.Discard, conn)
+			conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	tm := time.Now()
+	if _, err := NewProber(rawblock.MainNetParams).ProbeContext(ctx, l.Addr().String()); err == nil {
+		t.Fatal("ProbeContext succeeded against a silent peer")
+	}
+	if elapsed := time.Since(tm); elapsed > time.Second {
+		t.Fatalf("ProbeContext returned %s after the context was cancelled", elapsed)
+	}
+}
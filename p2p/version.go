@@ -0,0 +1,179 @@
+package p2p
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+)
+
+const (
+	// PROTOCOL_VERSION is the version sent to peers, Litecoin Core 0.21's
+	// MWEB protocol version.
+	PROTOCOL_VERSION = 70017
+
+	MAX_USER_AGENT_LENGTH = 256
+)
+
+var errTruncated = errors.New("truncated version message")
+
+// NetAddress is an address as carried in a version message, without the
+// time field.
+type NetAddress struct {
+	Services uint64
+	IP       net.IP
+	Port     uint16
+}
+
+// Version is the payload of a version message.
+type Version struct {
+	ProtocolVersion int32
+	Services        uint64
+	Timestamp       int64
+	AddrRecv        NetAddress
+	AddrFrom        NetAddress
+	Nonce           uint64
+	UserAgent       string
+	StartHeight     int32
+	Relay           bool
+}
+
+// NewNetAddress returns the address of a host:port pair. Hostnames are not
+// resolved and leave the IP unset.
+func NewNetAddress(address string) NetAddress {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return NetAddress{}
+	}
+	n, _ := strconv.ParseUint(port, 10, 16)
+	return NetAddress{IP: net.ParseIP(host), Port: uint16(n)}
+}
+
+func writeNetAddress(buf *bytes.Buffer, a NetAddress) {
+	binary.Write(buf, binary.LittleEndian, a.Services)
+	ip := a.IP.To16()
+	if ip == nil {
+		ip = net.IPv6zero
+	}
+	buf.Write(ip)
+	binary.Write(buf, binary.BigEndian, a.Port)
+}
+
+func writeVarInt(buf *bytes.Buffer, n uint64) {
+	switch {
+	case n < 0xfd:
+		buf.WriteByte(byte(n))
+	case n <= 0xffff:
+		buf.WriteByte(0xfd)
+		binary.Write(buf, binary.LittleEndian, uint16(n))
+	case n <= 0xffffffff:
+		buf.WriteByte(0xfe)
+		binary.Write(buf, binary.LittleEndian, uint32(n))
+	default:
+		buf.WriteByte(0xff)
+		binary.Write(buf, binary.LittleEndian, n)
+	}
+}
+
+// Serialize encodes the version message payload.
+func (v *Version) Serialize() []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, v.ProtocolVersion)
+	binary.Write(&buf, binary.LittleEndian, v.Services)
+	binary.Write(&buf, binary.LittleEndian, v.Timestamp)
+	writeNetAddress(&buf, v.AddrRecv)
+	writeNetAddress(&buf, v.AddrFrom)
+	binary.Write(&buf, binary.LittleEndian, v.Nonce)
+	writeVarInt(&buf, uint64(len(v.UserAgent)))
+	buf.WriteString(v.UserAgent)
+	binary.Write(&buf, binary.LittleEndian, v.StartHeight)
+	if v.Relay {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+func readNetAddress(r io.Reader) (NetAddress, error) {
+	var a NetAddress
+	if err := binary.Read(r, binary.LittleEndian, &a.Services); err != nil {
+		return a, err
+	}
+	ip := make([]byte, 16)
+	if _, err := io.ReadFull(r, ip); err != nil {
+		return a, err
+	}
+	a.IP = net.IP(ip)
+	err := binary.Read(r, binary.BigEndian, &a.Port)
+	return a, err
+}
+
+func readVarInt(r *bytes.Reader) (uint64, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch prefix {
+	case 0xfd:
+		var n uint16
+		err = binary.Read(r, binary.LittleEndian, &n)
+		return uint64(n), err
+	case 0xfe:
+		var n uint32
+		err = binary.Read(r, binary.LittleEndian, &n)
+		return uint64(n), err
+	case 0xff:
+		var n uint64
+		err = binary.Read(r, binary.LittleEndian, &n)
+		return n, err
+	}
+	return uint64(prefix), nil
+}
+
+// ParseVersion decodes a version message payload. Fields that old peers
+// leave off the end keep their zero values, except Relay which defaults to
+// true as in litecoind.
+func ParseVersion(payload []byte) (*Version, error) {
+	r := bytes.NewReader(payload)
+	v := &Version{Relay: true}
+
+	for _, field := range []interface{}{&v.ProtocolVersion, &v.Services, &v.Timestamp} {
+		if err := binary.Read(r, binary.LittleEndian, field); err != nil {
+			return nil, errTruncated
+		}
+	}
+
+	var err error
+	if v.AddrRecv, err = readNetAddress(r); err != nil {
+		return nil, errTruncated
+	}
+	if r.Len() == 0 {
+		return v, nil
+	}
+
+	if v.AddrFrom, err = readNetAddress(r); err != nil {
+		return nil, errTruncated
+	}
+	if err = binary.Read(r, binary.LittleEndian, &v.Nonce); err != nil {
+		return nil, errTruncated
+	}
+
+	n, err := readVarInt(r)
+	if err != nil || n > MAX_USER_AGENT_LENGTH || n > uint64(r.Len()) {
+		return nil, errors.New("invalid user agent in version message")
+	}
+	agent := make([]byte, n)
+	r.Read(agent)
+	v.UserAgent = string(agent)
+
+	if err = binary.Read(r, binary.LittleEndian, &v.StartHeight); err != nil {
+		return nil, errTruncated
+	}
+	if relay, err := r.ReadByte(); err == nil {
+		v.Relay = relay != 0
+	}
+	return v, nil
+}
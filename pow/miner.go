@@ -0,0 +1,116 @@
+package pow
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"code.google.com/p/go.crypto/scrypt"
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+// Miner grinds header nonces across several goroutines. It is meant for
+// solving regtest blocks and benchmarking scrypt, not for mainnet mining.
+type Miner struct {
+	Workers int
+
+	hashes uint64
+}
+
+func NewMiner(workers int) *Miner {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Miner{Workers: workers}
+}
+
+// Hashes returns the number of scrypt hashes computed so far. It is safe to
+// call while Mine or Bench is running.
+func (m *Miner) Hashes() uint64 {
+	return atomic.LoadUint64(&m.hashes)
+}
+
+// Mine searches the nonce space of h, starting from 0, for a hash that meets
+// the target in its nBits. found is false if every nonce was tried or stop
+// was closed first.
+func (m *Miner) Mine(h rawblock.Header, stop <-chan struct{}) (solved rawblock.Header, found bool, err error) {
+	target, err := DecodeCompact(h.Bits)
+	if err != nil {
+		return h, false, err
+	}
+	nonce, found, err := m.grind(h, target, stop)
+	h.Nonce = nonce
+	return h, found, err
+}
+
+// Bench hashes h with every nonce in turn until stop is closed, without
+// checking the results against a target.
+func (m *Miner) Bench(h rawblock.Header, stop <-chan struct{}) error {
+	_, _, err := m.grind(h, nil, stop)
+	return err
+}
+
+func (m *Miner) grind(h rawblock.Header, target *big.Int, stop <-chan struct{}) (uint32, bool, error) {
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		done     = make(chan struct{})
+		result   uint32
+		found    bool
+		firstErr error
+	)
+	finish := func(nonce uint32, ok bool, err error) {
+		once.Do(func() {
+			result, found, firstErr = nonce, ok, err
+			close(done)
+		})
+	}
+
+	// Workers can be set directly, so clamp it here as well as in NewMiner.
+	workers := m.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			data := h.Serialize()
+			var hash [32]byte
+			for n := uint64(w); n <= math.MaxUint32; n += uint64(workers) {
+				select {
+				case <-done:
+					return
+				case <-stop:
+					return
+				default:
+				}
+
+				binary.LittleEndian.PutUint32(data[76:], uint32(n))
+				dk, err := scrypt.Key(data, data, SCRYPT_N, SCRYPT_R, SCRYPT_P, SCRYPT_KEYLEN)
+				if err != nil {
+					finish(0, false, err)
+					return
+				}
+				atomic.AddUint64(&m.hashes, 1)
+
+				if target == nil {
+					continue
+				}
+				copy(hash[:], dk)
+				if HashToBig(hash).Cmp(target) <= 0 {
+					finish(uint32(n), true, nil)
+					return
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	finish(h.Nonce, false, nil)
+	return result, found, firstErr
+}
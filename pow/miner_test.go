@@ -0,0 +1,26 @@
+package pow
+
+import (
+	"testing"
+
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+func TestMine(t *testing.T) {
+	h := rawblock.Header{Version: 1, Time: 1296688602, Bits: RegTestConsensus.PowLimitBits()}
+
+	// A zero value Miner, or one with a negative worker count, still uses
+	// one worker.
+	for _, m := range []*Miner{NewMiner(4), {}, {Workers: -2}} {
+		solved, found, err := m.Mine(h, nil)
+		if err != nil || !found {
+			t.Fatalf("Mine with %d workers = %v, %v", m.Workers, found, err)
+		}
+		if result, _ := CheckHeader(solved); !result.Valid {
+			t.Fatalf("Mine with %d workers returned nonce %d, which doesn't meet the target", m.Workers, solved.Nonce)
+		}
+		if m.Hashes() == 0 {
+			t.Fatalf("Mine with %d workers counted no hashes", m.Workers)
+		}
+	}
+}
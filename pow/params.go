@@ -0,0 +1,72 @@
+package pow
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ConsensusParams holds the difficulty rules of a network.
+type ConsensusParams struct {
+	Name                     string
+	PowLimit                 *big.Int
+	TargetTimespan           int64
+	TargetSpacing            int64
+	AllowMinDifficultyBlocks bool
+	NoRetargeting            bool
+}
+
+func hexToBig(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex number " + s)
+	}
+	return n
+}
+
+var (
+	MainNetConsensus = ConsensusParams{
+		Name:           "main",
+		PowLimit:       hexToBig("00000fffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+		TargetTimespan: 3.5 * 24 * 60 * 60,
+		TargetSpacing:  150,
+	}
+
+	TestNetConsensus = ConsensusParams{
+		Name:                     "test",
+		PowLimit:                 hexToBig("00000fffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+		TargetTimespan:           3.5 * 24 * 60 * 60,
+		TargetSpacing:            150,
+		AllowMinDifficultyBlocks: true,
+	}
+
+	RegTestConsensus = ConsensusParams{
+		Name:                     "regtest",
+		PowLimit:                 hexToBig("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+		TargetTimespan:           3.5 * 24 * 60 * 60,
+		TargetSpacing:            150,
+		AllowMinDifficultyBlocks: true,
+		NoRetargeting:            true,
+	}
+)
+
+func GetConsensusParams(name string) (*ConsensusParams, error) {
+	switch name {
+	case "main", "mainnet":
+		return &MainNetConsensus, nil
+	case "test", "testnet":
+		return &TestNetConsensus, nil
+	case "regtest":
+		return &RegTestConsensus, nil
+	}
+	return nil, fmt.Errorf("unknown network %q", name)
+}
+
+// Interval is the number of blocks between difficulty retargets, 2016 on
+// every Litecoin network.
+func (p *ConsensusParams) Interval() int64 {
+	return p.TargetTimespan / p.TargetSpacing
+}
+
+func (p *ConsensusParams) PowLimitBits() uint32 {
+	return EncodeCompact(p.PowLimit)
+}
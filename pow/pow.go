@@ -0,0 +1,127 @@
+package pow
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"code.google.com/p/go.crypto/scrypt"
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+// Litecoin's proof of work is scrypt over the 80 byte header, using the
+// header as both password and salt.
+const (
+	SCRYPT_N      = 1024
+	SCRYPT_R      = 1
+	SCRYPT_P      = 1
+	SCRYPT_KEYLEN = 32
+)
+
+var (
+	ErrNegativeTarget = errors.New("target is negative")
+	ErrTargetOverflow = errors.New("target overflows 256 bits")
+	ErrZeroTarget     = errors.New("target is zero")
+)
+
+// ScryptHash returns the proof of work hash of a serialised header in the
+// same byte order as a block hash, so it can be printed with
+// rawblock.HashString and compared with HashToBig.
+func ScryptHash(header []byte) ([32]byte, error) {
+	var hash [32]byte
+	if len(header) != rawblock.HeaderSize {
+		return hash, fmt.Errorf("header must be %d bytes, got %d", rawblock.HeaderSize, len(header))
+	}
+
+	dk, err := scrypt.Key(header, header, SCRYPT_N, SCRYPT_R, SCRYPT_P, SCRYPT_KEYLEN)
+	if err != nil {
+		return hash, err
+	}
+	copy(hash[:], dk)
+	return hash, nil
+}
+
+// HashToBig interprets a hash as the little endian 256 bit number that is
+// compared against the target.
+func HashToBig(hash [32]byte) *big.Int {
+	var reversed [32]byte
+	for i := range hash {
+		reversed[i] = hash[31-i]
+	}
+	return new(big.Int).SetBytes(reversed[:])
+}
+
+// DecodeCompact expands nBits into a target and rejects the encodings
+// litecoind treats as invalid: negative, zero and overflowing targets.
+func DecodeCompact(bits uint32) (*big.Int, error) {
+	size := bits >> 24
+	word := bits & 0x007fffff
+
+	if word != 0 && bits&0x00800000 != 0 {
+		return nil, ErrNegativeTarget
+	}
+	if word != 0 && (size > 34 || word > 0xff && size > 33 || word > 0xffff && size > 32) {
+		return nil, ErrTargetOverflow
+	}
+
+	target := rawblock.CompactToBig(bits)
+	if target.Sign() == 0 {
+		return nil, ErrZeroTarget
+	}
+	return target, nil
+}
+
+// EncodeCompact converts a target into nBits, rounding down to the 23 bit
+// mantissa the same way as litecoind's GetCompact.
+func EncodeCompact(target *big.Int) uint32 {
+	if target.Sign() <= 0 {
+		return 0
+	}
+
+	size := uint32((target.BitLen() + 7) / 8)
+	var word uint32
+	if size <= 3 {
+		word = uint32(target.Int64()) << (8 * (3 - size))
+	} else {
+		word = uint32(new(big.Int).Rsh(target, uint(8*(size-3))).Int64())
+	}
+
+	// The sign bit is set, so move a byte into the exponent.
+	if word&0x00800000 != 0 {
+		word >>= 8
+		size++
+	}
+	return size<<24 | word
+}
+
+// TargetToString formats a target like a block hash, as 64 hex digits.
+func TargetToString(target *big.Int) string {
+	return fmt.Sprintf("%064x", target)
+}
+
+type Result struct {
+	Hash    [32]byte
+	PoWHash [32]byte
+	Target  *big.Int
+	Valid   bool
+}
+
+// CheckHeader computes the scrypt hash of a header and reports whether it
+// meets the target encoded in its nBits. An error is only returned when the
+// nBits are not a valid target.
+func CheckHeader(h rawblock.Header) (Result, error) {
+	result := Result{Hash: h.Hash()}
+
+	target, err := DecodeCompact(h.Bits)
+	if err != nil {
+		return result, fmt.Errorf("invalid nBits %08x: %s", h.Bits, err)
+	}
+	result.Target = target
+
+	result.PoWHash, err = ScryptHash(h.Serialize())
+	if err != nil {
+		return result, err
+	}
+	result.Valid = HashToBig(result.PoWHash).Cmp(target) <= 0
+	return result, nil
+}
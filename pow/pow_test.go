@@ -0,0 +1,115 @@
+package pow
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+// Mainnet block 29255, the vector from scrypt_pow's README.
+const header29255 = "01000000f615f7ce3b4fc6b8f61e8f89aedb1d0852507650533a9e3b10b9bbcc30639f279fcaa86746e1ef52d3edb3c4ad8259920d509bd073605c9bf1d59983752a6b06b817bb4ea78e011d012d59d4"
+
+func TestCheckHeader29255(t *testing.T) {
+	data, err := hex.DecodeString(header29255)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := rawblock.ParseHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := CheckHeader(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid {
+		t.Fatal("block 29255 doesn't meet its target")
+	}
+	if hash := rawblock.HashString(result.Hash); hash != "adf6e2e56df692822f5e064a8b6404a05d67cccd64bc90f57f65b46805e9a54b" {
+		t.Fatalf("Hash = %s", hash)
+	}
+	if hash := rawblock.HashString(result.PoWHash); hash != "0000000110c8357966576df46f3b802ca897deb7ad18b12f1c24ecff6386ebd9" {
+		t.Fatalf("PoWHash = %s", hash)
+	}
+	if target := TargetToString(result.Target); target != "000000018ea70000000000000000000000000000000000000000000000000000" {
+		t.Fatalf("Target = %s", target)
+	}
+
+	// A target just below the scrypt hash rejects the same header.
+	h.Bits = 0x1d010000
+	result, err = CheckHeader(h)
+	if err != nil || result.Valid {
+		t.Fatalf("CheckHeader with bits %08x = %v, %v, want invalid", h.Bits, result.Valid, err)
+	}
+
+	h.Bits = 0x04923456
+	if _, err := CheckHeader(h); err == nil {
+		t.Fatalf("expected an error for negative bits %08x", h.Bits)
+	}
+}
+
+func TestDecodeCompact(t *testing.T) {
+	tests := []struct {
+		bits   uint32
+		target string
+		err    error
+	}{
+		{0x1d018ea7, "18ea70000000000000000000000000000000000000000000000000000", nil},
+		{0x1e0ffff0, "ffff0000000000000000000000000000000000000000000000000000000", nil},
+		{0x01123456, "12", nil},
+		{0x02123456, "1234", nil},
+		{0x03123456, "123456", nil},
+		{0x04123456, "12345600", nil},
+		{0x2100ffff, "ffff000000000000000000000000000000000000000000000000000000000000", nil},
+		{0x22000001, "100000000000000000000000000000000000000000000000000000000000000", nil},
+		{0x00000000, "", ErrZeroTarget},
+		{0x01003456, "", ErrZeroTarget},
+		{0x04923456, "", ErrNegativeTarget},
+		{0x20ffff00, "", ErrNegativeTarget},
+		{0x21010000, "", ErrTargetOverflow},
+		{0x22010000, "", ErrTargetOverflow},
+		{0xff123456, "", ErrTargetOverflow},
+	}
+	for _, test := range tests {
+		target, err := DecodeCompact(test.bits)
+		if err != test.err {
+			t.Errorf("DecodeCompact(%08x) error = %v, want %v", test.bits, err, test.err)
+			continue
+		}
+		if err == nil && target.Text(16) != test.target {
+			t.Errorf("DecodeCompact(%08x) = %s, want %s", test.bits, target.Text(16), test.target)
+		}
+	}
+}
+
+func TestEncodeCompact(t *testing.T) {
+	for _, bits := range []uint32{0x1d018ea7, 0x1e0ffff0, 0x207fffff, 0x1b0404cb, 0x05009234, 0x04123456, 0x01120000} {
+		target, err := DecodeCompact(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := EncodeCompact(target); got != bits {
+			t.Errorf("EncodeCompact(DecodeCompact(%08x)) = %08x", bits, got)
+		}
+	}
+
+	// A mantissa with the sign bit set moves up a byte, and precision
+	// below the 23 bit mantissa is rounded away.
+	tests := []struct {
+		target *big.Int
+		bits   uint32
+	}{
+		{big.NewInt(0x80), 0x02008000},
+		{big.NewInt(0x12), 0x01120000},
+		{big.NewInt(0x12345678), 0x04123456},
+		{MainNetConsensus.PowLimit, 0x1e0fffff},
+	}
+	for _, test := range tests {
+		if got := EncodeCompact(test.target); got != test.bits {
+			t.Errorf("EncodeCompact(%x) = %08x, want %08x", test.target, got, test.bits)
+		}
+	}
+}
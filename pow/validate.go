@@ -0,0 +1,223 @@
+package pow
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+// HeaderError describes the first header that failed validation.
+type HeaderError struct {
+	Height int64
+	Hash   string
+	Reason string
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("block %d %s: %s", e.Height, e.Hash, e.Reason)
+}
+
+// CalculateNextWorkRequired returns the nBits for the block after a retarget
+// period that took actualTimespan seconds and ended with lastBits.
+func CalculateNextWorkRequired(params *ConsensusParams, lastBits uint32, actualTimespan int64) uint32 {
+	if actualTimespan < params.TargetTimespan/4 {
+		actualTimespan = params.TargetTimespan / 4
+	}
+	if actualTimespan > params.TargetTimespan*4 {
+		actualTimespan = params.TargetTimespan * 4
+	}
+
+	target := rawblock.CompactToBig(lastBits)
+
+	// Litecoin: the intermediate product can overflow 256 bits when the
+	// target is close to the limit, so shift it down one bit first.
+	shift := target.BitLen() > params.PowLimit.BitLen()-1
+	if shift {
+		target.Rsh(target, 1)
+	}
+	target.Mul(target, big.NewInt(actualTimespan))
+	target.Div(target, big.NewInt(params.TargetTimespan))
+	if shift {
+		target.Lsh(target, 1)
+	}
+
+	if target.Cmp(params.PowLimit) > 0 {
+		target.Set(params.PowLimit)
+	}
+	return EncodeCompact(target)
+}
+
+// Validator checks a contiguous run of headers: the prev-hash links, the
+// scrypt proof of work against nBits and the nBits themselves against the
+// retarget rules. It keeps the last retarget interval of headers as context.
+type Validator struct {
+	Params  *ConsensusParams
+	Workers int
+
+	// Unchecked counts headers whose nBits could not be verified because
+	// the headers the retarget depends on precede the first one added.
+	Unchecked int64
+
+	base    int64
+	headers []rawblock.Header
+}
+
+// NewValidator returns a validator whose first header, added with Prime or
+// Validate, is at height.
+func NewValidator(params *ConsensusParams, height int64) *Validator {
+	return &Validator{Params: params, Workers: 1, base: height}
+}
+
+// Height returns the height of the last header added.
+func (v *Validator) Height() int64 {
+	return v.base + int64(len(v.headers)) - 1
+}
+
+func (v *Validator) header(height int64) (rawblock.Header, bool) {
+	i := height - v.base
+	if i < 0 || i >= int64(len(v.headers)) {
+		return rawblock.Header{}, false
+	}
+	return v.headers[i], true
+}
+
+func (v *Validator) add(h rawblock.Header) {
+	v.headers = append(v.headers, h)
+
+	keep := int(v.Params.Interval()) + 1
+	if len(v.headers) > 2*keep {
+		drop := len(v.headers) - keep
+		v.headers = append(v.headers[:0], v.headers[drop:]...)
+		v.base += int64(drop)
+	}
+}
+
+// Prime adds a header as trusted context without validating it.
+func (v *Validator) Prime(h rawblock.Header) {
+	v.add(h)
+}
+
+// NextWorkRequired returns the nBits the block after the last added header
+// must have, following litecoind's GetNextWorkRequired. ok is false when the
+// headers needed to work it out are not available.
+func (v *Validator) NextWorkRequired(newTime uint32) (bits uint32, ok bool) {
+	params := v.Params
+	lastHeight := v.Height()
+	last, ok := v.header(lastHeight)
+	if !ok {
+		return 0, false
+	}
+
+	interval := params.Interval()
+	powLimitBits := params.PowLimitBits()
+
+	if (lastHeight+1)%interval != 0 {
+		if !params.AllowMinDifficultyBlocks {
+			return last.Bits, true
+		}
+
+		// Testnet allows a minimum difficulty block once no block has been
+		// found for twice the target spacing.
+		if int64(newTime) > int64(last.Time)+params.TargetSpacing*2 {
+			return powLimitBits, true
+		}
+
+		// Otherwise the last block that was not a minimum difficulty one
+		// sets the difficulty.
+		height, h := lastHeight, last
+		for height > 0 && height%interval != 0 && h.Bits == powLimitBits {
+			height--
+			if h, ok = v.header(height); !ok {
+				return 0, false
+			}
+		}
+		return h.Bits, true
+	}
+
+	if params.NoRetargeting {
+		return last.Bits, true
+	}
+
+	// Litecoin: go back the full interval rather than interval-1 blocks, so
+	// the timespan covers every block and cannot be time-warped. Only the
+	// first retarget after the genesis block lacks the extra block.
+	back := interval
+	if lastHeight+1 == interval {
+		back = interval - 1
+	}
+	first, ok := v.header(lastHeight - back)
+	if !ok {
+		return 0, false
+	}
+	return CalculateNextWorkRequired(params, last.Bits, int64(last.Time)-int64(first.Time)), true
+}
+
+func (v *Validator) checkPoW(h rawblock.Header) string {
+	result, err := CheckHeader(h)
+	if err != nil {
+		return err.Error()
+	}
+	if result.Target.Cmp(v.Params.PowLimit) > 0 {
+		return fmt.Sprintf("target %s is above the proof of work limit", TargetToString(result.Target))
+	}
+	if !result.Valid {
+		return fmt.Sprintf("scrypt hash %s is above the target %s", rawblock.HashString(result.PoWHash), TargetToString(result.Target))
+	}
+	return ""
+}
+
+// Validate checks headers, which must follow the last header added, and
+// returns a *HeaderError for the first invalid one. The scrypt hashes are
+// computed by Workers goroutines. Valid headers are added as context.
+func (v *Validator) Validate(headers []rawblock.Header) error {
+	powErrors := make([]string, len(headers))
+
+	workers := v.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(headers); i += workers {
+				powErrors[i] = v.checkPoW(headers[i])
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for i, h := range headers {
+		height := v.Height() + 1
+		fail := func(format string, args ...interface{}) error {
+			hash := h.Hash()
+			return &HeaderError{Height: height, Hash: rawblock.HashString(hash), Reason: fmt.Sprintf(format, args...)}
+		}
+
+		if powErrors[i] != "" {
+			return fail("%s", powErrors[i])
+		}
+
+		if prev, ok := v.header(height - 1); ok {
+			if prevHash := prev.Hash(); h.PrevBlock != prevHash {
+				return fail("previous block hash %s does not match block %d %s", rawblock.HashString(h.PrevBlock), height-1, rawblock.HashString(prevHash))
+			}
+		}
+
+		if height > 0 {
+			expected, ok := v.NextWorkRequired(h.Time)
+			switch {
+			case !ok:
+				v.Unchecked++
+			case h.Bits != expected:
+				return fail("nBits %08x do not match the expected %08x", h.Bits, expected)
+			}
+		}
+
+		v.add(h)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package pow
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+// testParams retargets every 4 blocks with a limit low enough that solve
+// finds a nonce in a few hundred hashes.
+var testParams = ConsensusParams{
+	Name:           "test",
+	PowLimit:       hexToBig("00ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+	TargetTimespan: 600,
+	TargetSpacing:  150,
+}
+
+func solve(h *rawblock.Header) {
+	for {
+		if result, _ := CheckHeader(*h); result.Valid {
+			return
+		}
+		h.Nonce++
+	}
+}
+
+// buildChain mines a chain with the given timestamps that follows the
+// retarget rules of testParams.
+func buildChain(t *testing.T, times []uint32) []rawblock.Header {
+	v := NewValidator(&testParams, 0)
+	var headers []rawblock.Header
+	var prev [32]byte
+	for i, tm := range times {
+		h := rawblock.Header{Version: 1, PrevBlock: prev, Time: tm, Bits: 0x2000ffff}
+		if i > 0 {
+			bits, ok := v.NextWorkRequired(tm)
+			if !ok {
+				t.Fatalf("no retarget context at height %d", i)
+			}
+			h.Bits = bits
+		}
+		solve(&h)
+		v.Prime(h)
+		headers = append(headers, h)
+		prev = h.Hash()
+	}
+	return headers
+}
+
+func TestCalculateNextWorkRequired(t *testing.T) {
+	params := &MainNetConsensus
+	const bits = 0x1c0ae493
+	target := rawblock.CompactToBig(bits)
+
+	if got := CalculateNextWorkRequired(params, bits, params.TargetTimespan); got != bits {
+		t.Fatalf("on schedule: %08x, want %08x", got, uint32(bits))
+	}
+
+	half := EncodeCompact(target.Rsh(target, 1))
+	if got := CalculateNextWorkRequired(params, bits, params.TargetTimespan/2); got != half {
+		t.Fatalf("twice as fast: %08x, want %08x", got, half)
+	}
+
+	// The timespan is clamped to a factor of 4 either way.
+	quarter := CalculateNextWorkRequired(params, bits, params.TargetTimespan/4)
+	if got := CalculateNextWorkRequired(params, bits, 1); got != quarter {
+		t.Fatalf("clamped fast: %08x, want %08x", got, quarter)
+	}
+	four := CalculateNextWorkRequired(params, bits, params.TargetTimespan*4)
+	if got := CalculateNextWorkRequired(params, bits, params.TargetTimespan*100); got != four {
+		t.Fatalf("clamped slow: %08x, want %08x", got, four)
+	}
+
+	// The target never goes above the proof of work limit.
+	if got := CalculateNextWorkRequired(params, params.PowLimitBits(), params.TargetTimespan*4); got != params.PowLimitBits() {
+		t.Fatalf("at the limit: %08x, want %08x", got, params.PowLimitBits())
+	}
+}
+
+// TestRetargetLookback checks the 2016 block mainnet retarget. The first
+// retarget measures the 2015 blocks after the genesis block, as Bitcoin
+// does, and every later one measures the full interval starting from the
+// last block of the previous period.
+func TestRetargetLookback(t *testing.T) {
+	params := &MainNetConsensus
+	interval := params.Interval()
+	if interval != 2016 {
+		t.Fatalf("Interval = %d, want 2016", interval)
+	}
+
+	const bits = 0x1d018ea7
+	times := make([]int64, 2*interval)
+	for i := range times {
+		times[i] = 1317972665 + int64(i)*params.TargetSpacing
+		// A long gap between the two periods, so measuring from the first
+		// block of the second period gives a different answer.
+		if int64(i) >= interval {
+			times[i] += 20000
+		}
+	}
+
+	v := NewValidator(params, 0)
+	for i := int64(0); i < interval; i++ {
+		v.Prime(rawblock.Header{Time: uint32(times[i]), Bits: bits})
+		if i == interval-1 {
+			break
+		}
+		if got, ok := v.NextWorkRequired(uint32(times[i+1])); !ok || got != bits {
+			t.Fatalf("NextWorkRequired at height %d = %08x, %v, want %08x", i+1, got, ok, uint32(bits))
+		}
+	}
+
+	first := CalculateNextWorkRequired(params, bits, times[interval-1]-times[0])
+	if got, ok := v.NextWorkRequired(uint32(times[interval])); !ok || got != first {
+		t.Fatalf("first retarget = %08x, %v, want %08x", got, ok, first)
+	}
+
+	for i := interval; i < 2*interval; i++ {
+		v.Prime(rawblock.Header{Time: uint32(times[i]), Bits: first})
+	}
+	second := CalculateNextWorkRequired(params, first, times[2*interval-1]-times[interval-1])
+	short := CalculateNextWorkRequired(params, first, times[2*interval-1]-times[interval])
+	if second == short {
+		t.Fatal("test timestamps don't distinguish the lookbacks")
+	}
+	if got, ok := v.NextWorkRequired(uint32(times[2*interval-1] + 150)); !ok || got != second {
+		t.Fatalf("second retarget = %08x, %v, want %08x (interval-1 lookback gives %08x)", got, ok, second, short)
+	}
+
+	// Without the header at the start of the lookback the retarget can't
+	// be worked out.
+	v = NewValidator(params, interval)
+	for i := interval; i < 2*interval; i++ {
+		v.Prime(rawblock.Header{Time: uint32(times[i]), Bits: first})
+	}
+	if _, ok := v.NextWorkRequired(uint32(times[2*interval-1] + 150)); ok {
+		t.Fatal("expected no retarget without the previous period's last header")
+	}
+}
+
+func TestValidator(t *testing.T) {
+	// Heights 0 to 9 with retargets at 4 and 8.
+	times := []uint32{1000, 1100, 1200, 1300, 1400, 1500, 1600, 1700, 1800, 1900}
+	headers := buildChain(t, times)
+
+	v := NewValidator(&testParams, 0)
+	v.Workers = 3
+	if err := v.Validate(headers); err != nil {
+		t.Fatal(err)
+	}
+	if v.Unchecked != 0 || v.Height() != 9 {
+		t.Fatalf("Unchecked = %d, Height = %d, want 0, 9", v.Unchecked, v.Height())
+	}
+
+	// Starting mid chain, the retarget at 8 depends on headers before the
+	// range, as does the check of the first header.
+	v = NewValidator(&testParams, 5)
+	if err := v.Validate(headers[5:]); err != nil {
+		t.Fatal(err)
+	}
+	if v.Unchecked != 2 {
+		t.Fatalf("Unchecked = %d, want 2", v.Unchecked)
+	}
+
+	tests := []struct {
+		height  int64
+		reason  string
+		corrupt func(h *rawblock.Header)
+	}{
+		{6, "nBits", func(h *rawblock.Header) { h.Bits = headers[4].Bits + 1; solve(h) }},
+		{3, "previous", func(h *rawblock.Header) { h.PrevBlock[0] ^= 1; solve(h) }},
+		{2, "scrypt", func(h *rawblock.Header) {
+			for {
+				h.Nonce++
+				if result, _ := CheckHeader(*h); !result.Valid {
+					return
+				}
+			}
+		}},
+	}
+	for _, test := range tests {
+		bad := append([]rawblock.Header(nil), headers...)
+		test.corrupt(&bad[test.height])
+		err := NewValidator(&testParams, 0).Validate(bad)
+		e, ok := err.(*HeaderError)
+		if !ok || e.Height != test.height || !strings.Contains(e.Reason, test.reason) {
+			t.Errorf("Validate error = %v, want a %s error at height %d", err, test.reason, test.height)
+		}
+	}
+}
+
+func TestMinDifficulty(t *testing.T) {
+	params := testParams
+	params.AllowMinDifficultyBlocks = true
+	limit := params.PowLimitBits()
+
+	v := NewValidator(&params, 0)
+	v.Prime(rawblock.Header{Time: 1000, Bits: 0x1f00ffff})
+	v.Prime(rawblock.Header{Time: 1100, Bits: 0x1f00ffff})
+
+	// More than twice the target spacing allows a minimum difficulty block.
+	if bits, _ := v.NextWorkRequired(1401); bits != limit {
+		t.Fatalf("NextWorkRequired after a gap = %08x, want %08x", bits, limit)
+	}
+
+	// The next one goes back to the last real difficulty.
+	v.Prime(rawblock.Header{Time: 1401, Bits: limit})
+	if bits, _ := v.NextWorkRequired(1450); bits != 0x1f00ffff {
+		t.Fatalf("NextWorkRequired = %08x, want 1f00ffff", bits)
+	}
+}
@@ -0,0 +1,168 @@
+package rawblock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	XOR_KEY_FILE = "xor.dat"
+)
+
+// WriteBlkRecord writes a block using the blk*.dat framing used by
+// litecoind: the network magic, the little endian block length and the
+// serialised block.
+func WriteBlkRecord(w io.Writer, magic [4]byte, block []byte) error {
+	var prefix [8]byte
+	copy(prefix[:], magic[:])
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(len(block)))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(block)
+	return err
+}
+
+// BlocksDir returns the blocks directory for a node data directory. A path
+// that already points at the blocks directory is returned unchanged.
+func BlocksDir(datadir string) string {
+	dir := filepath.Join(datadir, "blocks")
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return dir
+	}
+	return datadir
+}
+
+// ReadXorKey reads the key newer nodes use to obfuscate block files. It
+// returns nil when the directory has no key file or the key is all zeros.
+func ReadXorKey(blocksDir string) ([]byte, error) {
+	key, err := ioutil.ReadFile(filepath.Join(blocksDir, XOR_KEY_FILE))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 || bytes.Count(key, []byte{0}) == len(key) {
+		return nil, nil
+	}
+	return key, nil
+}
+
+// Xor applies the obfuscation key to data read from offset in a block file.
+func Xor(data []byte, key []byte, offset int64) {
+	if len(key) == 0 {
+		return
+	}
+	k := int(offset % int64(len(key)))
+	for i := range data {
+		data[i] ^= key[k]
+		k++
+		if k == len(key) {
+			k = 0
+		}
+	}
+}
+
+// BlkFiles lists the blk*.dat files in blocksDir in file number order.
+func BlkFiles(blocksDir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(blocksDir, "blk*.dat"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// xorReader deobfuscates a block file as it is read.
+type xorReader struct {
+	r      io.Reader
+	key    []byte
+	offset int64
+}
+
+func (x *xorReader) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	Xor(p[:n], x.key, x.offset)
+	x.offset += int64(n)
+	return n, err
+}
+
+// ReadBlkFile calls fn with the offset and contents of each block stored in a
+// blk*.dat file. The file is streamed, so only one block is held in memory at
+// a time and fn may keep the slice it is given. Data between records, such
+// as the zero padding the node preallocates, is skipped by searching for the
+// next magic, and a truncated record at the end of the file is ignored.
+func ReadBlkFile(path string, magic [4]byte, xorKey []byte, fn func(offset int64, block []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReaderSize(&xorReader{r: f, key: xorKey}, 1<<20)
+
+	var pos int64
+	matched := 0
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		pos++
+
+		switch {
+		case c == magic[matched]:
+			matched++
+		case c == magic[0]:
+			matched = 1
+			continue
+		default:
+			matched = 0
+			continue
+		}
+		if matched < len(magic) {
+			continue
+		}
+		matched = 0
+
+		var prefix [4]byte
+		if _, err := io.ReadFull(r, prefix[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		pos += 4
+
+		size := int64(binary.LittleEndian.Uint32(prefix[:]))
+		if size < HeaderSize || size > info.Size()-pos {
+			continue
+		}
+
+		block := make([]byte, size)
+		if _, err := io.ReadFull(r, block); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		if err := fn(pos, block); err != nil {
+			return err
+		}
+		pos += size
+	}
+}
@@ -0,0 +1,135 @@
+package rawblock
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testBlock returns a copy of the genesis block with its header changed so
+// each call with a different nonce gives a distinct block.
+func testBlock(t *testing.T, prev [32]byte, nonce uint32, bits uint32) ([]byte, [32]byte) {
+	genesis := readHexFixture(t, "genesis.hex")
+	h, err := ParseHeader(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.PrevBlock = prev
+	h.Nonce = nonce
+	h.Bits = bits
+	return append(h.Serialize(), genesis[HeaderSize:]...), h.Hash()
+}
+
+type blkRecord struct {
+	offset int64
+	block  []byte
+}
+
+func readBlkRecords(t *testing.T, data []byte, key []byte) []blkRecord {
+	path := filepath.Join(t.TempDir(), "blk00000.dat")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []blkRecord
+	err := ReadBlkFile(path, MainNetParams.Magic, key, func(offset int64, block []byte) error {
+		records = append(records, blkRecord{offset, block})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestReadBlkFile(t *testing.T) {
+	a, _ := testBlock(t, [32]byte{}, 1, 0x207fffff)
+	b, _ := testBlock(t, [32]byte{}, 2, 0x207fffff)
+
+	// Padding before and between the records, a stray magic with a length
+	// too small for a block, and zero padding at the end like a
+	// preallocated file.
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 5))
+	WriteBlkRecord(&buf, MainNetParams.Magic, a)
+	buf.Write(MainNetParams.Magic[:])
+	buf.Write([]byte{1, 0, 0, 0})
+	buf.Write(make([]byte, 3))
+	WriteBlkRecord(&buf, MainNetParams.Magic, b)
+	buf.Write(make([]byte, 64))
+
+	records := readBlkRecords(t, buf.Bytes(), nil)
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+	if records[0].offset != 13 || !bytes.Equal(records[0].block, a) {
+		t.Fatalf("record 0 at offset %d, want 13", records[0].offset)
+	}
+	if want := int64(13 + len(a) + 8 + 3 + 8); records[1].offset != want || !bytes.Equal(records[1].block, b) {
+		t.Fatalf("record 1 at offset %d, want %d", records[1].offset, want)
+	}
+}
+
+func TestReadBlkFileXor(t *testing.T) {
+	a, _ := testBlock(t, [32]byte{}, 1, 0x207fffff)
+	b, _ := testBlock(t, [32]byte{}, 2, 0x207fffff)
+
+	var buf bytes.Buffer
+	WriteBlkRecord(&buf, MainNetParams.Magic, a)
+	buf.Write(make([]byte, 7))
+	WriteBlkRecord(&buf, MainNetParams.Magic, b)
+	data := buf.Bytes()
+
+	key := []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+	Xor(data, key, 0)
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, XOR_KEY_FILE), key, 0644); err != nil {
+		t.Fatal(err)
+	}
+	readKey, err := ReadXorKey(dir)
+	if err != nil || !bytes.Equal(readKey, key) {
+		t.Fatalf("ReadXorKey = %x, %v", readKey, err)
+	}
+
+	records := readBlkRecords(t, data, readKey)
+	if len(records) != 2 || !bytes.Equal(records[0].block, a) || !bytes.Equal(records[1].block, b) {
+		t.Fatalf("read %d records, want both blocks deobfuscated", len(records))
+	}
+	if records := readBlkRecords(t, data, nil); len(records) != 0 {
+		t.Fatalf("read %d records without the key, want 0", len(records))
+	}
+
+	// An all zero key, as written by nodes with obfuscation disabled, is
+	// the same as no key.
+	ioutil.WriteFile(filepath.Join(dir, XOR_KEY_FILE), make([]byte, 8), 0644)
+	if key, err := ReadXorKey(dir); key != nil || err != nil {
+		t.Fatalf("ReadXorKey = %x, %v, want nil", key, err)
+	}
+	os.Remove(filepath.Join(dir, XOR_KEY_FILE))
+	if key, err := ReadXorKey(dir); key != nil || err != nil {
+		t.Fatalf("ReadXorKey = %x, %v, want nil", key, err)
+	}
+}
+
+func TestReadBlkFileTruncated(t *testing.T) {
+	a, _ := testBlock(t, [32]byte{}, 1, 0x207fffff)
+	b, _ := testBlock(t, [32]byte{}, 2, 0x207fffff)
+
+	var buf bytes.Buffer
+	WriteBlkRecord(&buf, MainNetParams.Magic, a)
+	WriteBlkRecord(&buf, MainNetParams.Magic, b)
+	full := buf.Bytes()
+
+	// Cut the second record inside its block, inside its length and right
+	// after its magic. Only the first block is returned each time.
+	second := 8 + len(a)
+	for _, n := range []int{len(full) - 1, second + 6, second + 4} {
+		records := readBlkRecords(t, full[:n], nil)
+		if len(records) != 1 || !bytes.Equal(records[0].block, a) {
+			t.Fatalf("truncated to %d bytes: read %d records, want 1", n, len(records))
+		}
+	}
+}
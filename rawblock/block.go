@@ -0,0 +1,244 @@
+package rawblock
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	HeaderSize = 80
+)
+
+func DoubleSHA256(data []byte) [32]byte {
+	first := sha256.Sum256(data)
+	return sha256.Sum256(first[:])
+}
+
+// HashString returns a hash in the byte reversed hex form used by the RPC
+// interface and block explorers.
+func HashString(h [32]byte) string {
+	var reversed [32]byte
+	for i := range h {
+		reversed[i] = h[31-i]
+	}
+	return hex.EncodeToString(reversed[:])
+}
+
+// ParseHash is the inverse of HashString.
+func ParseHash(s string) ([32]byte, error) {
+	var h [32]byte
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return h, err
+	}
+	if len(data) != 32 {
+		return h, fmt.Errorf("hash must be 32 bytes, got %d", len(data))
+	}
+	for i := range data {
+		h[i] = data[31-i]
+	}
+	return h, nil
+}
+
+type Header struct {
+	Version    int32
+	PrevBlock  [32]byte
+	MerkleRoot [32]byte
+	Time       uint32
+	Bits       uint32
+	Nonce      uint32
+}
+
+func ParseHeader(data []byte) (Header, error) {
+	if len(data) < HeaderSize {
+		return Header{}, fmt.Errorf("header must be %d bytes, got %d", HeaderSize, len(data))
+	}
+	r := &reader{data: data}
+	h := readHeader(r)
+	return h, r.err
+}
+
+// ParseHeaders decodes consecutive headers stored either as raw 80 byte
+// records or as hex, optionally split over several lines.
+func ParseHeaders(data []byte) ([]Header, error) {
+	if decoded, err := hex.DecodeString(strings.Join(strings.Fields(string(data)), "")); err == nil {
+		data = decoded
+	}
+	if len(data)%HeaderSize != 0 {
+		return nil, fmt.Errorf("data is not a whole number of %d byte headers", HeaderSize)
+	}
+
+	var headers []Header
+	for i := 0; i < len(data); i += HeaderSize {
+		h, err := ParseHeader(data[i : i+HeaderSize])
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, h)
+	}
+	return headers, nil
+}
+
+func readHeader(r *reader) Header {
+	var h Header
+	h.Version = int32(r.uint32())
+	h.PrevBlock = r.hash()
+	h.MerkleRoot = r.hash()
+	h.Time = r.uint32()
+	h.Bits = r.uint32()
+	h.Nonce = r.uint32()
+	return h
+}
+
+func (h *Header) Serialize() []byte {
+	b := make([]byte, HeaderSize)
+	binary.LittleEndian.PutUint32(b[0:], uint32(h.Version))
+	copy(b[4:], h.PrevBlock[:])
+	copy(b[36:], h.MerkleRoot[:])
+	binary.LittleEndian.PutUint32(b[68:], h.Time)
+	binary.LittleEndian.PutUint32(b[72:], h.Bits)
+	binary.LittleEndian.PutUint32(b[76:], h.Nonce)
+	return b
+}
+
+// Hash is the double SHA256 block hash. Litecoin's proof of work uses the
+// scrypt hash of the header instead, but blocks are identified by this one.
+func (h *Header) Hash() [32]byte {
+	return DoubleSHA256(h.Serialize())
+}
+
+type Block struct {
+	Header
+	Transactions []*Transaction
+	MWEB         *MWEBBlock
+
+	size int
+	// mwebRaw is the extension block section after a HogEx, including its
+	// leading flag byte.
+	mwebRaw []byte
+}
+
+// Parse decodes a serialised block as returned by getblock verbosity 0 or
+// stored in blk*.dat files, including the MWEB extension block that follows
+// the transactions when the last one is a HogEx.
+func Parse(data []byte) (*Block, error) {
+	r := &reader{data: data}
+	b, err := readBlock(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.pos != len(data) {
+		return nil, fmt.Errorf("%d trailing bytes after block", len(data)-r.pos)
+	}
+	return b, nil
+}
+
+func readBlock(r *reader) (*Block, error) {
+	start := r.pos
+	b := &Block{Header: readHeader(r)}
+
+	n := r.count(10)
+	for i := 0; i < n && r.err == nil; i++ {
+		b.Transactions = append(b.Transactions, readTransaction(r))
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(b.Transactions) == 0 {
+		return nil, errors.New("block has no transactions")
+	}
+
+	if b.HasHogEx() {
+		mwebStart := r.pos
+		b.MWEB = readMWEBBlock(r)
+		if r.err != nil {
+			return nil, r.err
+		}
+		b.mwebRaw = r.data[mwebStart:r.pos]
+	}
+
+	b.size = r.pos - start
+	return b, nil
+}
+
+// Serialize reassembles the block from its header, transactions and MWEB
+// extension block.
+func (b *Block) Serialize() []byte {
+	data := appendCompactSize(b.Header.Serialize(), uint64(len(b.Transactions)))
+	for _, tx := range b.Transactions {
+		data = append(data, tx.Serialize()...)
+	}
+	return append(data, b.mwebRaw...)
+}
+
+// HasHogEx reports whether the block ends with a HogEx (integration)
+// transaction and therefore carries an MWEB extension block.
+func (b *Block) HasHogEx() bool {
+	return len(b.Transactions) >= 2 && b.Transactions[len(b.Transactions)-1].HogEx
+}
+
+func (b *Block) Size() int {
+	return b.size
+}
+
+// StrippedSize is the block size without witness or MWEB data.
+func (b *Block) StrippedSize() int {
+	size := HeaderSize + compactSizeLen(uint64(len(b.Transactions)))
+	for _, tx := range b.Transactions {
+		size += tx.StrippedSize()
+	}
+	return size
+}
+
+// Weight follows Litecoin's GetBlockWeight, which excludes the MWEB data.
+func (b *Block) Weight() int {
+	stripped := b.StrippedSize()
+	full := HeaderSize + compactSizeLen(uint64(len(b.Transactions)))
+	for _, tx := range b.Transactions {
+		full += tx.NoMWEBSize()
+	}
+	return stripped*3 + full
+}
+
+// Height decodes the BIP34 height pushed at the start of the coinbase
+// scriptSig. BIP34 was only enforced from params.BIP34Height and older
+// coinbases can start with any push, so the caller passes the lowest height
+// it knows the block to be at, or -1 if it doesn't know. ok is false unless
+// that shows the block is past BIP34 activation and the decoded height is
+// consistent with it.
+func (b *Block) Height(params Params, minHeight int64) (height int64, ok bool) {
+	if minHeight < params.BIP34Height || len(b.Transactions) == 0 || len(b.Transactions[0].TxIn) == 0 {
+		return 0, false
+	}
+	height, ok = b.coinbaseHeight()
+	if !ok || height < minHeight {
+		return 0, false
+	}
+	return height, true
+}
+
+func (b *Block) coinbaseHeight() (int64, bool) {
+	script := b.Transactions[0].TxIn[0].ScriptSig
+	if len(script) == 0 {
+		return 0, false
+	}
+
+	op := script[0]
+	switch {
+	case op == 0x00:
+		return 0, true
+	case op >= 0x51 && op <= 0x60:
+		return int64(op - 0x50), true
+	case op >= 0x01 && op <= 0x08 && len(script) > int(op):
+		var n int64
+		for i := int(op); i >= 1; i-- {
+			n = n<<8 | int64(script[i])
+		}
+		return n, true
+	}
+	return 0, false
+}
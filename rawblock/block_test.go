@@ -0,0 +1,137 @@
+package rawblock
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readHexFixture(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestHeaderRoundTrip(t *testing.T) {
+	// Mainnet block 29255, also the default header in scrypt_pow.
+	data := readHexFixture(t, "header29255.hex")
+	h, err := ParseHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != 1 || h.Time != 0x4ebb17b8 || h.Bits != 0x1d018ea7 || h.Nonce != 0xd4592d01 {
+		t.Fatalf("ParseHeader = %+v", h)
+	}
+	if prev := HashString(h.PrevBlock); prev != "279f6330ccbbb9103b9e3a5350765052081ddbae898f1ef6b8c64f3bcef715f6" {
+		t.Fatalf("PrevBlock = %s", prev)
+	}
+	if hash := HashString(h.Hash()); hash != "adf6e2e56df692822f5e064a8b6404a05d67cccd64bc90f57f65b46805e9a54b" {
+		t.Fatalf("Hash = %s", hash)
+	}
+	if !bytes.Equal(h.Serialize(), data) {
+		t.Fatalf("Serialize = %x, want %x", h.Serialize(), data)
+	}
+
+	if _, err := ParseHeader(data[:HeaderSize-1]); err == nil {
+		t.Fatal("expected an error for a truncated header")
+	}
+}
+
+func TestGenesisRoundTrip(t *testing.T) {
+	data := readHexFixture(t, "genesis.hex")
+	b, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Serialize(), data) {
+		t.Fatal("Serialize doesn't match the parsed block")
+	}
+
+	v := b.Verbose(MainNetParams, 0)
+	if v.Hash != "12a765e31ffd4059bada1e25190f6e98c99d9714d334efa41a195a7e7e04bfe2" {
+		t.Fatalf("Hash = %s", v.Hash)
+	}
+	if v.MerkleRoot != "97ddfbbae6be97fd6cdf3e7ca13232a3afff2353e29badfab7f73011edd4ced9" || v.Tx[0].TxID != v.MerkleRoot {
+		t.Fatalf("MerkleRoot = %s, TxID = %s", v.MerkleRoot, v.Tx[0].TxID)
+	}
+	if v.Size != len(data) || v.Weight != 4*len(data) || v.Tx[0].Vout[0].Value != 50 || v.Tx[0].Vout[0].ScriptPubKey.Type != "pubkey" {
+		t.Fatalf("Verbose = %+v", v)
+	}
+	// The genesis coinbase starts with an nBits push, not a height.
+	if v.Height != -1 {
+		t.Fatalf("Height = %d, want -1", v.Height)
+	}
+
+	if _, err := Parse(append(data, 0)); err == nil {
+		t.Fatal("expected an error for trailing bytes")
+	}
+	for i := 0; i < len(data); i++ {
+		if _, err := Parse(data[:i]); err == nil {
+			t.Fatalf("expected an error for a block truncated to %d bytes", i)
+		}
+	}
+}
+
+// hogex.hex is a synthetic block at the MWEB activation height 2265984: a
+// coinbase, a HogEx transaction (flag 0x08) and an MWEB extension block with
+// a single kernel carrying a fee and a peg-in.
+func TestHogExRoundTrip(t *testing.T) {
+	data := readHexFixture(t, "hogex.hex")
+	b, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Serialize(), data) {
+		t.Fatal("Serialize doesn't match the parsed block")
+	}
+
+	hogex := b.Transactions[len(b.Transactions)-1]
+	if !b.HasHogEx() || hogex.Flags != TX_FLAG_MWEB || hogex.MWEB != nil {
+		t.Fatalf("HogEx = %+v", hogex)
+	}
+	if b.MWEB == nil || b.MWEB.Header.Height != 2265984 || len(b.MWEB.Kernels) != 1 {
+		t.Fatalf("MWEB = %+v", b.MWEB)
+	}
+	if k := b.MWEB.Kernels[0]; k.Fee != 10 || k.PegIn != 256 {
+		t.Fatalf("Kernel = %+v", k)
+	}
+	if b.Size() != len(data) || b.Weight() != b.StrippedSize()*4 {
+		t.Fatalf("Size = %d, StrippedSize = %d, Weight = %d", b.Size(), b.StrippedSize(), b.Weight())
+	}
+}
+
+func TestHeight(t *testing.T) {
+	b, err := Parse(readHexFixture(t, "hogex.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		params    Params
+		minHeight int64
+		height    int64
+		ok        bool
+	}{
+		{MainNetParams, -1, 0, false},
+		{MainNetParams, 709999, 0, false},
+		{MainNetParams, 710000, 2265984, true},
+		{MainNetParams, 2265984, 2265984, true},
+		{MainNetParams, 2265985, 0, false},
+		{TestNetParams, 76, 2265984, true},
+	}
+	for _, test := range tests {
+		height, ok := b.Height(test.params, test.minHeight)
+		if height != test.height || ok != test.ok {
+			t.Errorf("Height(%s, %d) = %d, %v, want %d, %v", test.params.Name, test.minHeight, height, ok, test.height, test.ok)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package rawblock
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+)
+
+type BlockLocation struct {
+	File   int
+	Offset int64
+	Size   int
+}
+
+type chainEntry struct {
+	hash   [32]byte
+	header Header
+	loc    BlockLocation
+}
+
+// Chain is the best chain rebuilt from the blk*.dat files of a node data
+// directory. Blocks are stored in the order they were downloaded rather than
+// by height, so every header is indexed first and the chain with the most
+// work is then followed from the genesis block through the prev-hash links.
+type Chain struct {
+	files   []string
+	xorKey  []byte
+	entries []chainEntry
+	heights map[[32]byte]int
+
+	mu      sync.Mutex
+	handles map[int]*os.File
+}
+
+// OpenChain indexes the blocks stored under datadir for the network in params.
+func OpenChain(datadir string, params Params) (*Chain, error) {
+	dir := BlocksDir(datadir)
+	files, err := BlkFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no blk*.dat files found in %s", dir)
+	}
+
+	xorKey, err := ReadXorKey(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []chainEntry
+	index := make(map[[32]byte]int)
+	for i, path := range files {
+		err := ReadBlkFile(path, params.Magic, xorKey, func(offset int64, block []byte) error {
+			header, err := ParseHeader(block)
+			if err != nil {
+				return err
+			}
+			hash := header.Hash()
+			if _, ok := index[hash]; ok {
+				return nil
+			}
+			index[hash] = len(all)
+			all = append(all, chainEntry{
+				hash:   hash,
+				header: header,
+				loc:    BlockLocation{File: i, Offset: offset, Size: len(block)},
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+
+	best, err := bestChain(all, index)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Chain{
+		files:   files,
+		xorKey:  xorKey,
+		entries: best,
+		heights: make(map[[32]byte]int, len(best)),
+		handles: make(map[int]*os.File),
+	}
+	for i, e := range best {
+		c.heights[e.hash] = i
+	}
+	return c, nil
+}
+
+// BlockWork is the expected number of hashes needed to find a block with the
+// given bits.
+func BlockWork(bits uint32) *big.Int {
+	target := CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+	work := new(big.Int).Lsh(big.NewInt(1), 256)
+	return work.Div(work, target.Add(target, big.NewInt(1)))
+}
+
+// bestChain walks every branch from the genesis block and returns the
+// entries of the branch with the most cumulative work in height order.
+// Blocks whose parent was never stored are ignored.
+func bestChain(all []chainEntry, index map[[32]byte]int) ([]chainEntry, error) {
+	children := make(map[int][]int)
+	genesis := -1
+	for i, e := range all {
+		if e.header.PrevBlock == ([32]byte{}) {
+			if genesis >= 0 {
+				return nil, errors.New("block files contain more than one genesis block")
+			}
+			genesis = i
+			continue
+		}
+		parent, ok := index[e.header.PrevBlock]
+		if !ok {
+			continue
+		}
+		children[parent] = append(children[parent], i)
+	}
+	if genesis < 0 {
+		return nil, errors.New("block files do not contain the genesis block")
+	}
+
+	type node struct {
+		entry  int
+		height int
+		work   *big.Int
+	}
+
+	parents := make(map[int]int)
+	bestTip, bestHeight, bestWork := genesis, 0, BlockWork(all[genesis].header.Bits)
+	stack := []node{{genesis, 0, bestWork}}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if n.work.Cmp(bestWork) > 0 {
+			bestTip, bestHeight, bestWork = n.entry, n.height, n.work
+		}
+
+		for _, child := range children[n.entry] {
+			parents[child] = n.entry
+			work := new(big.Int).Add(n.work, BlockWork(all[child].header.Bits))
+			stack = append(stack, node{child, n.height + 1, work})
+		}
+	}
+
+	orphans := len(all) - len(parents) - 1
+	if orphans > 0 {
+		log.Printf("Ignoring %d blocks that are not connected to the genesis block.", orphans)
+	}
+
+	chain := make([]chainEntry, bestHeight+1)
+	for i, e := bestHeight, bestTip; i >= 0; i-- {
+		chain[i] = all[e]
+		e = parents[e]
+	}
+	return chain, nil
+}
+
+// Height returns the height of the chain tip.
+func (c *Chain) Height() int64 {
+	return int64(len(c.entries) - 1)
+}
+
+func (c *Chain) entry(height int64) (*chainEntry, error) {
+	if height < 0 || height >= int64(len(c.entries)) {
+		return nil, fmt.Errorf("block height %d is out of range, the chain tip is %d", height, c.Height())
+	}
+	return &c.entries[height], nil
+}
+
+func (c *Chain) Header(height int64) (Header, error) {
+	e, err := c.entry(height)
+	if err != nil {
+		return Header{}, err
+	}
+	return e.header, nil
+}
+
+// Hash returns the block hash at height in RPC form. It has the same
+// signature as the RPC client's GetBlockHash so either can validate
+// checkpoints.
+func (c *Chain) Hash(height int64) (string, error) {
+	e, err := c.entry(height)
+	if err != nil {
+		return "", err
+	}
+	return HashString(e.hash), nil
+}
+
+// HeightOf returns the height of a block hash on the best chain.
+func (c *Chain) HeightOf(hash string) (int64, bool) {
+	h, err := ParseHash(hash)
+	if err != nil {
+		return 0, false
+	}
+	height, ok := c.heights[h]
+	return int64(height), ok
+}
+
+func (c *Chain) file(i int) (*os.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f, ok := c.handles[i]; ok {
+		return f, nil
+	}
+	f, err := os.Open(c.files[i])
+	if err != nil {
+		return nil, err
+	}
+	c.handles[i] = f
+	return f, nil
+}
+
+// BlockBytes reads the serialised block at height. It is safe to call from
+// several goroutines.
+func (c *Chain) BlockBytes(height int64) ([]byte, error) {
+	e, err := c.entry(height)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := c.file(e.loc.File)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, e.loc.Size)
+	if _, err := f.ReadAt(data, e.loc.Offset); err != nil {
+		return nil, fmt.Errorf("%s: %s", c.files[e.loc.File], err)
+	}
+	Xor(data, c.xorKey, e.loc.Offset)
+	return data, nil
+}
+
+func (c *Chain) Block(height int64) (*Block, error) {
+	data, err := c.BlockBytes(height)
+	if err != nil {
+		return nil, err
+	}
+	block, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %s", height, err)
+	}
+	return block, nil
+}
+
+// Close closes the open block files.
+func (c *Chain) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for i, f := range c.handles {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		delete(c.handles, i)
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package rawblock
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenChain(t *testing.T) {
+	var main [][]byte
+	var hashes [][32]byte
+	var prev [32]byte
+	for i := 0; i < 6; i++ {
+		block, hash := testBlock(t, prev, uint32(i), 0x207fffff)
+		main = append(main, block)
+		hashes = append(hashes, hash)
+		prev = hash
+	}
+
+	// A longer fork from height 2 with less total work, and a block whose
+	// parent was never stored.
+	var fork [][]byte
+	prev = hashes[2]
+	for i := 0; i < 5; i++ {
+		block, hash := testBlock(t, prev, uint32(100+i), 0x2100ffff)
+		fork = append(fork, block)
+		prev = hash
+	}
+	orphan, _ := testBlock(t, [32]byte{9}, 7, 0x207fffff)
+
+	// The blocks are stored out of order across two files, with a
+	// duplicate and a truncated record at the end.
+	var f1, f2 bytes.Buffer
+	for _, i := range []int{0, 3, 1} {
+		WriteBlkRecord(&f1, RegTestParams.Magic, main[i])
+	}
+	WriteBlkRecord(&f1, RegTestParams.Magic, fork[0])
+	f1.Write(make([]byte, 50))
+	for _, i := range []int{2, 5, 4} {
+		WriteBlkRecord(&f2, RegTestParams.Magic, main[i])
+	}
+	for _, block := range fork[1:] {
+		WriteBlkRecord(&f2, RegTestParams.Magic, block)
+	}
+	WriteBlkRecord(&f2, RegTestParams.Magic, orphan)
+	WriteBlkRecord(&f2, RegTestParams.Magic, main[5])
+	f2.Write(RegTestParams.Magic[:])
+	f2.Write([]byte{0xff, 0xff, 0, 0, 1})
+
+	key := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	d1, d2 := f1.Bytes(), f2.Bytes()
+	Xor(d1, key, 0)
+	Xor(d2, key, 0)
+
+	dir := t.TempDir()
+	blocks := filepath.Join(dir, "blocks")
+	if err := os.Mkdir(blocks, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(blocks, "blk00000.dat"), d1, 0644)
+	ioutil.WriteFile(filepath.Join(blocks, "blk00001.dat"), d2, 0644)
+	ioutil.WriteFile(filepath.Join(blocks, XOR_KEY_FILE), key, 0644)
+
+	c, err := OpenChain(dir, RegTestParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Height() != 5 {
+		t.Fatalf("Height = %d, want 5", c.Height())
+	}
+	for i := range main {
+		hash, err := c.Hash(int64(i))
+		if err != nil || hash != HashString(hashes[i]) {
+			t.Fatalf("Hash(%d) = %s, %v", i, hash, err)
+		}
+		data, err := c.BlockBytes(int64(i))
+		if err != nil || !bytes.Equal(data, main[i]) {
+			t.Fatalf("BlockBytes(%d) doesn't match the stored block: %v", i, err)
+		}
+	}
+	if height, ok := c.HeightOf(HashString(hashes[4])); !ok || height != 4 {
+		t.Fatalf("HeightOf = %d, %v, want 4", height, ok)
+	}
+	if _, err := c.Block(6); err == nil {
+		t.Fatal("expected an error for a height above the tip")
+	}
+}
@@ -0,0 +1,205 @@
+package rawblock
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+)
+
+const (
+	COIN = 100000000
+)
+
+// CompactToBig expands the compact "bits" form of a target.
+func CompactToBig(bits uint32) *big.Int {
+	mantissa := int64(bits & 0x007fffff)
+	exponent := uint(bits >> 24)
+
+	n := big.NewInt(mantissa)
+	if exponent <= 3 {
+		n.Rsh(n, 8*(3-exponent))
+	} else {
+		n.Lsh(n, 8*(exponent-3))
+	}
+	if bits&0x00800000 != 0 {
+		n.Neg(n)
+	}
+	return n
+}
+
+// Difficulty matches litecoind's GetDifficulty for the given bits.
+func Difficulty(bits uint32) float64 {
+	shift := int(bits>>24) & 0xff
+	diff := float64(0x0000ffff) / float64(bits&0x00ffffff)
+	for ; shift < 29; shift++ {
+		diff *= 256.0
+	}
+	for ; shift > 29; shift-- {
+		diff /= 256.0
+	}
+	return diff
+}
+
+// Verbose converts a decoded block into the same structure litecoind returns
+// for getblock with verbosity 2. Fields that need chain context
+// (confirmations, chainwork, mediantime, nextblockhash) are left empty, as are
+// the blake3 based MWEB hashes. minHeight is passed to Height and the height
+// is -1 when it can't be trusted.
+func (b *Block) Verbose(params Params, minHeight int64) *litecoinrpc.BlockVerbose {
+	v := &litecoinrpc.BlockVerbose{
+		Size:         b.Size(),
+		StrippedSize: b.StrippedSize(),
+		Weight:       b.Weight(),
+	}
+
+	hash := b.Header.Hash()
+	v.Hash = HashString(hash)
+	v.Height = -1
+	if height, ok := b.Height(params, minHeight); ok {
+		v.Height = height
+	}
+	v.Version = b.Header.Version
+	v.VersionHex = fmt.Sprintf("%08x", uint32(b.Header.Version))
+	v.MerkleRoot = HashString(b.Header.MerkleRoot)
+	v.Time = int64(b.Header.Time)
+	v.Nonce = b.Header.Nonce
+	v.Bits = fmt.Sprintf("%08x", b.Header.Bits)
+	v.Difficulty = Difficulty(b.Header.Bits)
+	v.NTx = len(b.Transactions)
+	if b.Header.PrevBlock != [32]byte{} {
+		v.PreviousBlockHash = HashString(b.Header.PrevBlock)
+	}
+
+	for _, tx := range b.Transactions {
+		v.Tx = append(v.Tx, tx.Verbose(params))
+	}
+
+	if b.MWEB != nil {
+		v.MWEB = b.MWEB.Verbose()
+	}
+	return v
+}
+
+// Verbose converts a transaction into the decoderawtransaction structure.
+func (tx *Transaction) Verbose(params Params) litecoinrpc.Transaction {
+	v := litecoinrpc.Transaction{
+		TxID:     HashString(tx.TxID()),
+		Hash:     HashString(tx.WTxID()),
+		Version:  tx.Version,
+		Size:     tx.Size(),
+		VSize:    tx.VSize(),
+		Weight:   tx.Weight(),
+		LockTime: tx.LockTime,
+		Vin:      []litecoinrpc.TxIn{},
+		Vout:     []litecoinrpc.TxOut{},
+		Hex:      hex.EncodeToString(tx.Serialize()),
+	}
+
+	coinbase := tx.IsCoinbase()
+	for _, in := range tx.TxIn {
+		var vin litecoinrpc.TxIn
+		if coinbase {
+			vin.Coinbase = hex.EncodeToString(in.ScriptSig)
+		} else {
+			vin.TxID = HashString(in.PrevTxID)
+			vin.Vout = in.PrevIndex
+			vin.ScriptSig = &litecoinrpc.ScriptSig{
+				Asm: ScriptToAsm(in.ScriptSig),
+				Hex: hex.EncodeToString(in.ScriptSig),
+			}
+		}
+		for _, item := range in.Witness {
+			vin.TxInWitness = append(vin.TxInWitness, hex.EncodeToString(item))
+		}
+		vin.Sequence = in.Sequence
+		v.Vin = append(v.Vin, vin)
+	}
+
+	for i, out := range tx.TxOut {
+		scriptType, _, _ := ScriptType(out.PkScript)
+		v.Vout = append(v.Vout, litecoinrpc.TxOut{
+			Value: float64(out.Value) / COIN,
+			N:     uint32(i),
+			ScriptPubKey: litecoinrpc.ScriptPubKey{
+				Asm:     ScriptToAsm(out.PkScript),
+				Hex:     hex.EncodeToString(out.PkScript),
+				Type:    scriptType,
+				Address: ScriptAddress(out.PkScript, params),
+			},
+		})
+	}
+	return v
+}
+
+type mwebInputJSON struct {
+	Features     byte   `json:"features"`
+	OutputID     string `json:"output_id"`
+	Commitment   string `json:"commit"`
+	OutputPubKey string `json:"output_pubkey"`
+	InputPubKey  string `json:"input_pubkey,omitempty"`
+}
+
+type mwebOutputJSON struct {
+	Commitment     string `json:"commit"`
+	SenderPubKey   string `json:"sender_pubkey"`
+	ReceiverPubKey string `json:"receiver_pubkey"`
+	Features       byte   `json:"features"`
+}
+
+// Verbose converts the extension block into litecoind's mweb getblock field.
+func (m *MWEBBlock) Verbose() *litecoinrpc.MWEBBlock {
+	v := &litecoinrpc.MWEBBlock{
+		Height:        m.Header.Height,
+		KernelOffset:  hex.EncodeToString(m.Header.KernelOffset[:]),
+		StealthOffset: hex.EncodeToString(m.Header.StealthOffset[:]),
+		NumKernels:    int64(m.Header.KernelMMRSize),
+		NumTXOs:       int64(m.Header.OutputMMRSize),
+		KernelRoot:    hex.EncodeToString(m.Header.KernelRoot[:]),
+		OutputRoot:    hex.EncodeToString(m.Header.OutputRoot[:]),
+		LeafRoot:      hex.EncodeToString(m.Header.LeafsetRoot[:]),
+		Inputs:        []json.RawMessage{},
+		Outputs:       []json.RawMessage{},
+		Kernels:       []litecoinrpc.MWEBKernel{},
+	}
+
+	for _, in := range m.Inputs {
+		data, _ := json.Marshal(mwebInputJSON{
+			Features:     in.Features,
+			OutputID:     hex.EncodeToString(in.OutputID[:]),
+			Commitment:   hex.EncodeToString(in.Commitment),
+			OutputPubKey: hex.EncodeToString(in.OutputPubKey),
+			InputPubKey:  hex.EncodeToString(in.InputPubKey),
+		})
+		v.Inputs = append(v.Inputs, data)
+	}
+
+	for _, out := range m.Outputs {
+		data, _ := json.Marshal(mwebOutputJSON{
+			Commitment:     hex.EncodeToString(out.Commitment),
+			SenderPubKey:   hex.EncodeToString(out.SenderPubKey),
+			ReceiverPubKey: hex.EncodeToString(out.ReceiverPubKey),
+			Features:       out.Message.Features,
+		})
+		v.Outputs = append(v.Outputs, data)
+	}
+
+	for _, k := range m.Kernels {
+		kernel := litecoinrpc.MWEBKernel{
+			Fee:        k.Fee,
+			PegIn:      k.PegIn,
+			PegOuts:    []litecoinrpc.MWEBPegOut{},
+			LockHeight: k.LockHeight,
+		}
+		for _, p := range k.PegOuts {
+			kernel.PegOuts = append(kernel.PegOuts, litecoinrpc.MWEBPegOut{
+				Value:        p.Value,
+				ScriptPubKey: hex.EncodeToString(p.PkScript),
+			})
+		}
+		v.Kernels = append(v.Kernels, kernel)
+	}
+	return v
+}
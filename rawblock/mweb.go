@@ -0,0 +1,206 @@
+package rawblock
+
+// The MWEB structures below mirror the libmw serialisation used by Litecoin
+// Core. Hashes inside the extension block (output and kernel IDs, the MWEB
+// header hash) are blake3 based and are not computed here.
+
+const (
+	COMMITMENT_SIZE     = 33
+	PUBKEY_SIZE         = 33
+	SIGNATURE_SIZE      = 64
+	BLINDING_SIZE       = 32
+	RANGE_PROOF_SIZE    = 675
+	MASKED_NONCE_SIZE   = 16
+	MWEB_HASH_SIZE      = 32
+	MIN_MWEB_INPUT_SIZE = 1 + MWEB_HASH_SIZE + COMMITMENT_SIZE + PUBKEY_SIZE + SIGNATURE_SIZE
+
+	INPUT_STEALTH_KEY_FEATURE = 0x01
+	INPUT_EXTRA_DATA_FEATURE  = 0x02
+
+	OUTPUT_STANDARD_FIELDS_FEATURE = 0x01
+	OUTPUT_EXTRA_DATA_FEATURE      = 0x02
+
+	KERNEL_FEE_FEATURE            = 0x01
+	KERNEL_PEGIN_FEATURE          = 0x02
+	KERNEL_PEGOUT_FEATURE         = 0x04
+	KERNEL_HEIGHT_LOCK_FEATURE    = 0x08
+	KERNEL_STEALTH_EXCESS_FEATURE = 0x10
+	KERNEL_EXTRA_DATA_FEATURE     = 0x20
+)
+
+type MWEBHeader struct {
+	Height        int64
+	OutputRoot    [32]byte
+	KernelRoot    [32]byte
+	LeafsetRoot   [32]byte
+	KernelOffset  [32]byte
+	StealthOffset [32]byte
+	OutputMMRSize uint64
+	KernelMMRSize uint64
+}
+
+type MWEBInput struct {
+	Features     byte
+	OutputID     [32]byte
+	Commitment   []byte
+	OutputPubKey []byte
+	InputPubKey  []byte
+	ExtraData    []byte
+	Signature    []byte
+}
+
+type MWEBOutputMessage struct {
+	Features          byte
+	KeyExchangePubKey []byte
+	ViewTag           byte
+	MaskedValue       uint64
+	MaskedNonce       []byte
+	ExtraData         []byte
+}
+
+type MWEBOutput struct {
+	Commitment     []byte
+	SenderPubKey   []byte
+	ReceiverPubKey []byte
+	Message        MWEBOutputMessage
+	RangeProof     []byte
+	Signature      []byte
+}
+
+type MWEBPegOut struct {
+	Value    int64
+	PkScript []byte
+}
+
+type MWEBKernel struct {
+	Features      byte
+	Fee           int64
+	PegIn         int64
+	PegOuts       []MWEBPegOut
+	LockHeight    int64
+	StealthExcess []byte
+	ExtraData     []byte
+	Excess        []byte
+	Signature     []byte
+}
+
+type MWEBTxBody struct {
+	Inputs  []MWEBInput
+	Outputs []MWEBOutput
+	Kernels []MWEBKernel
+}
+
+type MWEBBlock struct {
+	Header MWEBHeader
+	MWEBTxBody
+}
+
+type MWEBTransaction struct {
+	KernelOffset  [32]byte
+	StealthOffset [32]byte
+	MWEBTxBody
+}
+
+// readMWEBBlock reads the optional MWEB block that follows the transactions.
+// A block with a HogEx but no MWEB data (flag byte 0) returns nil.
+func readMWEBBlock(r *reader) *MWEBBlock {
+	if r.uint8() == 0 {
+		return nil
+	}
+
+	b := &MWEBBlock{}
+	b.Header.Height = int64(r.varInt())
+	b.Header.OutputRoot = r.hash()
+	b.Header.KernelRoot = r.hash()
+	b.Header.LeafsetRoot = r.hash()
+	b.Header.KernelOffset = r.hash()
+	b.Header.StealthOffset = r.hash()
+	b.Header.OutputMMRSize = r.varInt()
+	b.Header.KernelMMRSize = r.varInt()
+	b.MWEBTxBody = readMWEBTxBody(r)
+	return b
+}
+
+func readMWEBTransaction(r *reader) *MWEBTransaction {
+	tx := &MWEBTransaction{}
+	tx.KernelOffset = r.hash()
+	tx.StealthOffset = r.hash()
+	tx.MWEBTxBody = readMWEBTxBody(r)
+	return tx
+}
+
+func readMWEBTxBody(r *reader) MWEBTxBody {
+	var body MWEBTxBody
+
+	n := r.count(MIN_MWEB_INPUT_SIZE)
+	for i := 0; i < n && r.err == nil; i++ {
+		var in MWEBInput
+		in.Features = r.uint8()
+		in.OutputID = r.hash()
+		in.Commitment = r.bytes(COMMITMENT_SIZE)
+		in.OutputPubKey = r.bytes(PUBKEY_SIZE)
+		if in.Features&INPUT_STEALTH_KEY_FEATURE != 0 {
+			in.InputPubKey = r.bytes(PUBKEY_SIZE)
+		}
+		if in.Features&INPUT_EXTRA_DATA_FEATURE != 0 {
+			in.ExtraData = r.varBytes()
+		}
+		in.Signature = r.bytes(SIGNATURE_SIZE)
+		body.Inputs = append(body.Inputs, in)
+	}
+
+	n = r.count(COMMITMENT_SIZE + 2*PUBKEY_SIZE + 1 + RANGE_PROOF_SIZE + SIGNATURE_SIZE)
+	for i := 0; i < n && r.err == nil; i++ {
+		var out MWEBOutput
+		out.Commitment = r.bytes(COMMITMENT_SIZE)
+		out.SenderPubKey = r.bytes(PUBKEY_SIZE)
+		out.ReceiverPubKey = r.bytes(PUBKEY_SIZE)
+		out.Message.Features = r.uint8()
+		if out.Message.Features&OUTPUT_STANDARD_FIELDS_FEATURE != 0 {
+			out.Message.KeyExchangePubKey = r.bytes(PUBKEY_SIZE)
+			out.Message.ViewTag = r.uint8()
+			out.Message.MaskedValue = r.uint64()
+			out.Message.MaskedNonce = r.bytes(MASKED_NONCE_SIZE)
+		}
+		if out.Message.Features&OUTPUT_EXTRA_DATA_FEATURE != 0 {
+			out.Message.ExtraData = r.varBytes()
+		}
+		out.RangeProof = r.bytes(RANGE_PROOF_SIZE)
+		out.Signature = r.bytes(SIGNATURE_SIZE)
+		body.Outputs = append(body.Outputs, out)
+	}
+
+	n = r.count(1 + COMMITMENT_SIZE + SIGNATURE_SIZE)
+	for i := 0; i < n && r.err == nil; i++ {
+		var k MWEBKernel
+		k.Features = r.uint8()
+		if k.Features&KERNEL_FEE_FEATURE != 0 {
+			k.Fee = int64(r.varInt())
+		}
+		if k.Features&KERNEL_PEGIN_FEATURE != 0 {
+			k.PegIn = int64(r.varInt())
+		}
+		if k.Features&KERNEL_PEGOUT_FEATURE != 0 {
+			pegouts := r.count(2)
+			for j := 0; j < pegouts && r.err == nil; j++ {
+				var p MWEBPegOut
+				p.Value = int64(r.varInt())
+				p.PkScript = r.varBytes()
+				k.PegOuts = append(k.PegOuts, p)
+			}
+		}
+		if k.Features&KERNEL_HEIGHT_LOCK_FEATURE != 0 {
+			k.LockHeight = int64(r.varInt())
+		}
+		if k.Features&KERNEL_STEALTH_EXCESS_FEATURE != 0 {
+			k.StealthExcess = r.bytes(PUBKEY_SIZE)
+		}
+		if k.Features&KERNEL_EXTRA_DATA_FEATURE != 0 {
+			k.ExtraData = r.varBytes()
+		}
+		k.Excess = r.bytes(COMMITMENT_SIZE)
+		k.Signature = r.bytes(SIGNATURE_SIZE)
+		body.Kernels = append(body.Kernels, k)
+	}
+	return body
+}
@@ -0,0 +1,64 @@
+package rawblock
+
+import (
+	"fmt"
+)
+
+// Params holds the per network constants needed to frame blocks on disk,
+// encode addresses and connect to peers.
+type Params struct {
+	Name             string
+	Magic            [4]byte
+	DefaultPort      string
+	PubKeyHashAddrID byte
+	ScriptHashAddrID byte
+	Bech32HRP        string
+
+	// BIP34Height is the first height at which the coinbase must start
+	// with the block height.
+	BIP34Height int64
+}
+
+var (
+	MainNetParams = Params{
+		Name:             "main",
+		Magic:            [4]byte{0xfb, 0xc0, 0xb6, 0xdb},
+		DefaultPort:      "9333",
+		PubKeyHashAddrID: 0x30,
+		ScriptHashAddrID: 0x32,
+		Bech32HRP:        "ltc",
+		BIP34Height:      710000,
+	}
+
+	TestNetParams = Params{
+		Name:             "test",
+		Magic:            [4]byte{0xfd, 0xd2, 0xc8, 0xf1},
+		DefaultPort:      "19335",
+		PubKeyHashAddrID: 0x6f,
+		ScriptHashAddrID: 0x3a,
+		Bech32HRP:        "tltc",
+		BIP34Height:      76,
+	}
+
+	RegTestParams = Params{
+		Name:             "regtest",
+		Magic:            [4]byte{0xfa, 0xbf, 0xb5, 0xda},
+		DefaultPort:      "19444",
+		PubKeyHashAddrID: 0x6f,
+		ScriptHashAddrID: 0x3a,
+		Bech32HRP:        "rltc",
+		BIP34Height:      500,
+	}
+)
+
+func GetParams(name string) (Params, error) {
+	switch name {
+	case "main", "mainnet":
+		return MainNetParams, nil
+	case "test", "testnet":
+		return TestNetParams, nil
+	case "regtest":
+		return RegTestParams, nil
+	}
+	return Params{}, fmt.Errorf("unknown network %q", name)
+}
@@ -0,0 +1,172 @@
+package rawblock
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var ErrUnexpectedEOF = errors.New("unexpected end of data")
+
+// reader walks a serialised block. Once an error occurs every further read
+// returns zero values, so callers only need to check err at the end.
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) fail(err error) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%s at offset %d", err, r.pos)
+	}
+}
+
+func (r *reader) read(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.fail(ErrUnexpectedEOF)
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) peek() byte {
+	if r.err != nil || r.pos >= len(r.data) {
+		r.fail(ErrUnexpectedEOF)
+		return 0
+	}
+	return r.data[r.pos]
+}
+
+func (r *reader) bytes(n int) []byte {
+	b := r.read(n)
+	if b == nil {
+		return nil
+	}
+	out := make([]byte, n)
+	copy(out, b)
+	return out
+}
+
+func (r *reader) hash() (h [32]byte) {
+	copy(h[:], r.read(32))
+	return
+}
+
+func (r *reader) uint8() byte {
+	b := r.read(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.read(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *reader) uint64() uint64 {
+	b := r.read(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+// compactSize reads the variable length integer used for vector lengths.
+func (r *reader) compactSize() uint64 {
+	switch n := r.uint8(); n {
+	case 0xfd:
+		b := r.read(2)
+		if b == nil {
+			return 0
+		}
+		return uint64(binary.LittleEndian.Uint16(b))
+	case 0xfe:
+		return uint64(r.uint32())
+	case 0xff:
+		return r.uint64()
+	default:
+		return uint64(n)
+	}
+}
+
+// count reads a compactSize vector length and rejects values that cannot
+// possibly fit in the remaining data, with min being the smallest encoding
+// of a single element.
+func (r *reader) count(min int) int {
+	n := r.compactSize()
+	if r.err != nil {
+		return 0
+	}
+	if min < 1 {
+		min = 1
+	}
+	if n > uint64(len(r.data)-r.pos)/uint64(min) {
+		r.fail(fmt.Errorf("vector length %d exceeds remaining data", n))
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) varBytes() []byte {
+	return r.bytes(r.count(1))
+}
+
+// varInt reads the MSB base-128 VARINT encoding used by the MWEB structures,
+// which is distinct from compactSize.
+func (r *reader) varInt() uint64 {
+	var n uint64
+	for i := 0; i < 10; i++ {
+		b := r.uint8()
+		if r.err != nil {
+			return 0
+		}
+		n = n<<7 | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			return n
+		}
+		n++
+	}
+	r.fail(errors.New("varint too long"))
+	return 0
+}
+
+func appendCompactSize(b []byte, n uint64) []byte {
+	switch {
+	case n < 0xfd:
+		return append(b, byte(n))
+	case n <= 0xffff:
+		return append(b, 0xfd, byte(n), byte(n>>8))
+	case n <= 0xffffffff:
+		return append(b, 0xfe, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+	default:
+		b = append(b, 0xff)
+		for i := uint(0); i < 64; i += 8 {
+			b = append(b, byte(n>>i))
+		}
+		return b
+	}
+}
+
+func compactSizeLen(n uint64) int {
+	switch {
+	case n < 0xfd:
+		return 1
+	case n <= 0xffff:
+		return 3
+	case n <= 0xffffffff:
+		return 5
+	default:
+		return 9
+	}
+}
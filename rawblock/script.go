@@ -0,0 +1,212 @@
+package rawblock
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+const (
+	OP_0             = 0x00
+	OP_PUSHDATA1     = 0x4c
+	OP_PUSHDATA2     = 0x4d
+	OP_PUSHDATA4     = 0x4e
+	OP_1NEGATE       = 0x4f
+	OP_1             = 0x51
+	OP_16            = 0x60
+	OP_RETURN        = 0x6a
+	OP_DUP           = 0x76
+	OP_EQUAL         = 0x87
+	OP_EQUALVERIFY   = 0x88
+	OP_HASH160       = 0xa9
+	OP_CHECKSIG      = 0xac
+	OP_CHECKMULTISIG = 0xae
+)
+
+var opcodeNames = map[byte]string{
+	0x61: "OP_NOP", 0x62: "OP_VER", 0x63: "OP_IF", 0x64: "OP_NOTIF", 0x65: "OP_VERIF", 0x66: "OP_VERNOTIF",
+	0x67: "OP_ELSE", 0x68: "OP_ENDIF", 0x69: "OP_VERIFY", 0x6a: "OP_RETURN", 0x6b: "OP_TOALTSTACK",
+	0x6c: "OP_FROMALTSTACK", 0x6d: "OP_2DROP", 0x6e: "OP_2DUP", 0x6f: "OP_3DUP", 0x70: "OP_2OVER",
+	0x71: "OP_2ROT", 0x72: "OP_2SWAP", 0x73: "OP_IFDUP", 0x74: "OP_DEPTH", 0x75: "OP_DROP", 0x76: "OP_DUP",
+	0x77: "OP_NIP", 0x78: "OP_OVER", 0x79: "OP_PICK", 0x7a: "OP_ROLL", 0x7b: "OP_ROT", 0x7c: "OP_SWAP",
+	0x7d: "OP_TUCK", 0x7e: "OP_CAT", 0x7f: "OP_SUBSTR", 0x80: "OP_LEFT", 0x81: "OP_RIGHT", 0x82: "OP_SIZE",
+	0x83: "OP_INVERT", 0x84: "OP_AND", 0x85: "OP_OR", 0x86: "OP_XOR", 0x87: "OP_EQUAL", 0x88: "OP_EQUALVERIFY",
+	0x89: "OP_RESERVED1", 0x8a: "OP_RESERVED2", 0x8b: "OP_1ADD", 0x8c: "OP_1SUB", 0x8d: "OP_2MUL",
+	0x8e: "OP_2DIV", 0x8f: "OP_NEGATE", 0x90: "OP_ABS", 0x91: "OP_NOT", 0x92: "OP_0NOTEQUAL", 0x93: "OP_ADD",
+	0x94: "OP_SUB", 0x95: "OP_MUL", 0x96: "OP_DIV", 0x97: "OP_MOD", 0x98: "OP_LSHIFT", 0x99: "OP_RSHIFT",
+	0x9a: "OP_BOOLAND", 0x9b: "OP_BOOLOR", 0x9c: "OP_NUMEQUAL", 0x9d: "OP_NUMEQUALVERIFY",
+	0x9e: "OP_NUMNOTEQUAL", 0x9f: "OP_LESSTHAN", 0xa0: "OP_GREATERTHAN", 0xa1: "OP_LESSTHANOREQUAL",
+	0xa2: "OP_GREATERTHANOREQUAL", 0xa3: "OP_MIN", 0xa4: "OP_MAX", 0xa5: "OP_WITHIN", 0xa6: "OP_RIPEMD160",
+	0xa7: "OP_SHA1", 0xa8: "OP_SHA256", 0xa9: "OP_HASH160", 0xaa: "OP_HASH256", 0xab: "OP_CODESEPARATOR",
+	0xac: "OP_CHECKSIG", 0xad: "OP_CHECKSIGVERIFY", 0xae: "OP_CHECKMULTISIG", 0xaf: "OP_CHECKMULTISIGVERIFY",
+	0xb0: "OP_NOP1", 0xb1: "OP_CHECKLOCKTIMEVERIFY", 0xb2: "OP_CHECKSEQUENCEVERIFY", 0xb3: "OP_NOP4",
+	0xb4: "OP_NOP5", 0xb5: "OP_NOP6", 0xb6: "OP_NOP7", 0xb7: "OP_NOP8", 0xb8: "OP_NOP9", 0xb9: "OP_NOP10",
+	0xba: "OP_CHECKSIGADD", 0x50: "OP_RESERVED",
+}
+
+type scriptOp struct {
+	opcode byte
+	data   []byte
+}
+
+// parseScript splits a script into opcodes and pushes. ok is false if a push
+// runs past the end of the script.
+func parseScript(script []byte) (ops []scriptOp, ok bool) {
+	for i := 0; i < len(script); {
+		op := scriptOp{opcode: script[i]}
+		i++
+
+		var n int
+		switch {
+		case op.opcode < OP_PUSHDATA1:
+			n = int(op.opcode)
+		case op.opcode == OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return ops, false
+			}
+			n = int(script[i])
+			i++
+		case op.opcode == OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return ops, false
+			}
+			n = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		case op.opcode == OP_PUSHDATA4:
+			if i+4 > len(script) {
+				return ops, false
+			}
+			n = int(binary.LittleEndian.Uint32(script[i:]))
+			i += 4
+		}
+
+		if n > 0 {
+			if n > len(script)-i {
+				return ops, false
+			}
+			op.data = script[i : i+n]
+			i += n
+		}
+		ops = append(ops, op)
+	}
+	return ops, true
+}
+
+// scriptNum decodes a minimally encoded little endian sign-magnitude number.
+func scriptNum(data []byte) int64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var n int64
+	for i, b := range data {
+		n |= int64(b) << uint(8*i)
+	}
+	if data[len(data)-1]&0x80 != 0 {
+		return -(n &^ (int64(0x80) << uint(8*(len(data)-1))))
+	}
+	return n
+}
+
+// ScriptToAsm disassembles a script the same way as litecoind's asm fields,
+// without signature hash type decoding.
+func ScriptToAsm(script []byte) string {
+	ops, ok := parseScript(script)
+	var parts []string
+	for _, op := range ops {
+		switch {
+		case op.opcode <= OP_PUSHDATA4:
+			if len(op.data) <= 4 {
+				parts = append(parts, strconv.FormatInt(scriptNum(op.data), 10))
+			} else {
+				parts = append(parts, hex.EncodeToString(op.data))
+			}
+		case op.opcode == OP_1NEGATE:
+			parts = append(parts, "-1")
+		case op.opcode >= OP_1 && op.opcode <= OP_16:
+			parts = append(parts, strconv.Itoa(int(op.opcode-OP_1+1)))
+		default:
+			name, known := opcodeNames[op.opcode]
+			if !known {
+				name = "OP_UNKNOWN"
+			}
+			parts = append(parts, name)
+		}
+	}
+	if !ok {
+		parts = append(parts, "[error]")
+	}
+	return strings.Join(parts, " ")
+}
+
+func smallInt(opcode byte) (int, bool) {
+	if opcode == OP_0 {
+		return 0, true
+	}
+	if opcode >= OP_1 && opcode <= OP_16 {
+		return int(opcode - OP_1 + 1), true
+	}
+	return 0, false
+}
+
+// ScriptType classifies an output script using litecoind's type names and
+// returns the witness program or hash the address is derived from.
+func ScriptType(script []byte) (scriptType string, version int, program []byte) {
+	n := len(script)
+	switch {
+	case n == 25 && script[0] == OP_DUP && script[1] == OP_HASH160 && script[2] == 20 &&
+		script[23] == OP_EQUALVERIFY && script[24] == OP_CHECKSIG:
+		return "pubkeyhash", 0, script[3:23]
+	case n == 23 && script[0] == OP_HASH160 && script[1] == 20 && script[22] == OP_EQUAL:
+		return "scripthash", 0, script[2:22]
+	case (n == 35 && script[0] == 33 || n == 67 && script[0] == 65) && script[n-1] == OP_CHECKSIG:
+		return "pubkey", 0, script[1 : n-1]
+	case n > 0 && script[0] == OP_RETURN:
+		return "nulldata", 0, nil
+	}
+
+	if n >= 4 && n <= 42 && int(script[1]) == n-2 && script[1] >= 2 {
+		if v, ok := smallInt(script[0]); ok {
+			program = script[2:]
+			switch {
+			case v == 0 && len(program) == 20:
+				return "witness_v0_keyhash", v, program
+			case v == 0 && len(program) == 32:
+				return "witness_v0_scripthash", v, program
+			case v == 1 && len(program) == 32:
+				return "witness_v1_taproot", v, program
+			case v != 0:
+				return "witness_unknown", v, program
+			}
+		}
+	}
+
+	ops, ok := parseScript(script)
+	if ok && len(ops) >= 4 && ops[len(ops)-1].opcode == OP_CHECKMULTISIG {
+		m, okM := smallInt(ops[0].opcode)
+		keys, okN := smallInt(ops[len(ops)-2].opcode)
+		if okM && okN && m >= 1 && keys >= m && keys == len(ops)-3 {
+			return "multisig", 0, nil
+		}
+	}
+	return "nonstandard", 0, nil
+}
+
+// ScriptAddress returns the address for standard output scripts, or an empty
+// string when the script has none.
+func ScriptAddress(script []byte, params Params) string {
+	scriptType, version, program := ScriptType(script)
+	switch scriptType {
+	case "pubkeyhash":
+		return Base58CheckEncode(params.PubKeyHashAddrID, program)
+	case "scripthash":
+		return Base58CheckEncode(params.ScriptHashAddrID, program)
+	case "witness_v0_keyhash", "witness_v0_scripthash", "witness_v1_taproot", "witness_unknown":
+		addr, err := SegWitAddress(params.Bech32HRP, version, program)
+		if err != nil {
+			return ""
+		}
+		return addr
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package rawblock
+
+import (
+	"errors"
+)
+
+const (
+	TX_FLAG_WITNESS = 0x01
+	TX_FLAG_MWEB    = 0x08
+
+	WITNESS_SCALE_FACTOR = 4
+)
+
+type TxIn struct {
+	PrevTxID  [32]byte
+	PrevIndex uint32
+	ScriptSig []byte
+	Sequence  uint32
+	Witness   [][]byte
+}
+
+// IsCoinbase reports whether the input spends the null outpoint.
+func (in *TxIn) IsCoinbase() bool {
+	return in.PrevTxID == [32]byte{} && in.PrevIndex == 0xffffffff
+}
+
+type TxOut struct {
+	Value    int64
+	PkScript []byte
+}
+
+type Transaction struct {
+	Version  int32
+	Flags    byte
+	TxIn     []TxIn
+	TxOut    []TxOut
+	LockTime uint32
+
+	// HogEx is set for the integration transaction that moves coins in and
+	// out of the MWEB. MWEB holds the attached MWEB transaction, which only
+	// appears in transactions outside of blocks.
+	HogEx bool
+	MWEB  *MWEBTransaction
+
+	raw          []byte
+	legacy       []byte
+	witnessBytes int
+}
+
+func readTransaction(r *reader) *Transaction {
+	start := r.pos
+	tx := &Transaction{}
+	tx.Version = int32(r.uint32())
+
+	if r.peek() == 0x00 {
+		r.uint8()
+		tx.Flags = r.uint8()
+		if tx.Flags == 0 {
+			r.fail(errors.New("transaction has extended format marker but no flags"))
+			return tx
+		}
+	}
+
+	bodyStart := r.pos
+	n := r.count(41)
+	for i := 0; i < n && r.err == nil; i++ {
+		var in TxIn
+		in.PrevTxID = r.hash()
+		in.PrevIndex = r.uint32()
+		in.ScriptSig = r.varBytes()
+		in.Sequence = r.uint32()
+		tx.TxIn = append(tx.TxIn, in)
+	}
+
+	n = r.count(9)
+	for i := 0; i < n && r.err == nil; i++ {
+		var out TxOut
+		out.Value = int64(r.uint64())
+		out.PkScript = r.varBytes()
+		tx.TxOut = append(tx.TxOut, out)
+	}
+	bodyEnd := r.pos
+
+	if tx.Flags&TX_FLAG_WITNESS != 0 {
+		witnessStart := r.pos
+		for i := range tx.TxIn {
+			items := r.count(1)
+			for j := 0; j < items && r.err == nil; j++ {
+				tx.TxIn[i].Witness = append(tx.TxIn[i].Witness, r.varBytes())
+			}
+		}
+		tx.witnessBytes = r.pos - witnessStart
+	}
+
+	if tx.Flags&TX_FLAG_MWEB != 0 {
+		if r.uint8() != 0 {
+			tx.MWEB = readMWEBTransaction(r)
+		} else {
+			if len(tx.TxOut) == 0 {
+				r.fail(errors.New("HogEx transaction has no outputs"))
+			}
+			tx.HogEx = true
+		}
+	}
+
+	if tx.Flags&^(TX_FLAG_WITNESS|TX_FLAG_MWEB) != 0 {
+		r.fail(errors.New("unknown transaction flags"))
+	}
+
+	lockTimeStart := r.pos
+	tx.LockTime = r.uint32()
+	if r.err != nil {
+		return tx
+	}
+
+	tx.raw = r.data[start:r.pos]
+	tx.legacy = make([]byte, 0, 4+bodyEnd-bodyStart+4)
+	tx.legacy = append(tx.legacy, r.data[start:start+4]...)
+	tx.legacy = append(tx.legacy, r.data[bodyStart:bodyEnd]...)
+	tx.legacy = append(tx.legacy, r.data[lockTimeStart:r.pos]...)
+	return tx
+}
+
+// Serialize returns the transaction exactly as it appeared in the block.
+func (tx *Transaction) Serialize() []byte {
+	return tx.raw
+}
+
+func (tx *Transaction) HasWitness() bool {
+	return tx.Flags&TX_FLAG_WITNESS != 0
+}
+
+func (tx *Transaction) IsCoinbase() bool {
+	return len(tx.TxIn) == 1 && tx.TxIn[0].IsCoinbase()
+}
+
+// TxID hashes the serialisation without witness or MWEB data.
+func (tx *Transaction) TxID() [32]byte {
+	return DoubleSHA256(tx.legacy)
+}
+
+// WTxID hashes the full serialisation, and equals TxID when the transaction
+// has neither witness data nor an MWEB transaction.
+func (tx *Transaction) WTxID() [32]byte {
+	if !tx.HasWitness() && tx.MWEB == nil {
+		return tx.TxID()
+	}
+	return DoubleSHA256(tx.raw)
+}
+
+func (tx *Transaction) Size() int {
+	return len(tx.raw)
+}
+
+func (tx *Transaction) StrippedSize() int {
+	return len(tx.legacy)
+}
+
+// NoMWEBSize is the size with witness data but without the MWEB flag and
+// data, as used for weight.
+func (tx *Transaction) NoMWEBSize() int {
+	if !tx.HasWitness() {
+		return len(tx.legacy)
+	}
+	return len(tx.legacy) + 2 + tx.witnessBytes
+}
+
+func (tx *Transaction) Weight() int {
+	return tx.StrippedSize()*(WITNESS_SCALE_FACTOR-1) + tx.NoMWEBSize()
+}
+
+func (tx *Transaction) VSize() int {
+	return (tx.Weight() + WITNESS_SCALE_FACTOR - 1) / WITNESS_SCALE_FACTOR
+}
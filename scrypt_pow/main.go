@@ -1,56 +1,345 @@
 package main
 
 import (
-	"code.google.com/p/go.crypto/scrypt"
-	"crypto/sha256"
 	"encoding/hex"
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+	"github.com/thrasher-/litecoin-tools/pow"
+	"github.com/thrasher-/litecoin-tools/rawblock"
 )
 
-func HashSHA256(input []byte) []byte {
-	hash := sha256.New()
-	hash.Write(input)
-	return hash.Sum(nil)
+// Mainnet block 29255, used when no header is given.
+const EXAMPLE_HEADER = "01000000f615f7ce3b4fc6b8f61e8f89aedb1d0852507650533a9e3b10b9bbcc30639f279fcaa86746e1ef52d3edb3c4ad8259920d509bd073605c9bf1d59983752a6b06b817bb4ea78e011d012d59d4"
+
+var (
+	RPCHost       string
+	RPCPort       int
+	RPCUsername   string
+	RPCPassword   string
+	RPCCookieFile string
+)
+
+// ReadHeaderFile returns the header of a raw or hex encoded block or header
+// file, such as the ones written by blockdumper.
+func ReadHeaderFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoded, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil {
+		data = decoded
+	}
+
+	if len(data) < rawblock.HeaderSize {
+		return nil, fmt.Errorf("%s is too short to hold a block header", path)
+	}
+	return data[:rawblock.HeaderSize], nil
 }
 
-func DoubleSHA256(input []byte) []byte {
-	return HashSHA256(HashSHA256(input))
+// GetHeaderAtHeight fetches the serialised header at height from the node.
+func GetHeaderAtHeight(height int64) ([]byte, error) {
+	rpc := litecoinrpc.New(RPCHost, RPCPort, RPCUsername, RPCPassword)
+	rpc.CookieFile = RPCCookieFile
+
+	hash, err := rpc.GetBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+
+	headerHex, err := rpc.GetBlockHeaderHex(hash)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(headerHex)
 }
 
-func DecodeHexString(input string) ([]byte, error) {
-	return hex.DecodeString(input)
+// GetHeaders fetches the headers start through end inclusive from the node.
+func GetHeaders(rpc *litecoinrpc.Client, start, end int64) ([]rawblock.Header, error) {
+	hashes, err := rpc.GetBlockHashes(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var blockHashes []string
+	for _, x := range hashes {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block hash for height %d: %s", x.Height, x.Err)
+		}
+		blockHashes = append(blockHashes, x.Hash)
+	}
+
+	results, err := rpc.GetBlockHeadersHex(blockHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	var headers []rawblock.Header
+	for i, x := range results {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block header %s at height %d: %s", x.Hash, hashes[i].Height, x.Err)
+		}
+		data, err := hex.DecodeString(x.Hex)
+		if err != nil {
+			return nil, err
+		}
+		header, err := rawblock.ParseHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
 }
 
-func HexToString(input []byte) string {
-	return hex.EncodeToString(input)
+// ValidateFile validates the headers in a headers file, the first of which
+// is at v's starting height.
+func ValidateFile(v *pow.Validator, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	headers, err := rawblock.ParseHeaders(data)
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	log.Printf("Validating %d headers from %s", len(headers), path)
+	return v.Validate(headers)
 }
 
-func ArrayReverse(input []byte) []byte {
-	for i, j := 0, len(input)-1; i < j; i, j = i+1, j-1 {
-		input[i], input[j] = input[j], input[i]
+// ValidateRange validates the headers start through end fetched from the
+// node. v must start at start minus one retarget interval (or 0), and those
+// preceding headers are loaded as context first.
+func ValidateRange(v *pow.Validator, start, end int64) error {
+	rpc := litecoinrpc.New(RPCHost, RPCPort, RPCUsername, RPCPassword)
+	rpc.CookieFile = RPCCookieFile
+
+	if end < 0 {
+		count, err := rpc.GetBlockCount()
+		if err != nil {
+			return err
+		}
+		end = count
+	}
+	if start > end {
+		return fmt.Errorf("invalid block range %d-%d", start, end)
+	}
+
+	if contextStart := v.Height() + 1; contextStart < start {
+		headers, err := GetHeaders(rpc, contextStart, start-1)
+		if err != nil {
+			return err
+		}
+		for _, h := range headers {
+			v.Prime(h)
+		}
 	}
-	return input
+
+	log.Printf("Validating headers %d-%d", start, end)
+	batch := int64(rpc.BatchSize)
+	if batch <= 0 {
+		batch = litecoinrpc.DefaultBatchSize
+	}
+	for i := start; i <= end; i += batch {
+		last := i + batch - 1
+		if last > end {
+			last = end
+		}
+		headers, err := GetHeaders(rpc, i, last)
+		if err != nil {
+			return err
+		}
+		if err := v.Validate(headers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportHashRate logs the miner's hash rate every interval until done is
+// closed.
+func reportHashRate(m *pow.Miner, interval time.Duration, done <-chan struct{}) {
+	startTime := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			hashes := m.Hashes()
+			log.Printf("%d hashes, %.2f H/s", hashes, float64(hashes)/time.Since(startTime).Seconds())
+		case <-done:
+			return
+		}
+	}
+}
+
+// MineHeader grinds the nonce of header until it meets its nBits target, or
+// until timeout if it is above zero.
+func MineHeader(header rawblock.Header, workers int, timeout time.Duration) (rawblock.Header, bool, error) {
+	m := pow.NewMiner(workers)
+	stop := make(chan struct{})
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() { close(stop) })
+		defer timer.Stop()
+	}
+
+	done := make(chan struct{})
+	go reportHashRate(m, time.Second*10, done)
+	startTime := time.Now()
+	solved, found, err := m.Mine(header, stop)
+	close(done)
+
+	elapsed := time.Since(startTime)
+	log.Printf("Computed %d hashes in %s, %.2f H/s", m.Hashes(), elapsed, float64(m.Hashes())/elapsed.Seconds())
+	return solved, found, err
+}
+
+// Benchmark hashes header on workers goroutines for duration and returns the
+// hash rate.
+func Benchmark(header rawblock.Header, workers int, duration time.Duration) (float64, error) {
+	m := pow.NewMiner(workers)
+	stop := make(chan struct{})
+	timer := time.AfterFunc(duration, func() { close(stop) })
+	defer timer.Stop()
+
+	startTime := time.Now()
+	if err := m.Bench(header, stop); err != nil {
+		return 0, err
+	}
+	return float64(m.Hashes()) / time.Since(startTime).Seconds(), nil
 }
 
 func main() {
-	header := "01000000f615f7ce3b4fc6b8f61e8f89aedb1d0852507650533a9e3b10b9bbcc30639f279fcaa86746e1ef52d3edb3c4ad8259920d509bd073605c9bf1d59983752a6b06b817bb4ea78e011d012d59d4"
-	data, err := DecodeHexString(header)
+	var headerHex, file, headersFile, network string
+	var height, start, end, fileHeight int64
+	var validate, mine bool
+	var workers int
+	var bits string
+	var bench, timeout time.Duration
+	flag.StringVar(&headerHex, "header", "", "Hex encoded 80 byte block header to verify.")
+	flag.StringVar(&file, "file", "", "Raw or hex encoded block file to verify the header of.")
+	flag.Int64Var(&height, "height", -1, "Block height to fetch the header of over RPC.")
+	flag.StringVar(&RPCHost, "rpchost", "127.0.0.1", "The RPC host to connect to.")
+	flag.IntVar(&RPCPort, "rpcport", 9332, "The RPC port to connect to.")
+	flag.StringVar(&RPCUsername, "rpcuser", "user", "The RPC username.")
+	flag.StringVar(&RPCPassword, "rpcpass", "pass", "The RPC password.")
+	flag.StringVar(&RPCCookieFile, "rpccookiefile", "", "The RPC cookie file to authenticate with instead of -rpcuser/-rpcpass.")
+	flag.BoolVar(&validate, "validate", false, "Validate a chain of headers from -headersfile or the -start to -end range over RPC.")
+	flag.Int64Var(&start, "start", 0, "First block height to validate over RPC.")
+	flag.Int64Var(&end, "end", -1, "Last block height to validate over RPC, inclusive. Defaults to the current tip.")
+	flag.StringVar(&headersFile, "headersfile", "", "File of consecutive raw or hex encoded headers to validate.")
+	flag.Int64Var(&fileHeight, "fileheight", 0, "Block height of the first header in -headersfile.")
+	flag.StringVar(&network, "network", "main", "Network whose difficulty rules are used for validation: main, test or regtest.")
+	flag.IntVar(&workers, "workers", runtime.NumCPU(), "Number of goroutines computing scrypt hashes during validation, mining and benchmarking.")
+	flag.BoolVar(&mine, "mine", false, "Grind the nonce of the header until it meets its target and print the solved header.")
+	flag.StringVar(&bits, "bits", "", "Hex nBits to replace the header's with before mining, such as 207fffff for regtest.")
+	flag.DurationVar(&timeout, "timeout", 0, "Give up mining after this long. Defaults to trying every nonce.")
+	flag.DurationVar(&bench, "bench", 0, "Benchmark scrypt hashing of the header for this long and report the hash rate.")
+	flag.Parse()
 
-	if err != nil {
-		fmt.Errorf("Unable to decode header hex string: %s", err)
+	if validate {
+		params, err := pow.GetConsensusParams(network)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var v *pow.Validator
+		if headersFile != "" {
+			v = pow.NewValidator(params, fileHeight)
+			v.Workers = workers
+			err = ValidateFile(v, headersFile)
+		} else {
+			contextStart := start - params.Interval()
+			if contextStart < 0 {
+				contextStart = 0
+			}
+			v = pow.NewValidator(params, contextStart)
+			v.Workers = workers
+			err = ValidateRange(v, start, end)
+		}
+		if err != nil {
+			log.Fatalf("Validation failed. Err: %s", err)
+		}
+
+		log.Printf("All headers up to height %d are valid.", v.Height())
+		if v.Unchecked > 0 {
+			log.Printf("The nBits of %d headers could not be checked as the headers they depend on were not part of the range.", v.Unchecked)
+		}
 		return
 	}
 
-	result := DoubleSHA256(data)
-	fmt.Println(HexToString(result))
-	fmt.Println(HexToString(ArrayReverse(result)))
+	var data []byte
+	var err error
+	switch {
+	case file != "":
+		data, err = ReadHeaderFile(file)
+	case height >= 0:
+		data, err = GetHeaderAtHeight(height)
+	default:
+		if headerHex == "" {
+			headerHex = EXAMPLE_HEADER
+		}
+		data, err = hex.DecodeString(headerHex)
+	}
+	if err != nil {
+		log.Fatalf("Unable to read block header. Err: %s", err)
+	}
 
-	dk, err := scrypt.Key(data, data, 1024, 1, 1, 32)
+	header, err := rawblock.ParseHeader(data)
 	if err != nil {
-		fmt.Errorf("Unable to generate scrypt key: %s", err)
+		log.Fatalf("Unable to decode block header. Err: %s", err)
+	}
+
+	if bits != "" {
+		value, err := strconv.ParseUint(bits, 16, 32)
+		if err != nil {
+			log.Fatalf("Invalid -bits %q. Err: %s", bits, err)
+		}
+		header.Bits = uint32(value)
+	}
+
+	if bench > 0 {
+		log.Printf("Benchmarking scrypt with %d workers for %s", workers, bench)
+		rate, err := Benchmark(header, workers, bench)
+		if err != nil {
+			log.Fatalf("Benchmark failed. Err: %s", err)
+		}
+		log.Printf("%.2f H/s, %.2f H/s per worker", rate, rate/float64(workers))
 		return
 	}
 
-	fmt.Println(hex.EncodeToString(dk))
-	fmt.Println(hex.EncodeToString(ArrayReverse(dk)))
+	if mine {
+		log.Printf("Mining with %d workers for bits %08x", workers, header.Bits)
+		solved, found, err := MineHeader(header, workers, timeout)
+		if err != nil {
+			log.Fatalf("Mining failed. Err: %s", err)
+		}
+		if !found {
+			log.Fatal("No nonce found that meets the target.")
+		}
+		header = solved
+		log.Printf("Found nonce %d, solved header: %s", header.Nonce, hex.EncodeToString(header.Serialize()))
+	}
+
+	result, err := pow.CheckHeader(header)
+	if err != nil {
+		log.Fatalf("Unable to check block header. Err: %s", err)
+	}
+
+	log.Printf("Block hash: %s", rawblock.HashString(result.Hash))
+	log.Printf("Scrypt hash: %s", rawblock.HashString(result.PoWHash))
+	log.Printf("Target: %s (bits %08x)", pow.TargetToString(result.Target), header.Bits)
+
+	if !result.Valid {
+		log.Fatal("Scrypt hash is above the target, the proof of work is invalid.")
+	}
+	log.Println("Scrypt hash is below the target, the proof of work is valid.")
 }
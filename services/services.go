@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	NODE_NONE    uint64 = 0
+	NODE_NETWORK uint64 = (1 << 0)
+	NODE_GETUTXO uint64 = (1 << 1)
+	NODE_BLOOM   uint64 = (1 << 2)
+	NODE_WITNESS uint64 = (1 << 3)
+	NODE_XTHIN   uint64 = (1 << 4)
+	// NODE_COMPACT_FILTERS serves BIP157 compact block filters.
+	NODE_COMPACT_FILTERS uint64 = (1 << 6)
+	// NODE_NETWORK_LIMITED serves at least the last 288 blocks (BIP159).
+	NODE_NETWORK_LIMITED uint64 = (1 << 10)
+	// NODE_P2P_V2 supports the BIP324 encrypted transport.
+	NODE_P2P_V2 uint64 = (1 << 11)
+	// Litecoin: NODE_MWEB_LIGHT_CLIENT serves MWEB data to light clients and
+	// NODE_MWEB serves blocks with their MWEB extension block.
+	NODE_MWEB_LIGHT_CLIENT uint64 = (1 << 23)
+	NODE_MWEB              uint64 = (1 << 24)
+)
+
+type Flag struct {
+	Mask uint64
+	Name string
+}
+
+// Flags lists the known service flags in bit order.
+var Flags = []Flag{
+	{NODE_NETWORK, "NETWORK"},
+	{NODE_GETUTXO, "GETUTXO"},
+	{NODE_BLOOM, "BLOOM"},
+	{NODE_WITNESS, "WITNESS"},
+	{NODE_XTHIN, "XTHIN"},
+	{NODE_COMPACT_FILTERS, "COMPACT_FILTERS"},
+	{NODE_NETWORK_LIMITED, "NETWORK_LIMITED"},
+	{NODE_P2P_V2, "P2P_V2"},
+	{NODE_MWEB_LIGHT_CLIENT, "MWEB_LIGHT_CLIENT"},
+	{NODE_MWEB, "MWEB"},
+}
+
+// Names returns the names of the flags set in mask in bit order. Bits
+// without a name are given as UNKNOWN[1<<n].
+func Names(mask uint64) []string {
+	var names []string
+	for i := uint(0); i < 64; i++ {
+		bit := uint64(1) << i
+		if mask&bit == 0 {
+			continue
+		}
+		name := fmt.Sprintf("UNKNOWN[1<<%d]", i)
+		for _, f := range Flags {
+			if f.Mask == bit {
+				name = f.Name
+				break
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// Format returns the names of the flags set in mask separated by " & ", or
+// None when no flag is set. Parse reverses it.
+func Format(mask uint64) string {
+	if mask == NODE_NONE {
+		return "None"
+	}
+	return strings.Join(Names(mask), " & ")
+}
+
+// Parse reads a service mask written as a number or as flag names. Numbers
+// are decimal, or hex with a 0x prefix. The 16 hex digits getpeerinfo shows
+// need the prefix too, as they can be all digits. Names may be joined by |,
+// &, +, commas or spaces, are case insensitive, may carry a NODE_ prefix and
+// include UNKNOWN[1<<n].
+func Parse(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty service mask")
+	}
+
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasPrefix(lower, "0x"):
+		return strconv.ParseUint(s[2:], 16, 64)
+	case isDigits(s):
+		return strconv.ParseUint(s, 10, 64)
+	}
+
+	var mask uint64
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '|' || r == '&' || r == '+' || r == ',' || r == ' ' || r == '\t'
+	})
+	for _, field := range fields {
+		bit, err := parseName(field)
+		if err != nil {
+			return 0, err
+		}
+		mask |= bit
+	}
+	return mask, nil
+}
+
+func parseName(name string) (uint64, error) {
+	upper := strings.TrimPrefix(strings.ToUpper(name), "NODE_")
+	if upper == "NONE" {
+		return NODE_NONE, nil
+	}
+	for _, f := range Flags {
+		if f.Name == upper {
+			return f.Mask, nil
+		}
+	}
+
+	if strings.HasPrefix(upper, "UNKNOWN[") && strings.HasSuffix(upper, "]") {
+		value := upper[len("UNKNOWN[") : len(upper)-1]
+		if strings.HasPrefix(value, "1<<") {
+			n, err := strconv.ParseUint(value[3:], 10, 8)
+			if err == nil && n < 64 {
+				return 1 << n, nil
+			}
+		} else if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown service flag %q", name)
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
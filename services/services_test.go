@@ -0,0 +1,100 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		mask uint64
+		want string
+	}{
+		{NODE_NONE, ""},
+		{NODE_NETWORK, "NETWORK"},
+		{NODE_GETUTXO, "GETUTXO"},
+		{NODE_BLOOM, "BLOOM"},
+		{NODE_WITNESS, "WITNESS"},
+		{NODE_XTHIN, "XTHIN"},
+		{NODE_COMPACT_FILTERS, "COMPACT_FILTERS"},
+		{NODE_NETWORK_LIMITED, "NETWORK_LIMITED"},
+		{NODE_P2P_V2, "P2P_V2"},
+		{NODE_MWEB_LIGHT_CLIENT, "MWEB_LIGHT_CLIENT"},
+		{NODE_MWEB, "MWEB"},
+		{1 << 5, "UNKNOWN[1<<5]"},
+		{1 << 63, "UNKNOWN[1<<63]"},
+		{NODE_NETWORK | NODE_WITNESS | NODE_NETWORK_LIMITED | NODE_MWEB, "NETWORK,WITNESS,NETWORK_LIMITED,MWEB"},
+		{NODE_MWEB | 1<<40, "MWEB,UNKNOWN[1<<40]"},
+	}
+	for _, test := range tests {
+		if got := strings.Join(Names(test.mask), ","); got != test.want {
+			t.Errorf("Names(%x) = %q, want %q", test.mask, got, test.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format(NODE_NONE); got != "None" {
+		t.Errorf("Format(0) = %q, want None", got)
+	}
+	if got := Format(13); got != "NETWORK & BLOOM & WITNESS" {
+		t.Errorf("Format(13) = %q", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value string
+		want  uint64
+		fails bool
+	}{
+		{"0", 0, false},
+		{"1033", 0x409, false},
+		{"0x1000409", 0x1000409, false},
+		{"0X0000000001000409", 0x1000409, false},
+		// 16 digits without a prefix are decimal, not the hex getpeerinfo
+		// shows.
+		{"0000000000000409", 409, false},
+		{"0000000001000409", 1000409, false},
+		{"000000000100040d", 0, true},
+		{"NETWORK|WITNESS", 9, false},
+		{"network & witness & mweb", 0x1000009, false},
+		{"NODE_NETWORK+NODE_BLOOM,XTHIN", 0x15, false},
+		{"None", 0, false},
+		{"UNKNOWN[1<<40] MWEB_LIGHT_CLIENT", 1<<40 | NODE_MWEB_LIGHT_CLIENT, false},
+		{"UNKNOWN[32]", 32, false},
+		{"UNKNOWN[1<<64]", 0, true},
+		{"NETWORK|BOGUS", 0, true},
+		{"", 0, true},
+		{"0xzz", 0, true},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.value)
+		if test.fails {
+			if err == nil {
+				t.Errorf("Parse(%q) = %x, want an error", test.value, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("Parse(%q) = %x, %v, want %x", test.value, got, err, test.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, f := range Flags {
+		if m, err := Parse(Format(f.Mask)); err != nil || m != f.Mask {
+			t.Errorf("%s: %x %v", f.Name, m, err)
+		}
+	}
+	for i := uint(0); i < 64; i++ {
+		if m, err := Parse(Format(1 << i)); err != nil || m != 1<<i {
+			t.Errorf("bit %d: %x %v", i, m, err)
+		}
+	}
+	all := ^uint64(0)
+	if m, err := Parse(Format(all)); err != nil || m != all {
+		t.Errorf("all bits: %x %v", m, err)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/thrasher-/litecoin-tools/activation"
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+var (
+	RPCHost       string
+	RPCPort       int
+	RPCUsername   string
+	RPCPassword   string
+	RPCCookieFile string
+	rpc           *litecoinrpc.Client
+	chain         *rawblock.Chain
+	pools         *Pools
+	params        rawblock.Params
+)
+
+func GetBlockHeight() (int64, error) {
+	if chain != nil {
+		return chain.Height(), nil
+	}
+	return rpc.GetBlockCount()
+}
+
+// Signal decodes the version bits of block and attributes it to a pool.
+func Signal(height int64, block *rawblock.Block) BlockSignal {
+	hash := block.Header.Hash()
+	var coinbase []byte
+	if len(block.Transactions) > 0 && len(block.Transactions[0].TxIn) > 0 {
+		coinbase = block.Transactions[0].TxIn[0].ScriptSig
+	}
+	payout := PayoutAddresses(block, params)
+	return BlockSignal{
+		Height:  height,
+		Hash:    rawblock.HashString(hash),
+		Time:    int64(block.Header.Time),
+		Version: block.Header.Version,
+		Bits:    activation.SignalledBits(block.Header.Version),
+		Pool:    pools.Identify(coinbase, payout),
+		Tag:     CoinbaseTag(coinbase),
+		Payout:  payout,
+	}
+}
+
+// HeaderSignal decodes the version bits of a block whose transactions could
+// not be decoded, such as one using a newer MWEB serialisation. Its bits are
+// still counted, but it is attributed to UNKNOWN_POOL and listed as
+// undecoded in the report.
+func HeaderSignal(height int64, header rawblock.Header, err error) BlockSignal {
+	log.Printf("Unable to decode block %d, counting its header only. Err: %s", height, err)
+	hash := header.Hash()
+	return BlockSignal{
+		Height:    height,
+		Hash:      rawblock.HashString(hash),
+		Time:      int64(header.Time),
+		Version:   header.Version,
+		Bits:      activation.SignalledBits(header.Version),
+		Pool:      UNKNOWN_POOL,
+		Undecoded: true,
+	}
+}
+
+// GetBlocks returns the signalling of the blocks start through end
+// inclusive, read from the block files or fetched from the node.
+func GetBlocks(start, end int64) ([]BlockSignal, error) {
+	var result []BlockSignal
+	if chain != nil {
+		for height := start; height <= end; height++ {
+			block, err := chain.Block(height)
+			if err == nil {
+				result = append(result, Signal(height, block))
+				continue
+			}
+			header, headerErr := chain.Header(height)
+			if headerErr != nil {
+				return nil, headerErr
+			}
+			result = append(result, HeaderSignal(height, header, err))
+		}
+		return result, nil
+	}
+
+	hashes, err := rpc.GetBlockHashes(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var blockHashes []string
+	for _, x := range hashes {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block hash for height %d: %s", x.Height, x.Err)
+		}
+		blockHashes = append(blockHashes, x.Hash)
+	}
+
+	blocks, err := rpc.GetBlocksHex(blockHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, x := range blocks {
+		if x.Err != nil {
+			return nil, fmt.Errorf("unable to get block %s at height %d: %s", x.Hash, hashes[i].Height, x.Err)
+		}
+		data, err := hex.DecodeString(x.Hex)
+		if err != nil {
+			return nil, err
+		}
+		block, err := rawblock.Parse(data)
+		if err == nil {
+			result = append(result, Signal(hashes[i].Height, block))
+			continue
+		}
+		header, headerErr := rawblock.ParseHeader(data)
+		if headerErr != nil {
+			return nil, fmt.Errorf("unable to decode block %s at height %d: %s", x.Hash, hashes[i].Height, headerErr)
+		}
+		result = append(result, HeaderSignal(hashes[i].Height, header, err))
+	}
+	return result, nil
+}
+
+func main() {
+	var start, end, window int64
+	var batchSize int
+	var network, datadir, poolsFile, format, outputFile string
+	var perBlock bool
+	flag.StringVar(&RPCHost, "rpchost", "127.0.0.1", "The RPC host to connect to.")
+	flag.IntVar(&RPCPort, "rpcport", 0, "The RPC port to connect to. Defaults to the -network's port.")
+	flag.StringVar(&RPCUsername, "rpcuser", "user", "The RPC username.")
+	flag.StringVar(&RPCPassword, "rpcpass", "pass", "The RPC password.")
+	flag.StringVar(&RPCCookieFile, "rpccookiefile", "", "The RPC cookie file to authenticate with instead of -rpcuser/-rpcpass.")
+	flag.IntVar(&batchSize, "batchsize", litecoinrpc.DefaultBatchSize, "Number of blocks to request per RPC batch.")
+	flag.StringVar(&network, "network", "main", "Network whose addresses and deployments are used: main or test.")
+	flag.StringVar(&datadir, "datadir", "", "Read blocks from the blk*.dat files in this node data directory instead of over RPC.")
+	flag.Int64Var(&start, "start", -1, "First block height to report. Defaults to the start of the current window.")
+	flag.Int64Var(&end, "end", -1, "Last block height to report, inclusive. Defaults to the current tip.")
+	flag.Int64Var(&window, "window", 0, "Number of blocks per window. Defaults to the network's miner confirmation window.")
+	flag.StringVar(&poolsFile, "pools", "", "JSON file mapping coinbase_tags and payout_addresses to pool names, replacing the built in tags.")
+	flag.StringVar(&format, "format", "text", "Output format of the report: text, json or csv.")
+	flag.StringVar(&outputFile, "output", "", "File to write the report to. Defaults to stdout.")
+	flag.BoolVar(&perBlock, "perblock", false, "Include every block's signalled bits and pool in the json output, or write them instead of the windows as csv.")
+	flag.Parse()
+
+	if format != "text" && format != "json" && format != "csv" {
+		log.Fatalf("Unknown output format %q.", format)
+	}
+
+	deployments, err := activation.GetChainParams("litecoin", network)
+	if err != nil {
+		log.Fatal(err)
+	}
+	params, err = rawblock.GetParams(network)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	pools = &DefaultPools
+	if poolsFile != "" {
+		pools, err = LoadPools(poolsFile)
+		if err != nil {
+			log.Fatalf("Failed to load pools file %s. Err: %s", poolsFile, err)
+		}
+	}
+
+	if datadir != "" {
+		log.Printf("Indexing block files in %s", rawblock.BlocksDir(datadir))
+		chain, err = rawblock.OpenChain(datadir, params)
+		if err != nil {
+			log.Fatalf("Failed to read block files. Err: %s", err)
+		}
+	} else {
+		if RPCPort <= 0 {
+			RPCPort = deployments.RPCPort
+		}
+		rpc = litecoinrpc.New(RPCHost, RPCPort, RPCUsername, RPCPassword)
+		rpc.CookieFile = RPCCookieFile
+		rpc.BatchSize = batchSize
+		log.Printf("RPC URL: %s", rpc.URL())
+	}
+
+	report := NewReport(deployments, window, perBlock)
+
+	tip, err := GetBlockHeight()
+	if err != nil {
+		log.Fatalf("Failed to retrieve current block height. Err: %s", err)
+	}
+	if end < 0 || end > tip {
+		end = tip
+	}
+	if start < 0 {
+		start = end - end%report.Size
+	}
+	if start > end {
+		log.Fatalf("Invalid block range %d-%d.", start, end)
+	}
+
+	if batchSize <= 0 {
+		batchSize = litecoinrpc.DefaultBatchSize
+	}
+
+	log.Printf("Reading blocks %d-%d", start, end)
+	lastReport := time.Now()
+	for i := start; i <= end; i += int64(batchSize) {
+		last := i + int64(batchSize) - 1
+		if last > end {
+			last = end
+		}
+		blocks, err := GetBlocks(i, last)
+		if err != nil {
+			log.Fatalf("Failed to read blocks %d-%d. Err: %s", i, last, err)
+		}
+		for _, b := range blocks {
+			report.Add(b)
+		}
+		if time.Since(lastReport) >= time.Second*30 {
+			log.Printf("Read blocks up to height %d/%d", last, end)
+			lastReport = time.Now()
+		}
+	}
+	report.Finish()
+
+	err = WriteResult(report, format, outputFile, perBlock)
+	if err != nil {
+		log.Fatalf("Failed to write report. Err: %s", err)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteResult writes the report as text, json or csv to path, or to stdout
+// when path is empty. With perBlock the csv form lists every block instead
+// of the window table.
+func WriteResult(r *Report, format, path string, perBlock bool) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch {
+	case format == "json":
+		return WriteJSON(w, r)
+	case format == "csv" && perBlock:
+		return WriteBlocksCSV(w, r.Blocks)
+	case format == "csv":
+		return WriteCSV(w, r)
+	}
+	return WriteText(w, r)
+}
+
+func WriteJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", " ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
+
+func (r *Report) bitLabel(bit uint) string {
+	if name := r.BitName(bit); name != "" {
+		return fmt.Sprintf("bit %d (%s)", bit, name)
+	}
+	return fmt.Sprintf("bit %d", bit)
+}
+
+// WriteText writes a table per window with the signalling percentage of
+// each bit, overall and by pool.
+func WriteText(w io.Writer, r *Report) error {
+	bits := r.SignalledBits()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	for _, win := range r.Windows {
+		fmt.Fprintf(tw, "Window %d-%d, %d blocks\n", win.Start, win.End, win.Blocks)
+
+		header := []string{"Pool", "Blocks", "Share"}
+		for _, bit := range bits {
+			header = append(header, r.bitLabel(bit))
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+		row := func(name string, s Signalling) {
+			fields := []string{name, strconv.FormatInt(s.Blocks, 10), fmt.Sprintf("%.2f%%", float64(s.Blocks)*100/float64(win.Blocks))}
+			for _, bit := range bits {
+				fields = append(fields, fmt.Sprintf("%.2f%%", s.Percent(bit)))
+			}
+			fmt.Fprintln(tw, strings.Join(fields, "\t"))
+		}
+		row("all", win.Signalling)
+		for _, p := range win.Pools {
+			row(p.Pool, p.Signalling)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	if len(r.Undecoded) > 0 {
+		var heights []string
+		for _, x := range r.Undecoded {
+			heights = append(heights, strconv.FormatInt(x, 10))
+		}
+		fmt.Fprintf(tw, "%d blocks could only be decoded from their header and are counted under %s: %s\n",
+			len(r.Undecoded), UNKNOWN_POOL, strings.Join(heights, ", "))
+	}
+	return tw.Flush()
+}
+
+// WriteCSV writes a row per window and pool, with a pool of "all" for the
+// whole window, and the count and percentage of blocks signalling each bit.
+func WriteCSV(w io.Writer, r *Report) error {
+	bits := r.SignalledBits()
+	cw := csv.NewWriter(w)
+	header := []string{"window_start", "window_end", "pool", "blocks"}
+	for _, bit := range bits {
+		name := "bit_" + strconv.Itoa(int(bit))
+		header = append(header, name, name+"_pct")
+	}
+	cw.Write(header)
+
+	for _, win := range r.Windows {
+		row := func(name string, s Signalling) {
+			record := []string{strconv.FormatInt(win.Start, 10), strconv.FormatInt(win.End, 10), name, strconv.FormatInt(s.Blocks, 10)}
+			for _, bit := range bits {
+				record = append(record, strconv.FormatInt(s.Bits[bit], 10), strconv.FormatFloat(s.Percent(bit), 'f', 2, 64))
+			}
+			cw.Write(record)
+		}
+		row("all", win.Signalling)
+		for _, p := range win.Pools {
+			row(p.Pool, p.Signalling)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteBlocksCSV writes a row per block with its signalled bits separated by
+// spaces.
+func WriteBlocksCSV(w io.Writer, blocks []BlockSignal) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"height", "hash", "time", "version", "bits", "pool", "coinbase_tag", "payout_address"})
+	for _, b := range blocks {
+		var bits []string
+		for _, bit := range b.Bits {
+			bits = append(bits, strconv.Itoa(int(bit)))
+		}
+		var payout string
+		if len(b.Payout) > 0 {
+			payout = b.Payout[0]
+		}
+		cw.Write([]string{
+			strconv.FormatInt(b.Height, 10),
+			b.Hash,
+			strconv.FormatInt(b.Time, 10),
+			fmt.Sprintf("%08x", uint32(b.Version)),
+			strings.Join(bits, " "),
+			b.Pool,
+			b.Tag,
+			payout,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
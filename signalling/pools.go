@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+const (
+	UNKNOWN_POOL = "unknown"
+)
+
+// Pools maps coinbase tags and payout addresses to pool names, in the same
+// layout as the pools.json files used by block explorers. Tags are matched
+// case insensitively anywhere in the coinbase script.
+type Pools struct {
+	CoinbaseTags    map[string]string `json:"coinbase_tags"`
+	PayoutAddresses map[string]string `json:"payout_addresses"`
+
+	tags []string
+}
+
+// DefaultPools holds the tags of well known Litecoin pools. Use -pools to
+// load a more complete list.
+var DefaultPools = Pools{
+	CoinbaseTags: map[string]string{
+		"antpool":          "AntPool",
+		"binance":          "Binance Pool",
+		"emcd":             "EMCD",
+		"f2pool":           "F2Pool",
+		"七彩神仙鱼":            "F2Pool",
+		"litecoinpool.org": "LitecoinPool.org",
+		"ltc.top":          "LTC.TOP",
+		"mining-dutch":     "Mining-Dutch",
+		"poolin":           "Poolin",
+		"prohashing":       "ProHashing",
+		"trustpool":        "TrustPool",
+		"viabtc":           "ViaBTC",
+	},
+}
+
+func LoadPools(path string) (*Pools, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Pools
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// sortedTags returns the tags longest first so that the most specific tag
+// wins when several match.
+func (p *Pools) sortedTags() []string {
+	if p.tags == nil {
+		for tag := range p.CoinbaseTags {
+			p.tags = append(p.tags, tag)
+		}
+		sort.Slice(p.tags, func(i, j int) bool {
+			if len(p.tags[i]) != len(p.tags[j]) {
+				return len(p.tags[i]) > len(p.tags[j])
+			}
+			return p.tags[i] < p.tags[j]
+		})
+	}
+	return p.tags
+}
+
+// Identify returns the pool that mined a block from its coinbase script and
+// payout addresses. Blocks of unknown pools are attributed to their first
+// payout address.
+func (p *Pools) Identify(coinbase []byte, addresses []string) string {
+	script := strings.ToLower(string(coinbase))
+	for _, tag := range p.sortedTags() {
+		if strings.Contains(script, strings.ToLower(tag)) {
+			return p.CoinbaseTags[tag]
+		}
+	}
+
+	for _, addr := range addresses {
+		if name, ok := p.PayoutAddresses[addr]; ok {
+			return name
+		}
+	}
+	if len(addresses) > 0 {
+		return addresses[0]
+	}
+	return UNKNOWN_POOL
+}
+
+// CoinbaseTag returns the printable text in a coinbase script, with each run
+// of other bytes replaced by a single space.
+func CoinbaseTag(coinbase []byte) string {
+	var b strings.Builder
+	space := false
+	for _, c := range coinbase {
+		if c >= 0x20 && c < 0x7f {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteByte(c)
+			space = false
+			continue
+		}
+		space = true
+	}
+	return b.String()
+}
+
+// PayoutAddresses returns the addresses paid by the coinbase transaction
+// outputs with a value, in output order.
+func PayoutAddresses(block *rawblock.Block, params rawblock.Params) []string {
+	var addresses []string
+	for _, out := range block.Transactions[0].TxOut {
+		if out.Value <= 0 {
+			continue
+		}
+		if addr := rawblock.ScriptAddress(out.PkScript, params); addr != "" {
+			addresses = append(addresses, addr)
+		}
+	}
+	return addresses
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/thrasher-/litecoin-tools/activation"
+)
+
+// BlockSignal is the signalling of one block and the miner it is
+// attributed to.
+type BlockSignal struct {
+	Height  int64    `json:"height"`
+	Hash    string   `json:"hash"`
+	Time    int64    `json:"time"`
+	Version int32    `json:"version"`
+	Bits    []uint   `json:"bits"`
+	Pool    string   `json:"pool"`
+	Tag     string   `json:"coinbase_tag"`
+	Payout  []string `json:"payout_addresses"`
+	// Undecoded is set when only the header could be decoded, so the pool
+	// is unknown.
+	Undecoded bool `json:"undecoded,omitempty"`
+}
+
+// Signalling counts the blocks signalling each bit.
+type Signalling struct {
+	Blocks int64          `json:"blocks"`
+	Bits   map[uint]int64 `json:"bits"`
+}
+
+func (s *Signalling) add(bits []uint) {
+	if s.Bits == nil {
+		s.Bits = make(map[uint]int64)
+	}
+	s.Blocks++
+	for _, bit := range bits {
+		s.Bits[bit]++
+	}
+}
+
+// Percent returns the share of blocks signalling bit.
+func (s *Signalling) Percent(bit uint) float64 {
+	if s.Blocks == 0 {
+		return 0
+	}
+	return float64(s.Bits[bit]) * 100 / float64(s.Blocks)
+}
+
+type PoolSignalling struct {
+	Pool string `json:"pool"`
+	Signalling
+}
+
+// Window is the signalling over one confirmation window, overall and by
+// pool.
+type Window struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Signalling
+	Pools []*PoolSignalling `json:"pools"`
+
+	pools map[string]*PoolSignalling
+}
+
+// Report accumulates BlockSignals into windows aligned to multiples of the
+// window size.
+type Report struct {
+	Params  *activation.ChainParams `json:"-"`
+	Size    int64                   `json:"window"`
+	Windows []*Window               `json:"windows"`
+	Blocks  []BlockSignal           `json:"blocks,omitempty"`
+	// Undecoded lists the heights of blocks counted from their header only.
+	Undecoded []int64 `json:"undecoded,omitempty"`
+
+	keepBlocks bool
+}
+
+func NewReport(params *activation.ChainParams, size int64, keepBlocks bool) *Report {
+	if size <= 0 {
+		size = params.Window
+	}
+	return &Report{Params: params, Size: size, keepBlocks: keepBlocks}
+}
+
+// Add counts a block, which must not precede the last one added.
+func (r *Report) Add(b BlockSignal) {
+	start := b.Height - b.Height%r.Size
+	var w *Window
+	if n := len(r.Windows); n > 0 && r.Windows[n-1].Start == start {
+		w = r.Windows[n-1]
+	} else {
+		w = &Window{Start: start, End: start + r.Size - 1, pools: make(map[string]*PoolSignalling)}
+		r.Windows = append(r.Windows, w)
+	}
+
+	w.add(b.Bits)
+	p, ok := w.pools[b.Pool]
+	if !ok {
+		p = &PoolSignalling{Pool: b.Pool}
+		w.pools[b.Pool] = p
+		w.Pools = append(w.Pools, p)
+	}
+	p.add(b.Bits)
+
+	if r.keepBlocks {
+		r.Blocks = append(r.Blocks, b)
+	}
+	if b.Undecoded {
+		r.Undecoded = append(r.Undecoded, b.Height)
+	}
+}
+
+// Finish sorts the pools of each window by blocks mined, most first.
+func (r *Report) Finish() {
+	for _, w := range r.Windows {
+		sort.SliceStable(w.Pools, func(i, j int) bool { return w.Pools[i].Blocks > w.Pools[j].Blocks })
+	}
+}
+
+// SignalledBits returns every bit signalled in any window, in order.
+func (r *Report) SignalledBits() []uint {
+	seen := make(map[uint]bool)
+	var bits []uint
+	for _, w := range r.Windows {
+		for bit := range w.Bits {
+			if !seen[bit] {
+				seen[bit] = true
+				bits = append(bits, bit)
+			}
+		}
+	}
+	sort.Slice(bits, func(i, j int) bool { return bits[i] < bits[j] })
+	return bits
+}
+
+// BitName returns the deployments of the chain that use bit, such as
+// "segwit", or an empty string for none.
+func (r *Report) BitName(bit uint) string {
+	var name string
+	for _, d := range r.Params.Deployments {
+		if d.Type != activation.VERSIONBITS || d.Bit != bit {
+			continue
+		}
+		if name != "" {
+			name += "/"
+		}
+		name += d.Name
+	}
+	return name
+}
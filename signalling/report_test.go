@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/thrasher-/litecoin-tools/activation"
+	"github.com/thrasher-/litecoin-tools/litecoinrpc"
+	"github.com/thrasher-/litecoin-tools/rawblock"
+)
+
+// testBlock serialises a block with version and a single coinbase paying
+// nothing, whose scriptSig carries tag.
+func testBlock(version int32, tag string) []byte {
+	script := append([]byte{0x03, 0x01, 0x02, 0x03}, tag...)
+	tx := []byte{1, 0, 0, 0, 1}
+	tx = append(tx, make([]byte, 32)...)
+	tx = append(tx, 0xff, 0xff, 0xff, 0xff, byte(len(script)))
+	tx = append(tx, script...)
+	tx = append(tx, 0xff, 0xff, 0xff, 0xff, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0x51, 0, 0, 0, 0)
+
+	h := rawblock.Header{Version: version, Time: 1650000000, Bits: 0x1a01cd2d}
+	block := append(h.Serialize(), 1)
+	return append(block, tx...)
+}
+
+func TestReport(t *testing.T) {
+	r := NewReport(&activation.LitecoinMain, 10, true)
+	for height := int64(5); height < 25; height++ {
+		version := int32(activation.VERSIONBITS_TOP_BITS)
+		if height%2 == 0 {
+			version |= 1 << 4
+		}
+		pool := "A"
+		if height%3 == 0 {
+			pool = "B"
+		}
+		r.Add(BlockSignal{Height: height, Version: version, Bits: activation.SignalledBits(version), Pool: pool})
+	}
+	r.Finish()
+
+	if len(r.Windows) != 3 || r.Windows[0].Blocks != 5 || r.Windows[1].Blocks != 10 || r.Windows[1].Bits[4] != 5 {
+		t.Fatalf("Windows = %+v", r.Windows)
+	}
+	if w := r.Windows[1]; w.Pools[0].Pool != "A" || w.Pools[0].Blocks != 7 || w.Percent(4) != 50 {
+		t.Fatalf("window 10-19 pools %+v", w.Pools)
+	}
+	if r.BitName(4) != "mweb" {
+		t.Fatalf("BitName(4) = %q", r.BitName(4))
+	}
+}
+
+func TestSignal(t *testing.T) {
+	pools = &DefaultPools
+	params = rawblock.MainNetParams
+
+	block, err := rawblock.Parse(testBlock(0x20000010, "/ViaBTC/Mined by x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Signal(100, block)
+	if s.Pool != "ViaBTC" || s.Tag != "/ViaBTC/Mined by x" || len(s.Bits) != 1 || s.Bits[0] != 4 || s.Undecoded {
+		t.Fatalf("Signal = %+v", s)
+	}
+
+	// A coinbase without inputs has no tag to match.
+	block.Transactions[0].TxIn = nil
+	if s := Signal(100, block); s.Tag != "" {
+		t.Fatalf("Signal without a coinbase input = %+v", s)
+	}
+}
+
+// serveBlocks answers getblockhash and getblock verbosity 0 for the given
+// raw blocks over a fake RPC server, and points rpc at it.
+func serveBlocks(t *testing.T, blocks [][]byte) {
+	call := func(req map[string]interface{}) map[string]interface{} {
+		params := req["params"].([]interface{})
+		var result interface{}
+		switch req["method"] {
+		case "getblockhash":
+			result = fmt.Sprintf("%064d", int(params[0].(float64)))
+		case "getblock":
+			height, _ := strconv.Atoi(params[0].(string))
+			result = hex.EncodeToString(blocks[height])
+		}
+		return map[string]interface{}{"id": req["id"], "result": result, "error": nil}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var batch []map[string]interface{}
+		json.Unmarshal(body, &batch)
+		var results []interface{}
+		for _, x := range batch {
+			results = append(results, call(x))
+		}
+		json.NewEncoder(w).Encode(results)
+	}))
+	t.Cleanup(srv.Close)
+
+	host, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	rpc = litecoinrpc.New(host, p, "u", "p")
+	chain = nil
+	t.Cleanup(func() { rpc = nil })
+}
+
+func TestGetBlocksUndecodable(t *testing.T) {
+	pools = &DefaultPools
+	params = rawblock.MainNetParams
+
+	// Block 1 has a valid header followed by transactions that can't be
+	// decoded. It is still counted, from its header.
+	bad := testBlock(0x20000010, "/F2Pool/")
+	bad = append(bad[:rawblock.HeaderSize+1], 0xff, 0xff, 0xff)
+	serveBlocks(t, [][]byte{
+		testBlock(0x20000010, "/ViaBTC/"),
+		bad,
+		testBlock(0x20000000, "/F2Pool/"),
+	})
+
+	signals, err := GetBlocks(0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signals) != 3 {
+		t.Fatalf("got %d blocks, want 3", len(signals))
+	}
+	if s := signals[1]; !s.Undecoded || s.Pool != UNKNOWN_POOL || len(s.Bits) != 1 || s.Bits[0] != 4 || s.Height != 1 {
+		t.Fatalf("undecodable block = %+v", s)
+	}
+	if signals[0].Pool != "ViaBTC" || signals[2].Pool != "F2Pool" || signals[0].Undecoded || signals[2].Undecoded {
+		t.Fatalf("decodable blocks = %+v, %+v", signals[0], signals[2])
+	}
+
+	r := NewReport(&activation.LitecoinMain, 10, false)
+	for _, s := range signals {
+		r.Add(s)
+	}
+	r.Finish()
+	if len(r.Undecoded) != 1 || r.Undecoded[0] != 1 || r.Windows[0].Bits[4] != 2 {
+		t.Fatalf("report undecoded %v, bit 4 count %d", r.Undecoded, r.Windows[0].Bits[4])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "1 blocks could only be decoded from their header and are counted under unknown: 1") {
+		t.Fatalf("WriteText doesn't list the undecoded block:\n%s", buf.String())
+	}
+}